@@ -0,0 +1,341 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Redaction classes understood by HOMEBOX_AUDIT_REDACT.
+const (
+	redactTokens       = "tokens"
+	redactNotifierURLs = "notifier_urls"
+	redactBase64       = "base64"
+)
+
+// base64MinLength is the shortest string value that is treated as a base64
+// payload when the base64 redaction class is enabled.
+const base64MinLength = 256
+
+// auditEntry is a single line of the audit log.
+type auditEntry struct {
+	Time      string          `json:"time"`
+	SessionID string          `json:"sessionId,omitempty"`
+	Caller    string          `json:"caller,omitempty"`
+	Tool      string          `json:"tool"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+	Endpoints []string        `json:"endpoints,omitempty"`
+	Status    string          `json:"status"`
+	Error     string          `json:"error,omitempty"`
+	LatencyMS int64           `json:"latencyMs"`
+}
+
+// auditLogger writes one JSON object per tool call.
+type auditLogger struct {
+	mu     sync.Mutex
+	w      io.Writer
+	redact map[string]bool
+	fields map[string]bool
+}
+
+// newAuditLoggerFromEnv configures the audit logger from the environment.
+// It returns nil if HOMEBOX_AUDIT_LOG is not set.
+//
+// HOMEBOX_AUDIT_LOG is "stdout", "stderr" or a file path. Files are rotated
+// once they reach HOMEBOX_AUDIT_MAX_SIZE_MB (default 10), keeping
+// HOMEBOX_AUDIT_MAX_BACKUPS old files (default 5). HOMEBOX_AUDIT_REDACT is a
+// comma separated list of redaction classes (tokens, notifier_urls, base64),
+// "all" (the default) or "none". HOMEBOX_AUDIT_REDACT_FIELDS lists additional
+// argument names whose values are always redacted.
+func newAuditLoggerFromEnv(stdio bool) (*auditLogger, error) {
+	dest := os.Getenv("HOMEBOX_AUDIT_LOG")
+	if dest == "" {
+		return nil, nil
+	}
+
+	var w io.Writer
+	switch dest {
+	case "stdout":
+		if stdio {
+			// stdout carries the MCP protocol on the stdio transport.
+			log.Println("HOMEBOX_AUDIT_LOG=stdout conflicts with the stdio transport, writing audit log to stderr")
+			w = os.Stderr
+		} else {
+			w = os.Stdout
+		}
+	case "stderr":
+		w = os.Stderr
+	default:
+		maxSize, err := envInt("HOMEBOX_AUDIT_MAX_SIZE_MB", 10)
+		if err != nil {
+			return nil, err
+		}
+		maxBackups, err := envInt("HOMEBOX_AUDIT_MAX_BACKUPS", 5)
+		if err != nil {
+			return nil, err
+		}
+		rw, err := newRotatingWriter(dest, int64(maxSize)<<20, maxBackups)
+		if err != nil {
+			return nil, err
+		}
+		w = rw
+	}
+
+	redact := map[string]bool{}
+	switch classes := os.Getenv("HOMEBOX_AUDIT_REDACT"); classes {
+	case "", "all":
+		redact[redactTokens] = true
+		redact[redactNotifierURLs] = true
+		redact[redactBase64] = true
+	case "none":
+	default:
+		for _, class := range strings.Split(classes, ",") {
+			class = strings.TrimSpace(class)
+			switch class {
+			case redactTokens, redactNotifierURLs, redactBase64:
+				redact[class] = true
+			default:
+				return nil, fmt.Errorf("unknown HOMEBOX_AUDIT_REDACT class %q", class)
+			}
+		}
+	}
+
+	fields := map[string]bool{}
+	for _, field := range strings.Split(os.Getenv("HOMEBOX_AUDIT_REDACT_FIELDS"), ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields[strings.ToLower(field)] = true
+		}
+	}
+
+	return &auditLogger{w: w, redact: redact, fields: fields}, nil
+}
+
+// envInt reads an integer environment variable, returning def if it is unset.
+func envInt(name string, def int) (int, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return n, nil
+}
+
+// middleware returns an MCP receiving middleware that audits every tools/call request.
+func (l *auditLogger) middleware() mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			callReq, ok := req.(*mcp.CallToolRequest)
+			if method != "tools/call" || !ok {
+				return next(ctx, method, req)
+			}
+
+			entry := &auditEntry{
+				Tool:      callReq.Params.Name,
+				Arguments: l.redactArguments(callReq.Params.Arguments),
+			}
+			if callReq.Session != nil {
+				entry.SessionID = callReq.Session.ID()
+				if params := callReq.Session.InitializeParams(); params != nil && params.ClientInfo != nil {
+					entry.Caller = params.ClientInfo.Name
+					if params.ClientInfo.Version != "" {
+						entry.Caller += "/" + params.ClientInfo.Version
+					}
+				}
+			}
+
+			ctx, call := withToolCall(ctx)
+			start := time.Now()
+			result, err := next(ctx, method, req)
+			entry.LatencyMS = time.Since(start).Milliseconds()
+			entry.Time = start.UTC().Format(time.RFC3339Nano)
+			entry.Endpoints = call.Endpoints()
+
+			switch res, _ := result.(*mcp.CallToolResult); {
+			case err != nil:
+				entry.Status = "error"
+				entry.Error = err.Error()
+			case res != nil && res.IsError:
+				entry.Status = "tool_error"
+				for _, c := range res.Content {
+					if text, ok := c.(*mcp.TextContent); ok {
+						entry.Error = text.Text
+						break
+					}
+				}
+			default:
+				entry.Status = "ok"
+			}
+
+			l.write(entry)
+			return result, err
+		}
+	}
+}
+
+// write appends entry to the audit log as a single JSON line.
+func (l *auditLogger) write(entry *auditEntry) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("audit: failed to marshal entry: %v", err)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.w.Write(append(line, '\n')); err != nil {
+		log.Printf("audit: failed to write entry: %v", err)
+	}
+}
+
+// redactArguments returns a copy of the raw tool arguments with sensitive
+// values replaced according to the configured redaction classes.
+func (l *auditLogger) redactArguments(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 {
+		return nil
+	}
+	var args any
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return json.RawMessage(strconv.Quote("[unparseable arguments]"))
+	}
+	redacted, err := json.Marshal(l.redactValue("", args))
+	if err != nil {
+		return nil
+	}
+	return redacted
+}
+
+func (l *auditLogger) redactValue(key string, v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, val := range v {
+			out[k] = l.redactValue(k, val)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, val := range v {
+			out[i] = l.redactValue(key, val)
+		}
+		return out
+	case string:
+		return l.redactString(key, v)
+	default:
+		return v
+	}
+}
+
+func (l *auditLogger) redactString(key, v string) any {
+	name := strings.ToLower(key)
+	if l.fields[name] {
+		return "[redacted]"
+	}
+	if l.redact[redactTokens] && (strings.Contains(name, "token") || strings.Contains(name, "password") || strings.Contains(name, "secret")) {
+		return "[redacted]"
+	}
+	if l.redact[redactNotifierURLs] && name == "url" {
+		return "[redacted url]"
+	}
+	if l.redact[redactBase64] && isBase64Payload(name, v) {
+		return fmt.Sprintf("[redacted base64, %d bytes]", base64.StdEncoding.DecodedLen(len(v)))
+	}
+	return v
+}
+
+// isBase64Payload reports whether v looks like an encoded file or image.
+func isBase64Payload(name, v string) bool {
+	switch name {
+	case "file_content", "image", "imagebase64":
+		return true
+	}
+	if len(v) < base64MinLength {
+		return false
+	}
+	_, err := base64.StdEncoding.DecodeString(v)
+	return err == nil
+}
+
+// rotatingWriter is an io.Writer that appends to a file and rotates it once
+// it grows past maxSize bytes, keeping at most maxBackups old files named
+// path.1 (newest) through path.N (oldest).
+type rotatingWriter struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+func newRotatingWriter(path string, maxSize int64, maxBackups int) (*rotatingWriter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	w := &rotatingWriter{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *rotatingWriter) open() error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stat audit log: %w", err)
+	}
+	w.file = f
+	w.size = info.Size()
+	return nil
+}
+
+// Write implements io.Writer.
+func (w *rotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.maxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *rotatingWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	if w.maxBackups > 0 {
+		os.Remove(fmt.Sprintf("%s.%d", w.path, w.maxBackups))
+		for i := w.maxBackups - 1; i >= 1; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", w.path, i), fmt.Sprintf("%s.%d", w.path, i+1))
+		}
+		if err := os.Rename(w.path, w.path+".1"); err != nil {
+			return fmt.Errorf("failed to rotate audit log: %w", err)
+		}
+	} else if err := os.Remove(w.path); err != nil {
+		return fmt.Errorf("failed to rotate audit log: %w", err)
+	}
+	return w.open()
+}
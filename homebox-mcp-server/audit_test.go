@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditRedactArguments(t *testing.T) {
+	logger := &auditLogger{
+		redact: map[string]bool{redactTokens: true, redactNotifierURLs: true, redactBase64: true},
+		fields: map[string]bool{"email": true},
+	}
+
+	raw := json.RawMessage(`{"name":"drill","url":"ntfy://secret","apiToken":"abc","email":"a@b.c","file_content":"aGVsbG8=","nested":[{"password":"p"}]}`)
+	var got map[string]any
+	assert.NoError(t, json.Unmarshal(logger.redactArguments(raw), &got))
+
+	assert.Equal(t, "drill", got["name"])
+	assert.Equal(t, "[redacted url]", got["url"])
+	assert.Equal(t, "[redacted]", got["apiToken"])
+	assert.Equal(t, "[redacted]", got["email"])
+	assert.Equal(t, "[redacted base64, 6 bytes]", got["file_content"])
+	assert.Equal(t, "[redacted]", got["nested"].([]any)[0].(map[string]any)["password"])
+}
+
+func TestAuditMiddleware(t *testing.T) {
+	homebox := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id":"123","name":"Tools"}`))
+	}))
+	defer homebox.Close()
+
+	os.Setenv("HOMEBOX_URL", homebox.URL)
+	os.Setenv("HOMEBOX_TOKEN", "test-token")
+
+	var buf bytes.Buffer
+	logger := &auditLogger{w: &buf, redact: map[string]bool{}, fields: map[string]bool{}}
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	server.AddReceivingMiddleware(logger.middleware())
+	mcp.AddTool(server, &mcp.Tool{Name: "get_label"}, getLabel)
+
+	ctx := context.Background()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	_, err := server.Connect(ctx, serverTransport, nil)
+	assert.NoError(t, err)
+	client := mcp.NewClient(&mcp.Implementation{Name: "audit-client", Version: "1.0"}, nil)
+	session, err := client.Connect(ctx, clientTransport, nil)
+	assert.NoError(t, err)
+	defer session.Close()
+
+	_, err = session.CallTool(ctx, &mcp.CallToolParams{Name: "get_label", Arguments: map[string]any{"id": "123"}})
+	assert.NoError(t, err)
+
+	var entry map[string]any
+	assert.NoError(t, json.Unmarshal([]byte(strings.TrimSpace(buf.String())), &entry))
+	assert.Equal(t, "get_label", entry["tool"])
+	assert.Equal(t, "audit-client/1.0", entry["caller"])
+	assert.Equal(t, "ok", entry["status"])
+	assert.Equal(t, []any{"GET /api/v1/labels/123"}, entry["endpoints"])
+	assert.Equal(t, map[string]any{"id": "123"}, entry["arguments"])
+}
@@ -0,0 +1,591 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Bulk operations run this many Homebox requests at once unless the caller
+// asks for another concurrency, up to maxBulkConcurrency.
+const (
+	defaultBulkConcurrency = 4
+	maxBulkConcurrency     = 16
+)
+
+// BulkItemRow is one item to create with create_items_bulk.
+type BulkItemRow struct {
+	Name        string      `json:"name" jsonschema:"Name of the item"`
+	Description string      `json:"description,omitempty" jsonschema:"Free-text description of the item"`
+	LabelIDs    []string    `json:"labelIds,omitempty" jsonschema:"Labels to attach, by ID or name"`
+	LocationID  string      `json:"locationId,omitempty" jsonschema:"Location of the item, by ID, name or path such as Garage/Shelf 2"`
+	ParentID    string      `json:"parentId,omitempty" jsonschema:"Item to nest this item under, by ID, asset ID or name"`
+	Quantity    int         `json:"quantity,omitempty" jsonschema:"How many of the item there are"`
+	Fields      []ItemField `json:"fields,omitempty" jsonschema:"Custom fields of the item"`
+	Photo       string      `json:"photo,omitempty" jsonschema:"Base64 encoded photo, attached as the primary photo of the item"`
+	PhotoName   string      `json:"photoName,omitempty" jsonschema:"File name of the photo, including its extension"`
+}
+
+// Input for the create_items_bulk tool.
+type CreateItemsBulkInput struct {
+	Items           []BulkItemRow `json:"items" jsonschema:"Items to create"`
+	CreateMissing   bool          `json:"createMissing,omitempty" jsonschema:"Create the locations and labels that do not exist yet"`
+	RollbackOnError bool          `json:"rollbackOnError,omitempty" jsonschema:"Delete every created item again if any row fails"`
+	Concurrency     int           `json:"concurrency,omitempty" jsonschema:"How many items to create at once, 4 by default and at most 16"`
+}
+
+// BulkItemResult is the outcome of one row of a bulk operation.
+type BulkItemResult struct {
+	Index   int      `json:"index" jsonschema:"Position of the row in the input"`
+	ID      string   `json:"id,omitempty" jsonschema:"ID of the item"`
+	AssetID string   `json:"assetId,omitempty" jsonschema:"Asset ID of the item"`
+	Name    string   `json:"name" jsonschema:"Name of the item"`
+	Status  string   `json:"status" jsonschema:"Outcome of the row, such as created, updated or failed"`
+	Changes []string `json:"changes,omitempty" jsonschema:"What was or would be changed"`
+	Error   string   `json:"error,omitempty" jsonschema:"Why the row failed"`
+}
+
+// Output for the create_items_bulk tool.
+type CreateItemsBulkOutput struct {
+	Created    int              `json:"created" jsonschema:"Number of items created and kept"`
+	Failed     int              `json:"failed" jsonschema:"Number of rows that failed"`
+	RolledBack bool             `json:"rolledBack" jsonschema:"Whether the created items were deleted again because a row failed"`
+	Results    []BulkItemResult `json:"results" jsonschema:"Outcome of each row, in input order"`
+}
+
+// bulkConcurrency returns the number of workers to use for n rows.
+func bulkConcurrency(requested, n int) int {
+	workers := requested
+	if workers <= 0 {
+		workers = defaultBulkConcurrency
+	}
+	workers = min(workers, maxBulkConcurrency, n)
+	return max(workers, 1)
+}
+
+// forEachBounded calls fn for every index below n, running at most workers
+// calls at once, and returns once all calls have returned.
+func forEachBounded(n, workers int, fn func(i int)) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, workers)
+	for i := 0; i < n; i++ {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			fn(i)
+		}()
+	}
+	wg.Wait()
+}
+
+// createItemsBulk is the implementation of the "create_items_bulk" tool.
+// References are resolved one row at a time first, so that rows naming the
+// same missing location or label create it once; the items are then created
+// concurrently. Locations and labels created for missing references are kept
+// on rollback.
+func createItemsBulk(ctx context.Context, req *mcp.CallToolRequest, input CreateItemsBulkInput) (*mcp.CallToolResult, CreateItemsBulkOutput, error) {
+	if len(input.Items) == 0 {
+		return nil, CreateItemsBulkOutput{}, fmt.Errorf("items must not be empty")
+	}
+
+	results := make([]BulkItemResult, len(input.Items))
+	rows := make([]BulkItemRow, len(input.Items))
+	photos := make([][]byte, len(input.Items))
+	failed := false
+	for i, row := range input.Items {
+		results[i] = BulkItemResult{Index: i, Name: row.Name}
+		row.LabelIDs = append([]string(nil), row.LabelIDs...)
+		err := resolveItemReferences(ctx, &row.LocationID, row.LabelIDs, &row.ParentID, input.CreateMissing)
+		if err == nil && row.Photo != "" {
+			if photos[i], err = base64.StdEncoding.DecodeString(row.Photo); err != nil {
+				err = fmt.Errorf("failed to decode photo: %w", err)
+			}
+		}
+		if err != nil {
+			results[i].Status, results[i].Error = "failed", err.Error()
+			failed = true
+		}
+		rows[i] = row
+	}
+
+	// Nothing would be kept, so do not create anything.
+	if failed && input.RollbackOnError {
+		for i := range results {
+			if results[i].Status == "" {
+				results[i].Status, results[i].Error = "failed", "not created because another row failed"
+			}
+		}
+		return nil, bulkCreateOutput(results, false), nil
+	}
+
+	progress := newToolProgress(req)
+	progress.Logf(ctx, "info", "Creating %d items", len(rows))
+	var mu sync.Mutex
+	done := 0
+	forEachBounded(len(rows), bulkConcurrency(input.Concurrency, len(rows)), func(i int) {
+		if results[i].Status != "" {
+			return
+		}
+		item, err := createBulkItem(ctx, rows[i], photos[i])
+		mu.Lock()
+		defer mu.Unlock()
+		results[i].ID, results[i].AssetID = item.ID, item.AssetID
+		if err != nil {
+			results[i].Status, results[i].Error = "failed", err.Error()
+			failed = true
+		} else {
+			results[i].Status = "created"
+		}
+		done++
+		progress.Report(ctx, float64(done), float64(len(rows)), fmt.Sprintf("Created %d of %d items", done, len(rows)))
+	})
+	inventory.Invalidate()
+
+	if !failed || !input.RollbackOnError {
+		return nil, bulkCreateOutput(results, false), nil
+	}
+
+	// Delete everything that was created, including rows that failed after
+	// their item was created.
+	progress.Logf(ctx, "warning", "Rolling back the created items")
+	forEachBounded(len(results), bulkConcurrency(input.Concurrency, len(results)), func(i int) {
+		if results[i].ID == "" {
+			return
+		}
+		if _, _, err := deleteItem(ctx, nil, DeleteItemInput{ID: results[i].ID}); err != nil {
+			results[i].Error = fmt.Sprintf("rollback failed: %v", err)
+			return
+		}
+		if results[i].Status == "created" {
+			results[i].Status = "rolled_back"
+		}
+		results[i].ID, results[i].AssetID = "", ""
+	})
+	return nil, bulkCreateOutput(results, true), nil
+}
+
+// createBulkItem creates the item of a row whose references are resolved,
+// then sets its custom fields and uploads its photo. The returned item is
+// set whenever the item was created, even if a later step failed.
+func createBulkItem(ctx context.Context, row BulkItemRow, photo []byte) (ItemSummary, error) {
+	_, item, err := createItem(ctx, nil, CreateItemInput{
+		Name:        row.Name,
+		Description: row.Description,
+		LabelIDs:    row.LabelIDs,
+		LocationID:  row.LocationID,
+		ParentID:    row.ParentID,
+		Quantity:    row.Quantity,
+	})
+	if err != nil {
+		return ItemSummary{}, err
+	}
+
+	// Homebox only accepts custom fields on update.
+	if len(row.Fields) > 0 {
+		_, full, err := getItem(ctx, nil, GetItemInput{ID: item.ID})
+		if err != nil {
+			return item, err
+		}
+		update := updateInputFromItem(full)
+		update.Fields = row.Fields
+		if _, _, err := updateItem(ctx, nil, update); err != nil {
+			return item, err
+		}
+	}
+
+	if photo != nil {
+		name := row.PhotoName
+		if name == "" {
+			name = "photo"
+		}
+		if _, err := uploadItemAttachment(ctx, item.ID, name, photo, "photo", true); err != nil {
+			return item, err
+		}
+	}
+	return item, nil
+}
+
+// updateInputFromItem returns the update_item input that writes item back
+// unchanged, for read-modify-write updates.
+func updateInputFromItem(item ItemOut) UpdateItemInput {
+	input := UpdateItemInput{
+		ID:                      item.ID,
+		Archived:                item.Archived,
+		AssetID:                 item.AssetID,
+		Description:             item.Description,
+		Fields:                  item.Fields,
+		Insured:                 item.Insured,
+		LabelIDs:                []string{},
+		LifetimeWarranty:        item.LifetimeWarranty,
+		Manufacturer:            item.Manufacturer,
+		ModelNumber:             item.ModelNumber,
+		Name:                    item.Name,
+		Notes:                   item.Notes,
+		PurchaseFrom:            item.PurchaseFrom,
+		PurchasePrice:           item.PurchasePrice,
+		PurchaseTime:            item.PurchaseTime,
+		Quantity:                item.Quantity,
+		SerialNumber:            item.SerialNumber,
+		SoldNotes:               item.SoldNotes,
+		SoldPrice:               item.SoldPrice,
+		SoldTime:                item.SoldTime,
+		SoldTo:                  item.SoldTo,
+		SyncChildItemsLocations: item.SyncChildItemsLocations,
+		WarrantyDetails:         item.WarrantyDetails,
+		WarrantyExpires:         item.WarrantyExpires,
+	}
+	for _, label := range item.Labels {
+		input.LabelIDs = append(input.LabelIDs, label.ID)
+	}
+	if item.Location != nil {
+		input.LocationID = item.Location.ID
+	}
+	if item.Parent != nil {
+		input.ParentID = item.Parent.ID
+	}
+	return input
+}
+
+func bulkCreateOutput(results []BulkItemResult, rolledBack bool) CreateItemsBulkOutput {
+	out := CreateItemsBulkOutput{RolledBack: rolledBack, Results: results}
+	for _, result := range results {
+		switch result.Status {
+		case "created":
+			out.Created++
+		case "failed":
+			out.Failed++
+		}
+	}
+	return out
+}
+
+// update_items_bulk refuses to change more items than this unless the caller
+// raises maxAffected.
+const defaultMaxAffected = 50
+
+// ItemSelection selects the items a bulk update applies to: the listed IDs,
+// or the items matching every given search criterion.
+type ItemSelection struct {
+	IDs             []string `json:"ids,omitempty" jsonschema:"Items to update, by ID, asset ID or name. Takes the place of the search criteria"`
+	Query           string   `json:"query,omitempty" jsonschema:"Text to search for in item names, descriptions and other fields"`
+	Labels          []string `json:"labels,omitempty" jsonschema:"Only items with all of these labels, by ID or name"`
+	Location        string   `json:"location,omitempty" jsonschema:"Only items in this location, by ID, name or path"`
+	Parent          string   `json:"parent,omitempty" jsonschema:"Only items nested under this item, by ID, asset ID or name"`
+	IncludeArchived bool     `json:"includeArchived,omitempty" jsonschema:"Also select archived items"`
+}
+
+// ItemPatch is the change a bulk update makes to each selected item. Fields
+// that are not set are left as they are.
+type ItemPatch struct {
+	LocationID   string      `json:"locationId,omitempty" jsonschema:"Move the items to this location, by ID, name or path"`
+	AddLabels    []string    `json:"addLabels,omitempty" jsonschema:"Labels to add, by ID or name"`
+	RemoveLabels []string    `json:"removeLabels,omitempty" jsonschema:"Labels to remove, by ID or name"`
+	Insured      *bool       `json:"insured,omitempty" jsonschema:"Set whether the items are insured"`
+	Archived     *bool       `json:"archived,omitempty" jsonschema:"Archive or unarchive the items"`
+	SetFields    []ItemField `json:"setFields,omitempty" jsonschema:"Custom fields to set, by name. Fields the items do not have yet are added"`
+}
+
+// Input for the update_items_bulk tool.
+type UpdateItemsBulkInput struct {
+	Select        ItemSelection `json:"select" jsonschema:"Which items to update"`
+	Patch         ItemPatch     `json:"patch" jsonschema:"What to change on each item"`
+	DryRun        bool          `json:"dryRun,omitempty" jsonschema:"Report what would change without changing anything"`
+	MaxAffected   int           `json:"maxAffected,omitempty" jsonschema:"Fail without changing anything if more items than this are selected, 50 by default"`
+	CreateMissing bool          `json:"createMissing,omitempty" jsonschema:"Create the location and labels that do not exist yet"`
+	Concurrency   int           `json:"concurrency,omitempty" jsonschema:"How many items to update at once, 4 by default and at most 16"`
+}
+
+// Output for the update_items_bulk tool.
+type UpdateItemsBulkOutput struct {
+	Matched   int              `json:"matched" jsonschema:"Number of items selected"`
+	Updated   int              `json:"updated" jsonschema:"Number of items changed, or that would be changed in a dry run"`
+	Unchanged int              `json:"unchanged" jsonschema:"Number of selected items the patch does not change"`
+	Failed    int              `json:"failed" jsonschema:"Number of items that could not be updated"`
+	DryRun    bool             `json:"dryRun" jsonschema:"Whether nothing was changed"`
+	Results   []BulkItemResult `json:"results" jsonschema:"Outcome for each selected item"`
+}
+
+// updateItemsBulk is the implementation of the "update_items_bulk" tool. Each
+// selected item is read, patched and written back through update_item, so
+// the fields the patch does not touch are kept.
+func updateItemsBulk(ctx context.Context, req *mcp.CallToolRequest, input UpdateItemsBulkInput) (*mcp.CallToolResult, UpdateItemsBulkOutput, error) {
+	maxAffected := input.MaxAffected
+	if maxAffected <= 0 {
+		maxAffected = defaultMaxAffected
+	}
+
+	// Resolve the patch once, rather than for every item. names maps the
+	// resolved IDs back to the references, to describe the changes.
+	patch := input.Patch
+	names := make(map[string]string)
+	resolve := func(ref string, resolveRef func(createMissing bool) (string, error)) (string, error) {
+		if ref == "" {
+			return "", nil
+		}
+		id, err := resolveRef(input.CreateMissing && !input.DryRun)
+		// A dry run does not create anything, so references the real run
+		// would create are kept as they are.
+		if refErr, ok := err.(*referenceError); ok && refErr.Kind == "unknown_reference" && input.DryRun && input.CreateMissing {
+			id, err = ref, nil
+		}
+		names[id] = ref
+		return id, err
+	}
+	var err error
+	patch.LocationID, err = resolve(patch.LocationID, func(createMissing bool) (string, error) {
+		return resolveLocationRef(ctx, "patch.locationId", patch.LocationID, createMissing)
+	})
+	if err != nil {
+		return nil, UpdateItemsBulkOutput{}, err
+	}
+	resolveLabels := func(field string, refs []string, canCreate bool) ([]string, error) {
+		ids := make([]string, len(refs))
+		for i, ref := range refs {
+			id, err := resolve(ref, func(createMissing bool) (string, error) {
+				resolved := []string{ref}
+				err := resolveLabelRefs(ctx, field, resolved, createMissing && canCreate)
+				return resolved[0], err
+			})
+			if err != nil {
+				return nil, err
+			}
+			ids[i] = id
+		}
+		return ids, nil
+	}
+	if patch.AddLabels, err = resolveLabels("patch.addLabels", patch.AddLabels, true); err != nil {
+		return nil, UpdateItemsBulkOutput{}, err
+	}
+	if patch.RemoveLabels, err = resolveLabels("patch.removeLabels", patch.RemoveLabels, false); err != nil {
+		return nil, UpdateItemsBulkOutput{}, err
+	}
+
+	ids, err := selectItems(ctx, input.Select)
+	if err != nil {
+		return nil, UpdateItemsBulkOutput{}, err
+	}
+	if len(ids) > maxAffected && !input.DryRun {
+		return nil, UpdateItemsBulkOutput{}, fmt.Errorf("%d items selected, more than maxAffected (%d); narrow the selection or raise maxAffected", len(ids), maxAffected)
+	}
+
+	progress := newToolProgress(req)
+	progress.Logf(ctx, "info", "Updating %d items", len(ids))
+	results := make([]BulkItemResult, len(ids))
+	var mu sync.Mutex
+	done := 0
+	forEachBounded(len(ids), bulkConcurrency(input.Concurrency, len(ids)), func(i int) {
+		result := patchItem(ctx, ids[i], patch, names, input.DryRun)
+		result.Index = i
+		mu.Lock()
+		defer mu.Unlock()
+		results[i] = result
+		done++
+		progress.Report(ctx, float64(done), float64(len(ids)), fmt.Sprintf("Updated %d of %d items", done, len(ids)))
+	})
+	if !input.DryRun {
+		inventory.Invalidate()
+	}
+
+	out := UpdateItemsBulkOutput{Matched: len(ids), DryRun: input.DryRun, Results: results}
+	for _, result := range results {
+		switch result.Status {
+		case "updated", "would_update":
+			out.Updated++
+		case "unchanged":
+			out.Unchanged++
+		case "failed":
+			out.Failed++
+		}
+	}
+	return nil, out, nil
+}
+
+// selectItems returns the IDs of the items selection selects.
+func selectItems(ctx context.Context, selection ItemSelection) ([]string, error) {
+	if len(selection.IDs) > 0 {
+		ids := make([]string, 0, len(selection.IDs))
+		seen := make(map[string]bool)
+		for _, ref := range selection.IDs {
+			id, err := resolveItemRef(ctx, "select.ids", ref)
+			if err != nil {
+				return nil, err
+			}
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+		return ids, nil
+	}
+
+	query := url.Values{}
+	if selection.Query != "" {
+		query.Set("q", selection.Query)
+	}
+	if len(selection.Labels) > 0 {
+		labels := append([]string(nil), selection.Labels...)
+		if err := resolveLabelRefs(ctx, "select.labels", labels, false); err != nil {
+			return nil, err
+		}
+		query["labels"] = labels
+	}
+	if selection.Location != "" {
+		id, err := resolveLocationRef(ctx, "select.location", selection.Location, false)
+		if err != nil {
+			return nil, err
+		}
+		query.Set("locations", id)
+	}
+	if selection.Parent != "" {
+		id, err := resolveItemRef(ctx, "select.parent", selection.Parent)
+		if err != nil {
+			return nil, err
+		}
+		query.Set("parentIds", id)
+	}
+	if len(query) == 0 {
+		return nil, fmt.Errorf("select needs ids or at least one of query, labels, location and parent")
+	}
+	if selection.IncludeArchived {
+		query.Set("includeArchived", "true")
+	}
+
+	items, err := queryAllItems(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	return ids, nil
+}
+
+// queryAllItems returns the items matching query from every page.
+func queryAllItems(ctx context.Context, query url.Values) ([]ItemSummary, error) {
+	query = maps.Clone(query)
+	query.Set("pageSize", strconv.Itoa(resourcePageSize))
+	var items []ItemSummary
+	for page := 1; ; page++ {
+		query.Set("page", strconv.Itoa(page))
+		out, err := queryItems(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, out.Items...)
+		if page*resourcePageSize >= out.Total || len(out.Items) == 0 {
+			return items, nil
+		}
+	}
+}
+
+// readItems reads the full item of each summary, a few at a time, reporting
+// progress to req. The search results lack attachments, serial numbers,
+// warranties and purchase details.
+func readItems(ctx context.Context, req *mcp.CallToolRequest, summaries []ItemSummary) ([]ItemOut, error) {
+	progress := newToolProgress(req)
+	items := make([]ItemOut, len(summaries))
+	errs := make([]error, len(summaries))
+	var mu sync.Mutex
+	done := 0
+	forEachBounded(len(summaries), bulkConcurrency(0, len(summaries)), func(i int) {
+		_, item, err := getItem(ctx, nil, GetItemInput{ID: summaries[i].ID})
+		mu.Lock()
+		defer mu.Unlock()
+		items[i], errs[i] = item, err
+		done++
+		progress.Report(ctx, float64(done), float64(len(summaries)), fmt.Sprintf("Read %d of %d items", done, len(summaries)))
+	})
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+// patchItem applies patch, whose references are resolved, to the item with
+// the given ID and reports what changed. names maps the IDs in patch to the
+// references they were given as.
+func patchItem(ctx context.Context, id string, patch ItemPatch, names map[string]string, dryRun bool) BulkItemResult {
+	_, item, err := getItem(ctx, nil, GetItemInput{ID: id})
+	if err != nil {
+		return BulkItemResult{ID: id, Status: "failed", Error: err.Error()}
+	}
+	result := BulkItemResult{ID: item.ID, AssetID: item.AssetID, Name: item.Name}
+	update := updateInputFromItem(item)
+
+	if patch.LocationID != "" && patch.LocationID != update.LocationID {
+		from := "none"
+		if item.Location != nil {
+			from = item.Location.Name
+		}
+		result.Changes = append(result.Changes, fmt.Sprintf("location: %s -> %s", from, names[patch.LocationID]))
+		update.LocationID = patch.LocationID
+	}
+
+	for _, label := range patch.RemoveLabels {
+		if i := slices.Index(update.LabelIDs, label); i >= 0 {
+			update.LabelIDs = slices.Delete(update.LabelIDs, i, i+1)
+			result.Changes = append(result.Changes, fmt.Sprintf("remove label %s", names[label]))
+		}
+	}
+	for _, label := range patch.AddLabels {
+		if !slices.Contains(update.LabelIDs, label) {
+			update.LabelIDs = append(update.LabelIDs, label)
+			result.Changes = append(result.Changes, fmt.Sprintf("add label %s", names[label]))
+		}
+	}
+
+	if patch.Insured != nil && *patch.Insured != update.Insured {
+		update.Insured = *patch.Insured
+		result.Changes = append(result.Changes, fmt.Sprintf("insured: %t -> %t", !update.Insured, update.Insured))
+	}
+	if patch.Archived != nil && *patch.Archived != update.Archived {
+		update.Archived = *patch.Archived
+		result.Changes = append(result.Changes, fmt.Sprintf("archived: %t -> %t", !update.Archived, update.Archived))
+	}
+
+	update.Fields = slices.Clone(update.Fields)
+	for _, field := range patch.SetFields {
+		i := slices.IndexFunc(update.Fields, func(f ItemField) bool { return strings.EqualFold(f.Name, field.Name) })
+		if i < 0 {
+			if field.Type == "" {
+				field.Type = "text"
+			}
+			update.Fields = append(update.Fields, field)
+			result.Changes = append(result.Changes, fmt.Sprintf("add field %s", field.Name))
+			continue
+		}
+		existing := update.Fields[i]
+		if existing.TextValue == field.TextValue && existing.NumberValue == field.NumberValue && existing.BooleanValue == field.BooleanValue {
+			continue
+		}
+		existing.TextValue, existing.NumberValue, existing.BooleanValue = field.TextValue, field.NumberValue, field.BooleanValue
+		update.Fields[i] = existing
+		result.Changes = append(result.Changes, fmt.Sprintf("set field %s", existing.Name))
+	}
+
+	switch {
+	case len(result.Changes) == 0:
+		result.Status = "unchanged"
+	case dryRun:
+		result.Status = "would_update"
+	default:
+		if _, _, err := updateItem(ctx, nil, update); err != nil {
+			result.Status, result.Error = "failed", err.Error()
+		} else {
+			result.Status = "updated"
+		}
+	}
+	return result
+}
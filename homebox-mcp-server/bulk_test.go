@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeItemStore is a fake Homebox that creates, reads, updates and deletes
+// items, with their attachments and maintenance entries, and fails to create
+// items named "broken". If labels is set, it also serves, updates and
+// deletes those labels.
+type fakeItemStore struct {
+	mu          sync.Mutex
+	items       map[string]ItemOut
+	labels      map[string]*LabelOut
+	files       map[string]string // attachment ID to content
+	maintenance map[string][]MaintenanceEntryWithDetails
+	deleted     []string
+	next        int
+}
+
+func newFakeItemStore() *fakeItemStore {
+	return &fakeItemStore{
+		items:       make(map[string]ItemOut),
+		files:       make(map[string]string),
+		maintenance: make(map[string][]MaintenanceEntryWithDetails),
+	}
+}
+
+func (s *fakeItemStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	segments := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/"), "/")
+	switch {
+	case r.URL.Path == "/api/v1/locations/tree":
+		w.Write([]byte(`[{"id":"00000000-0000-4000-9000-000000000001","name":"Garage","type":"location","children":[]}]`))
+	case r.URL.Path == "/api/v1/locations":
+		w.Write([]byte(`[{"id":"00000000-0000-4000-9000-000000000001","name":"Garage"}]`))
+	case r.URL.Path == "/api/v1/labels" && s.labels == nil:
+		w.Write([]byte(`[{"id":"00000000-0000-4000-a000-000000000001","name":"Tools"}]`))
+	case r.URL.Path == "/api/v1/labels":
+		labels := []*LabelOut{}
+		for _, label := range s.labels {
+			labels = append(labels, label)
+		}
+		sort.Slice(labels, func(i, j int) bool { return labels[i].ID < labels[j].ID })
+		json.NewEncoder(w).Encode(labels)
+	case len(segments) == 2 && segments[0] == "labels" && s.labels[segments[1]] != nil:
+		if r.Method == http.MethodDelete {
+			delete(s.labels, segments[1])
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		var input UpdateLabelInput
+		json.NewDecoder(r.Body).Decode(&input)
+		label := s.labels[segments[1]]
+		label.Name, label.Description, label.Color = input.Name, input.Description, input.Color
+		json.NewEncoder(w).Encode(label)
+	case r.URL.Path == "/api/v1/maintenance":
+		status := r.URL.Query().Get("status")
+		entries := []MaintenanceEntryWithDetails{}
+		for itemID, log := range s.maintenance {
+			for _, entry := range log {
+				completed := dateOnly(entry.CompletedDate) != ""
+				if status == "both" || completed == (status == "completed") {
+					entry.ItemName = s.items[itemID].Name
+					entries = append(entries, entry)
+				}
+			}
+		}
+		sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+		json.NewEncoder(w).Encode(entries)
+	case len(segments) == 2 && segments[0] == "maintenance":
+		for itemID, entries := range s.maintenance {
+			i := slices.IndexFunc(entries, func(e MaintenanceEntryWithDetails) bool { return e.ID == segments[1] })
+			if i < 0 {
+				continue
+			}
+			if r.Method == http.MethodDelete {
+				s.maintenance[itemID] = slices.Delete(entries, i, i+1)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			var input UpdateMaintenanceEntryInput
+			json.NewDecoder(r.Body).Decode(&input)
+			entry := &entries[i]
+			entry.Name, entry.Description, entry.Cost = input.Name, input.Description, input.Cost
+			entry.CompletedDate, entry.ScheduledDate = input.CompletedDate, input.ScheduledDate
+			json.NewEncoder(w).Encode(entry)
+			return
+		}
+		http.NotFound(w, r)
+	case r.URL.Path == "/api/v1/items" && r.Method == http.MethodGet:
+		query := r.URL.Query()
+		result := PaginationResult_ItemSummary{Page: 1, PageSize: 100, Items: []ItemSummary{}}
+		for _, item := range s.items {
+			if !strings.Contains(strings.ToLower(item.Name), strings.ToLower(query.Get("q"))) {
+				continue
+			}
+			if location := query.Get("locations"); location != "" && (item.Location == nil || item.Location.ID != location) {
+				continue
+			}
+			if label := query.Get("labels"); label != "" && !slices.ContainsFunc(item.Labels, func(l LabelSummary) bool { return l.ID == label }) {
+				continue
+			}
+			result.Items = append(result.Items, ItemSummary{ID: item.ID, Name: item.Name, AssetID: item.AssetID, Location: item.Location, Labels: item.Labels})
+		}
+		sort.Slice(result.Items, func(i, j int) bool { return result.Items[i].ID < result.Items[j].ID })
+		result.Total = len(result.Items)
+		json.NewEncoder(w).Encode(result)
+	case r.URL.Path == "/api/v1/items" && r.Method == http.MethodPost:
+		var input CreateItemInput
+		json.NewDecoder(r.Body).Decode(&input)
+		if input.Name == "broken" {
+			http.Error(w, "cannot create", http.StatusInternalServerError)
+			return
+		}
+		s.next++
+		item := ItemOut{ID: fmt.Sprintf("00000000-0000-4000-8000-%012d", s.next), Name: input.Name, AssetID: fmt.Sprintf("000-%03d", s.next), Quantity: input.Quantity}
+		s.items[item.ID] = item
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(ItemSummary{ID: item.ID, Name: item.Name, AssetID: item.AssetID})
+	case len(segments) == 2 && segments[0] == "items":
+		item, ok := s.items[segments[1]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			json.NewEncoder(w).Encode(item)
+		case http.MethodPut:
+			var input UpdateItemInput
+			json.NewDecoder(r.Body).Decode(&input)
+			item.Name, item.Fields, item.Insured, item.Archived, item.Quantity = input.Name, input.Fields, input.Insured, input.Archived, input.Quantity
+			item.Location = &LocationSummary{ID: input.LocationID}
+			item.Parent = nil
+			if input.ParentID != "" {
+				item.Parent = &ItemSummary{ID: input.ParentID}
+			}
+			item.Labels = nil
+			for _, id := range input.LabelIDs {
+				label := LabelSummary{ID: id}
+				if s.labels[id] != nil {
+					label.Name = s.labels[id].Name
+				}
+				item.Labels = append(item.Labels, label)
+			}
+			s.items[item.ID] = item
+			json.NewEncoder(w).Encode(item)
+		case http.MethodDelete:
+			delete(s.items, item.ID)
+			s.deleted = append(s.deleted, item.ID)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	case len(segments) == 3 && segments[2] == "attachments":
+		file, _, err := r.FormFile("file")
+		if err != nil || r.FormValue("type") == "" {
+			http.Error(w, "bad upload", http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(file)
+		s.next++
+		attachment := ItemAttachment{ID: fmt.Sprintf("att%d", s.next), Title: r.FormValue("name"), Type: r.FormValue("type"), Primary: r.FormValue("primary") == "true"}
+		s.files[attachment.ID] = string(data)
+		item := s.items[segments[1]]
+		item.Attachments = append(item.Attachments, attachment)
+		s.items[item.ID] = item
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(item)
+	case len(segments) == 4 && segments[2] == "attachments" && r.Method == http.MethodDelete:
+		item := s.items[segments[1]]
+		item.Attachments = slices.DeleteFunc(item.Attachments, func(a ItemAttachment) bool { return a.ID == segments[3] })
+		s.items[item.ID] = item
+		delete(s.files, segments[3])
+		w.WriteHeader(http.StatusNoContent)
+	case len(segments) == 4 && segments[2] == "attachments":
+		data, ok := s.files[segments[3]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(data))
+	case len(segments) == 3 && segments[2] == "maintenance" && r.Method == http.MethodGet:
+		entries := s.maintenance[segments[1]]
+		if entries == nil {
+			entries = []MaintenanceEntryWithDetails{}
+		}
+		json.NewEncoder(w).Encode(entries)
+	case len(segments) == 3 && segments[2] == "maintenance":
+		var entry MaintenanceEntryWithDetails
+		json.NewDecoder(r.Body).Decode(&entry)
+		s.next++
+		entry.ID, entry.ItemID = fmt.Sprintf("m%d", s.next), segments[1]
+		s.maintenance[segments[1]] = append(s.maintenance[segments[1]], entry)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(entry)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestCreateItemsBulk(t *testing.T) {
+	store := newFakeItemStore()
+	homebox := httptest.NewServer(store)
+	defer homebox.Close()
+
+	os.Setenv("HOMEBOX_URL", homebox.URL)
+	os.Setenv("HOMEBOX_TOKEN", "test-token")
+	ctx := context.Background()
+
+	_, out, err := createItemsBulk(ctx, nil, CreateItemsBulkInput{Items: []BulkItemRow{
+		{Name: "Drill", Fields: []ItemField{{Name: "Voltage", Type: "text", TextValue: "18V"}}},
+		{Name: "broken"},
+		{Name: "Saw", Photo: base64.StdEncoding.EncodeToString([]byte("jpeg")), PhotoName: "saw.jpg"},
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, out.Created)
+	assert.Equal(t, 1, out.Failed)
+	assert.False(t, out.RolledBack)
+	assert.Equal(t, "created", out.Results[0].Status)
+	assert.Equal(t, "failed", out.Results[1].Status)
+	assert.Contains(t, out.Results[1].Error, "cannot create")
+	assert.Equal(t, "created", out.Results[2].Status)
+
+	assert.Equal(t, "18V", store.items[out.Results[0].ID].Fields[0].TextValue)
+	photo := store.items[out.Results[2].ID].Attachments[0]
+	assert.Equal(t, ItemAttachment{ID: photo.ID, Title: "saw.jpg", Type: "photo", Primary: true}, photo)
+	assert.Equal(t, "jpeg", store.files[photo.ID])
+}
+
+func TestCreateItemsBulkRollback(t *testing.T) {
+	store := newFakeItemStore()
+	homebox := httptest.NewServer(store)
+	defer homebox.Close()
+
+	os.Setenv("HOMEBOX_URL", homebox.URL)
+	os.Setenv("HOMEBOX_TOKEN", "test-token")
+	ctx := context.Background()
+
+	_, out, err := createItemsBulk(ctx, nil, CreateItemsBulkInput{
+		Items:           []BulkItemRow{{Name: "Drill"}, {Name: "broken"}, {Name: "Saw"}},
+		RollbackOnError: true,
+		Concurrency:     1,
+	})
+	assert.NoError(t, err)
+	assert.True(t, out.RolledBack)
+	assert.Equal(t, 0, out.Created)
+	assert.Equal(t, 1, out.Failed)
+	assert.Equal(t, []string{"rolled_back", "failed", "rolled_back"}, []string{out.Results[0].Status, out.Results[1].Status, out.Results[2].Status})
+	assert.Len(t, store.deleted, 2)
+	assert.Empty(t, store.items)
+
+	// A row that cannot be prepared stops the call before anything is created.
+	_, out, err = createItemsBulk(ctx, nil, CreateItemsBulkInput{
+		Items:           []BulkItemRow{{Name: "Drill"}, {Name: "Saw", Photo: "not base64!"}},
+		RollbackOnError: true,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, out.Failed)
+	assert.Contains(t, out.Results[1].Error, "decode photo")
+	assert.Empty(t, store.items)
+}
+
+func TestUpdateItemsBulk(t *testing.T) {
+	store := newFakeItemStore()
+	homebox := httptest.NewServer(store)
+	defer homebox.Close()
+
+	os.Setenv("HOMEBOX_URL", homebox.URL)
+	os.Setenv("HOMEBOX_TOKEN", "test-token")
+	inventory.Invalidate()
+	defer inventory.Invalidate()
+	ctx := context.Background()
+
+	for i, name := range []string{"Drill", "Drill bits", "Saw"} {
+		id := fmt.Sprintf("00000000-0000-4000-8000-%012d", i+1)
+		store.items[id] = ItemOut{ID: id, Name: name, Fields: []ItemField{{ID: "f1", Name: "Voltage", Type: "text", TextValue: "12V"}}}
+	}
+	insured := true
+	input := UpdateItemsBulkInput{
+		Select: ItemSelection{Query: "drill"},
+		Patch: ItemPatch{
+			LocationID: "Garage",
+			AddLabels:  []string{"tools"},
+			Insured:    &insured,
+			SetFields:  []ItemField{{Name: "voltage", TextValue: "18V"}},
+		},
+		DryRun: true,
+	}
+
+	_, out, err := updateItemsBulk(ctx, nil, input)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, out.Matched)
+	assert.Equal(t, 2, out.Updated)
+	assert.Equal(t, "would_update", out.Results[0].Status)
+	assert.Equal(t, []string{"location: none -> Garage", "add label tools", "insured: false -> true", "set field Voltage"}, out.Results[0].Changes)
+	assert.Equal(t, "12V", store.items["00000000-0000-4000-8000-000000000001"].Fields[0].TextValue)
+
+	input.DryRun, input.MaxAffected = false, 1
+	_, _, err = updateItemsBulk(ctx, nil, input)
+	assert.ErrorContains(t, err, "2 items selected")
+
+	input.MaxAffected = 0
+	_, out, err = updateItemsBulk(ctx, nil, input)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, out.Updated)
+	for _, result := range out.Results {
+		item := store.items[result.ID]
+		assert.Equal(t, "updated", result.Status)
+		assert.True(t, item.Insured)
+		assert.Equal(t, "00000000-0000-4000-9000-000000000001", item.Location.ID)
+		assert.Equal(t, []LabelSummary{{ID: "00000000-0000-4000-a000-000000000001"}}, item.Labels)
+		assert.Equal(t, []ItemField{{ID: "f1", Name: "Voltage", Type: "text", TextValue: "18V"}}, item.Fields)
+	}
+	assert.False(t, store.items["00000000-0000-4000-8000-000000000003"].Insured)
+
+	// Running the same patch again changes nothing.
+	_, out, err = updateItemsBulk(ctx, nil, UpdateItemsBulkInput{Select: ItemSelection{IDs: []string{"Saw", "00000000-0000-4000-8000-000000000001"}}, Patch: input.Patch})
+	assert.NoError(t, err)
+	assert.Equal(t, "updated", out.Results[0].Status)
+	assert.Equal(t, "unchanged", out.Results[1].Status)
+
+	_, _, err = updateItemsBulk(ctx, nil, UpdateItemsBulkInput{Patch: input.Patch})
+	assert.ErrorContains(t, err, "select needs")
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// calendarUIDDomain is the domain part of the event UIDs. UIDs only depend on
+// the ID of what an event is for, so importing the calendar again updates
+// the events instead of adding them twice.
+const calendarUIDDomain = "homebox-mcp-server"
+
+// Kinds of calendar events.
+var calendarKinds = []string{"maintenance", "schedules", "warranties"}
+
+// Input for the export_calendar tool.
+type ExportCalendarInput struct {
+	Include          []string `json:"include,omitempty" jsonschema:"Which dates to export: maintenance (maintenance entries), schedules (recurring maintenance schedules) and warranties (warranty expiry dates). All by default"`
+	IncludeCompleted bool     `json:"includeCompleted,omitempty" jsonschema:"Also export completed maintenance entries, on the date they were completed"`
+}
+
+// Output for the export_calendar tool.
+type ExportCalendarOutput struct {
+	Events   int    `json:"events" jsonschema:"Number of events in the calendar"`
+	Calendar string `json:"calendar" jsonschema:"The iCalendar (.ics) document"`
+}
+
+// calendarEvent is an all-day event of the calendar.
+type calendarEvent struct {
+	uid         string
+	date        string
+	summary     string
+	description string
+	rrule       string
+	url         string
+}
+
+// exportCalendar is the implementation of the "export_calendar" tool.
+func exportCalendar(ctx context.Context, req *mcp.CallToolRequest, input ExportCalendarInput) (*mcp.CallToolResult, ExportCalendarOutput, error) {
+	events, err := calendarEvents(ctx, req, input)
+	if err != nil {
+		return nil, ExportCalendarOutput{}, err
+	}
+	return nil, ExportCalendarOutput{Events: len(events), Calendar: formatCalendar(events, time.Now())}, nil
+}
+
+// calendarEvents collects the events selected by input, sorted by date.
+func calendarEvents(ctx context.Context, req *mcp.CallToolRequest, input ExportCalendarInput) ([]calendarEvent, error) {
+	include, err := calendarInclude(input.Include)
+	if err != nil {
+		return nil, err
+	}
+	events := []calendarEvent{}
+
+	// The scheduled entry of a schedule is exported as the recurring event of
+	// the schedule instead.
+	scheduled := make(map[string]bool)
+	if slices.Contains(include, "schedules") {
+		list, err := schedules.list()
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range list {
+			if s.Status != scheduleActive || s.NextDate == "" {
+				continue
+			}
+			scheduled[s.EntryID] = true
+			events = append(events, scheduleEvent(s))
+		}
+	}
+
+	if slices.Contains(include, "maintenance") {
+		status := "scheduled"
+		if input.IncludeCompleted {
+			status = "both"
+		}
+		entries, err := getGroupMaintenance(ctx, status)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if scheduled[e.ID] {
+				continue
+			}
+			event := calendarEvent{
+				uid:         "maintenance-" + e.ID + "@" + calendarUIDDomain,
+				date:        dateOnly(e.ScheduledDate),
+				summary:     e.ItemName + ": " + e.Name,
+				description: e.Description,
+				url:         itemURL(e.ItemID),
+			}
+			if completed := dateOnly(e.CompletedDate); completed != "" {
+				event.date = completed
+				event.summary += " (done)"
+			}
+			if e.Cost != "" && parseCost(e.Cost) != 0 {
+				event.description = strings.TrimSpace(event.description + "\nCost: " + e.Cost)
+			}
+			if event.date != "" {
+				events = append(events, event)
+			}
+		}
+	}
+
+	if slices.Contains(include, "warranties") {
+		summaries, err := queryAllItems(ctx, url.Values{})
+		if err != nil {
+			return nil, err
+		}
+		items, err := readItems(ctx, req, summaries)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			expires := dateOnly(item.WarrantyExpires)
+			if item.LifetimeWarranty || expires == "" {
+				continue
+			}
+			events = append(events, calendarEvent{
+				uid:         "warranty-" + item.ID + "@" + calendarUIDDomain,
+				date:        expires,
+				summary:     "Warranty expires: " + item.Name,
+				description: item.WarrantyDetails,
+				url:         itemURL(item.ID),
+			})
+		}
+	}
+
+	slices.SortStableFunc(events, func(a, b calendarEvent) int {
+		if d := strings.Compare(a.date, b.date); d != 0 {
+			return d
+		}
+		return strings.Compare(a.uid, b.uid)
+	})
+	return events, nil
+}
+
+// calendarInclude checks the kinds of events to export, all by default.
+func calendarInclude(kinds []string) ([]string, error) {
+	if len(kinds) == 0 {
+		return calendarKinds, nil
+	}
+	include := make([]string, len(kinds))
+	for i, kind := range kinds {
+		include[i] = strings.ToLower(strings.TrimSpace(kind))
+		if !slices.Contains(calendarKinds, include[i]) {
+			return nil, fmt.Errorf("include must contain maintenance, schedules or warranties, got %q", kind)
+		}
+	}
+	return include, nil
+}
+
+// scheduleEvent returns the event of an active schedule, recurring from its
+// next date. Schedules that only count usage cannot be predicted and become
+// a single event.
+func scheduleEvent(s MaintenanceSchedule) calendarEvent {
+	event := calendarEvent{
+		uid:         "schedule-" + s.ID + "@" + calendarUIDDomain,
+		date:        s.NextDate,
+		summary:     s.ItemName + ": " + s.Task,
+		description: s.Description,
+		url:         itemURL(s.ItemID),
+	}
+	if s.UsageField != "" {
+		event.description = strings.TrimSpace(fmt.Sprintf("%s\nDue earlier once %s has grown by %g.", event.description, s.UsageField, s.UsageInterval))
+	}
+	if s.Recurrence == "" {
+		return event
+	}
+	r, err := parseRecurrence(s.Recurrence)
+	if err != nil {
+		return event
+	}
+	rule := fmt.Sprintf("FREQ=%s;INTERVAL=%d", r.freq, r.interval)
+	if r.count > 0 {
+		// The event starts at the current occurrence, so only the remaining
+		// ones are counted.
+		rule += fmt.Sprintf(";COUNT=%d", max(r.count-s.Occurrences+1, 1))
+	}
+	if r.until != "" {
+		rule += ";UNTIL=" + strings.ReplaceAll(r.until, "-", "")
+	}
+	event.rrule = rule
+	return event
+}
+
+// itemURL returns the link to an item in the Homebox web interface.
+func itemURL(id string) string {
+	homeboxURL := strings.TrimRight(os.Getenv("HOMEBOX_URL"), "/")
+	if homeboxURL == "" || id == "" {
+		return ""
+	}
+	return homeboxURL + "/item/" + id
+}
+
+// formatCalendar writes events as an iCalendar document (RFC 5545), stamped
+// with now.
+func formatCalendar(events []calendarEvent, now time.Time) string {
+	var b strings.Builder
+	line := func(content string) {
+		b.WriteString(foldCalendarLine(content))
+		b.WriteString("\r\n")
+	}
+	stamp := now.UTC().Format("20060102T150405Z")
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//homebox-mcp-server//Homebox calendar//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:Homebox")
+	for _, e := range events {
+		start, err := time.Parse(time.DateOnly, e.date)
+		if err != nil {
+			continue
+		}
+		line("BEGIN:VEVENT")
+		line("UID:" + e.uid)
+		line("DTSTAMP:" + stamp)
+		line("DTSTART;VALUE=DATE:" + start.Format("20060102"))
+		line("DTEND;VALUE=DATE:" + start.AddDate(0, 0, 1).Format("20060102"))
+		if e.rrule != "" {
+			line("RRULE:" + e.rrule)
+		}
+		line("SUMMARY:" + escapeCalendarText(e.summary))
+		if e.description != "" {
+			line("DESCRIPTION:" + escapeCalendarText(e.description))
+		}
+		if e.url != "" {
+			line("URL:" + e.url)
+		}
+		line("TRANSP:TRANSPARENT")
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return b.String()
+}
+
+// escapeCalendarText escapes a TEXT value of an iCalendar property.
+func escapeCalendarText(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(text)
+}
+
+// foldCalendarLine folds a content line into lines of at most 75 octets,
+// without splitting a UTF-8 character.
+func foldCalendarLine(content string) string {
+	var b strings.Builder
+	limit := 75
+	for len(content) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+		b.WriteString(content[:cut])
+		b.WriteString("\r\n ")
+		content = content[cut:]
+		// Continuation lines start with a space.
+		limit = 74
+	}
+	b.WriteString(content)
+	return b.String()
+}
+
+// calendarFeedHandler serves the calendar as a subscribable feed. Calendar
+// applications cannot send headers, so the token may also be given as the
+// token query parameter. The include and completed query parameters select
+// the events like the tool's input.
+func calendarFeedHandler(token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given := r.URL.Query().Get("token")
+		if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			given = bearer
+		}
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="homebox-calendar"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		input := ExportCalendarInput{IncludeCompleted: r.URL.Query().Get("completed") == "true"}
+		if include := r.URL.Query().Get("include"); include != "" {
+			input.Include = strings.Split(include, ",")
+			if _, err := calendarInclude(input.Include); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		events, err := calendarEvents(r.Context(), nil, input)
+		if err != nil {
+			log.Printf("Calendar feed error: %v", err)
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.Header().Set("Content-Disposition", `inline; filename="homebox.ics"`)
+		fmt.Fprint(w, formatCalendar(events, time.Now()))
+	})
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFoldCalendarLine(t *testing.T) {
+	assert.Equal(t, "SUMMARY:short", foldCalendarLine("SUMMARY:short"))
+	folded := foldCalendarLine("DESCRIPTION:" + strings.Repeat("é", 60))
+	for _, line := range strings.Split(folded, "\r\n") {
+		assert.LessOrEqual(t, len(line), 75)
+	}
+	assert.Equal(t, "DESCRIPTION:"+strings.Repeat("é", 60), strings.ReplaceAll(folded, "\r\n ", ""))
+	assert.Equal(t, `a\, b\; c\\d\ne`, escapeCalendarText("a, b; c\\d\ne"))
+}
+
+func TestExportCalendar(t *testing.T) {
+	store := newFakeItemStore()
+	store.items[item1ID] = ItemOut{ID: item1ID, Name: "Mower", WarrantyExpires: "2027-03-01T00:00:00Z", WarrantyDetails: "Call the shop"}
+	store.items[item2ID] = ItemOut{ID: item2ID, Name: "Heater", WarrantyExpires: "2027-01-01", LifetimeWarranty: true}
+	store.maintenance[item1ID] = []MaintenanceEntryWithDetails{
+		{ID: "e1", ItemID: item1ID, Name: "Oil change", Cost: "40", ScheduledDate: "2026-11-02"},
+		{ID: "e2", ItemID: item1ID, Name: "Sharpen blade", CompletedDate: "2026-05-01", ScheduledDate: "0001-01-01"},
+	}
+	homebox := httptest.NewServer(store)
+	defer homebox.Close()
+	os.Setenv("HOMEBOX_URL", homebox.URL)
+	os.Setenv("HOMEBOX_TOKEN", "test-token")
+	t.Setenv("HOMEBOX_SCHEDULE_FILE", filepath.Join(t.TempDir(), "schedules.json"))
+	ctx := context.Background()
+
+	_, out, err := exportCalendar(ctx, nil, ExportCalendarInput{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, out.Events)
+	assert.True(t, strings.HasPrefix(out.Calendar, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.Contains(t, out.Calendar, "UID:maintenance-e1@homebox-mcp-server\r\n")
+	assert.Contains(t, out.Calendar, "DTSTART;VALUE=DATE:20261102\r\nDTEND;VALUE=DATE:20261103\r\n")
+	assert.Contains(t, out.Calendar, "SUMMARY:Mower: Oil change\r\nDESCRIPTION:Cost: 40\r\n")
+	assert.Contains(t, out.Calendar, "UID:warranty-"+item1ID+"@homebox-mcp-server\r\n")
+	assert.Contains(t, out.Calendar, "DTSTART;VALUE=DATE:20270301\r\n")
+	assert.NotContains(t, out.Calendar, "Heater")
+	assert.NotContains(t, out.Calendar, "Sharpen blade")
+	assert.Less(t, strings.Index(out.Calendar, "maintenance-e1"), strings.Index(out.Calendar, "warranty-"))
+
+	_, out, err = exportCalendar(ctx, nil, ExportCalendarInput{Include: []string{"Maintenance"}, IncludeCompleted: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, out.Events)
+	assert.Contains(t, out.Calendar, "SUMMARY:Mower: Sharpen blade (done)\r\n")
+
+	// The entry of a schedule is exported as the schedule's recurring event.
+	_, schedule, err := createMaintenanceSchedule(ctx, nil, CreateMaintenanceScheduleInput{
+		Item: item1ID, Task: "Inspection", Recurrence: "FREQ=MONTHLY;INTERVAL=6;COUNT=4", Start: "2026-12-01",
+	})
+	assert.NoError(t, err)
+	_, out, err = exportCalendar(ctx, nil, ExportCalendarInput{Include: []string{"maintenance", "schedules"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, out.Events)
+	assert.Contains(t, out.Calendar, "UID:schedule-"+schedule.ID+"@homebox-mcp-server\r\n")
+	assert.Contains(t, out.Calendar, "RRULE:FREQ=MONTHLY;INTERVAL=6;COUNT=4\r\n")
+	assert.NotContains(t, out.Calendar, "maintenance-"+schedule.EntryID+"@")
+
+	// UIDs stay the same from one export to the next.
+	_, again, err := exportCalendar(ctx, nil, ExportCalendarInput{Include: []string{"maintenance", "schedules"}})
+	assert.NoError(t, err)
+	uids := func(calendar string) []string {
+		var uids []string
+		for _, line := range strings.Split(calendar, "\r\n") {
+			if strings.HasPrefix(line, "UID:") {
+				uids = append(uids, line)
+			}
+		}
+		return uids
+	}
+	assert.Equal(t, uids(out.Calendar), uids(again.Calendar))
+
+	_, _, err = exportCalendar(ctx, nil, ExportCalendarInput{Include: []string{"birthdays"}})
+	assert.ErrorContains(t, err, "include must contain")
+}
+
+func TestCalendarFeed(t *testing.T) {
+	store := newFakeItemStore()
+	store.maintenance[item1ID] = []MaintenanceEntryWithDetails{
+		{ID: "m1", ItemID: item1ID, ItemName: "Mower", Name: "Oil change", ScheduledDate: time.Now().Format(time.DateOnly)},
+	}
+	homebox := httptest.NewServer(store)
+	defer homebox.Close()
+	os.Setenv("HOMEBOX_URL", homebox.URL)
+	os.Setenv("HOMEBOX_TOKEN", "test-token")
+	feed := httptest.NewServer(calendarFeedHandler("secret"))
+	defer feed.Close()
+
+	get := func(path string, header string) *http.Response {
+		req, _ := http.NewRequest("GET", feed.URL+path, nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+	assert.Equal(t, http.StatusUnauthorized, get("/calendar.ics", "").StatusCode)
+	assert.Equal(t, http.StatusUnauthorized, get("/calendar.ics?token=wrong", "").StatusCode)
+	assert.Equal(t, http.StatusBadRequest, get("/calendar.ics?token=secret&include=birthdays", "").StatusCode)
+
+	resp := get("/calendar.ics?include=maintenance", "Bearer secret")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/calendar; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.Equal(t, http.StatusOK, get("/calendar.ics?token=secret&include=maintenance", "").StatusCode)
+}
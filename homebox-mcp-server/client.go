@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxUpstreamRetries is how many times an idempotent request is retried
+	// after a network error or a 502, 503 or 504 response.
+	maxUpstreamRetries = 2
+	// retryBackoff is the wait before the first retry; it doubles for each
+	// retry after that.
+	retryBackoff = 200 * time.Millisecond
+
+	// breakerThreshold is the number of consecutive failed requests that
+	// opens the circuit breaker.
+	breakerThreshold = 5
+	// breakerCooldown is how long the breaker stays open before it lets a
+	// single request through to probe Homebox.
+	breakerCooldown = 30 * time.Second
+)
+
+// States of the circuit breaker, as exported by homebox_upstream_circuit_state.
+const (
+	circuitClosed   = 0
+	circuitOpen     = 1
+	circuitHalfOpen = 2
+)
+
+// errCircuitOpen is returned without calling Homebox while the breaker is open.
+var errCircuitOpen = errors.New("Homebox is unavailable: too many failed requests, retrying later")
+
+// homeboxClient is the HTTP client used for every request to the Homebox API.
+// Its transport lets cross-cutting concerns such as auditing, metrics and
+// tracing observe the outbound calls made on behalf of a tool.
+var homeboxClient = &http.Client{Transport: &homeboxTransport{base: http.DefaultTransport, breaker: upstreamBreaker}}
+
+// upstreamBreaker is the circuit breaker shared by every request to Homebox.
+var upstreamBreaker = &circuitBreaker{}
+
+// homeboxTransport wraps an http.RoundTripper, retries idempotent requests
+// that fail because Homebox is unavailable, stops calling it while the
+// circuit breaker is open, records upstream metrics and spans, and reports
+// each request to the tool call it was made for.
+type homeboxTransport struct {
+	base    http.RoundTripper
+	breaker *circuitBreaker
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *homeboxTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := endpointPattern(req.URL.Path)
+	call := toolCallFromContext(req.Context())
+	if call != nil {
+		call.addEndpoint(req.Method + " " + req.URL.Path)
+	}
+
+	var (
+		resp *http.Response
+		err  error
+	)
+	for attempt := 0; ; attempt++ {
+		if !t.breaker.allow() {
+			resp, err = nil, errCircuitOpen
+			break
+		}
+		resp, err = t.attempt(req, endpoint)
+		if err != nil && req.Context().Err() != nil {
+			// The caller gave up; that says nothing about Homebox, so the
+			// request counts as neither a failure nor a success.
+			t.breaker.release()
+			break
+		}
+		failed := err != nil || unavailableStatus(resp.StatusCode)
+		t.breaker.record(failed)
+		if !failed || attempt == maxUpstreamRetries || !retryable(req) {
+			break
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		if req.GetBody != nil {
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return nil, bodyErr
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(retryBackoff << attempt):
+		}
+		upstreamRetriesTotal.WithLabelValues(endpoint).Inc()
+	}
+
+	if call != nil {
+		if err != nil {
+			call.addStatus(0)
+		} else {
+			call.addStatus(resp.StatusCode)
+		}
+	}
+	return resp, err
+}
+
+// attempt sends req once, recording its metrics and span.
+func (t *homeboxTransport) attempt(req *http.Request, endpoint string) (*http.Response, error) {
+	req, span := startUpstreamSpan(req, endpoint)
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	observeUpstream(req.Method, endpoint, resp, err, time.Since(start))
+	endUpstreamSpan(span, resp, err)
+	return resp, err
+}
+
+// unavailableStatus reports whether a status code means Homebox, or a proxy
+// in front of it, could not handle the request at all.
+func unavailableStatus(code int) bool {
+	return code == http.StatusBadGateway || code == http.StatusServiceUnavailable || code == http.StatusGatewayTimeout
+}
+
+// retryable reports whether req can be sent again: its method is idempotent
+// and its body, if any, can be read again.
+func retryable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	}
+	return false
+}
+
+// circuitBreaker stops requests to Homebox after breakerThreshold
+// consecutive failures. After breakerCooldown it lets one request through:
+// if that succeeds the breaker closes, otherwise it opens again.
+type circuitBreaker struct {
+	mu       sync.Mutex
+	state    int
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// allow reports whether a request may be sent now.
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case circuitOpen:
+		if time.Since(b.openedAt) < breakerCooldown {
+			return false
+		}
+		b.setState(circuitHalfOpen)
+		b.probing = true
+		return true
+	case circuitHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	return true
+}
+
+// record updates the breaker with the outcome of a request.
+func (b *circuitBreaker) record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if !failed {
+		b.failures = 0
+		b.setState(circuitClosed)
+		return
+	}
+	b.failures++
+	if b.state == circuitHalfOpen || b.failures >= breakerThreshold {
+		b.openedAt = time.Now()
+		b.setState(circuitOpen)
+	}
+}
+
+// release ends a request without an outcome, so that another probe can be
+// sent if it was one.
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// setState changes the state of the breaker and its gauge. b.mu is held.
+func (b *circuitBreaker) setState(state int) {
+	b.state = state
+	upstreamCircuitState.Set(float64(state))
+}
+
+// toolCall collects what happened upstream while a single tool call was handled.
+type toolCall struct {
+	mu        sync.Mutex
+	endpoints []string
+	statuses  []int // 0 for requests that failed without a response
+}
+
+func (c *toolCall) addEndpoint(endpoint string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.endpoints = append(c.endpoints, endpoint)
+}
+
+func (c *toolCall) addStatus(code int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.statuses = append(c.statuses, code)
+}
+
+// Endpoints returns the Homebox endpoints called so far.
+func (c *toolCall) Endpoints() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.endpoints...)
+}
+
+// errorClass classifies a failed tool call by what went wrong upstream.
+func (c *toolCall) errorClass() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	class := "tool"
+	for _, code := range c.statuses {
+		switch {
+		case code == 0:
+			return "network"
+		case code >= 500:
+			class = "upstream_5xx"
+		case code >= 400 && class != "upstream_5xx":
+			class = "upstream_4xx"
+		}
+	}
+	return class
+}
+
+type toolCallKey struct{}
+
+// withToolCall returns a context tracking a tool call, reusing one that is
+// already being tracked.
+func withToolCall(ctx context.Context) (context.Context, *toolCall) {
+	if call := toolCallFromContext(ctx); call != nil {
+		return ctx, call
+	}
+	call := &toolCall{}
+	return context.WithValue(ctx, toolCallKey{}, call), call
+}
+
+// toolCallFromContext returns the tool call tracked in ctx, if any.
+func toolCallFromContext(ctx context.Context) *toolCall {
+	call, _ := ctx.Value(toolCallKey{}).(*toolCall)
+	return call
+}
+
+// idSegment matches path segments that identify a single entity: UUIDs,
+// numbers and asset IDs such as 000-123.
+var idSegment = regexp.MustCompile(`^([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|[0-9]+(-[0-9]+)?)$`)
+
+// endpointPattern replaces entity IDs in an API path with {id} so it can be
+// used as a low-cardinality metric label.
+func endpointPattern(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if idSegment.MatchString(segment) {
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// maxCompletions is the most values a completion/complete result may hold.
+const maxCompletions = 100
+
+// completeArgument is the server's CompletionHandler. It completes the ID
+// arguments of the resource templates and prompts from the inventory index,
+// matching the typed prefix against names, location paths, IDs and asset IDs.
+func completeArgument(ctx context.Context, req *mcp.CompleteRequest) (*mcp.CompleteResult, error) {
+	ref, arg := req.Params.Ref, req.Params.Argument
+	var complete func(*indexSnapshot, string) []string
+	switch {
+	case ref.Type == "ref/resource" && arg.Name == "attachmentId":
+		var itemID string
+		if req.Params.Context != nil {
+			itemID = req.Params.Context.Arguments["id"]
+		}
+		return completeAttachments(ctx, itemID, arg.Value)
+	case ref.Type == "ref/resource" && arg.Name == "id":
+		switch {
+		case strings.HasPrefix(ref.URI, resourceScheme+"items/"):
+			complete = completeItemIDs
+		case strings.HasPrefix(ref.URI, resourceScheme+"locations/"):
+			complete = completeLocationIDs
+		case strings.HasPrefix(ref.URI, resourceScheme+"labels/"):
+			complete = completeLabelIDs
+		}
+	case ref.Type == "ref/prompt" && arg.Name == "location_id":
+		complete = completeLocationIDs
+	case ref.Type == "ref/prompt" && arg.Name == "query":
+		complete = completeItemNames
+	}
+	if complete == nil {
+		return completionResult(nil), nil
+	}
+
+	snapshot, err := inventory.Snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return completionResult(complete(snapshot, strings.ToLower(arg.Value))), nil
+}
+
+// completionResult caps values at maxCompletions.
+func completionResult(values []string) *mcp.CompleteResult {
+	result := &mcp.CompleteResult{Completion: mcp.CompletionResultDetails{Values: []string{}, Total: len(values)}}
+	if len(values) > maxCompletions {
+		values = values[:maxCompletions]
+		result.Completion.HasMore = true
+	}
+	result.Completion.Values = append(result.Completion.Values, values...)
+	return result
+}
+
+// hasPrefix reports whether any of values starts with the lower-case prefix,
+// ignoring case.
+func hasPrefix(prefix string, values ...string) bool {
+	for _, value := range values {
+		if value != "" && strings.HasPrefix(strings.ToLower(value), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// completeItemIDs suggests item IDs by name or ID, and asset IDs when the
+// prefix looks like one.
+func completeItemIDs(snapshot *indexSnapshot, prefix string) []string {
+	var values []string
+	for _, item := range snapshot.Items {
+		if item.AssetID != "" && prefix != "" && strings.HasPrefix(item.AssetID, prefix) {
+			values = append(values, item.AssetID)
+		} else if hasPrefix(prefix, item.Name, item.ID) {
+			values = append(values, item.ID)
+		}
+	}
+	return values
+}
+
+// completeItemNames suggests item names.
+func completeItemNames(snapshot *indexSnapshot, prefix string) []string {
+	var values []string
+	seen := make(map[string]bool)
+	for _, item := range snapshot.Items {
+		if hasPrefix(prefix, item.Name) && !seen[item.Name] {
+			seen[item.Name] = true
+			values = append(values, item.Name)
+		}
+	}
+	return values
+}
+
+// completeLocationIDs suggests location IDs by name, path or ID.
+func completeLocationIDs(snapshot *indexSnapshot, prefix string) []string {
+	var values []string
+	for _, loc := range snapshot.Locations {
+		if hasPrefix(prefix, loc.Name, loc.Path, loc.ID) {
+			values = append(values, loc.ID)
+		}
+	}
+	return values
+}
+
+// completeLabelIDs suggests label IDs by name or ID.
+func completeLabelIDs(snapshot *indexSnapshot, prefix string) []string {
+	var values []string
+	for _, label := range snapshot.Labels {
+		if hasPrefix(prefix, label.Name, label.ID) {
+			values = append(values, label.ID)
+		}
+	}
+	return values
+}
+
+// completeAttachments suggests the attachment IDs of an item by title or ID.
+func completeAttachments(ctx context.Context, itemID, prefix string) (*mcp.CompleteResult, error) {
+	if itemID == "" {
+		return completionResult(nil), nil
+	}
+	if assetIDPattern.MatchString(itemID) {
+		var err error
+		if itemID, err = resolveAssetID(ctx, itemID); err != nil {
+			return nil, err
+		}
+	}
+	_, item, err := getItem(ctx, nil, GetItemInput{ID: itemID})
+	if err != nil {
+		return nil, err
+	}
+	prefix = strings.ToLower(prefix)
+	var values []string
+	for _, attachment := range item.Attachments {
+		if hasPrefix(prefix, attachment.Title, attachment.ID) {
+			values = append(values, attachment.ID)
+		}
+	}
+	return completionResult(values), nil
+}
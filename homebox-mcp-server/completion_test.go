@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompletion(t *testing.T) {
+	homebox := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/locations/tree":
+			w.Write([]byte(`[{"id":"loc1","name":"Garage","type":"location","children":[{"id":"loc2","name":"Shelf 2","type":"location","children":[]}]}]`))
+		case "/api/v1/labels":
+			w.Write([]byte(`[{"id":"lab1","name":"Tools"},{"id":"lab2","name":"Electronics"}]`))
+		case "/api/v1/items":
+			w.Write([]byte(`{"items":[{"id":"item1","name":"Drill","assetId":"000-123"},{"id":"item2","name":"Saw","assetId":"000-124"}],"page":1,"pageSize":100,"total":2}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer homebox.Close()
+
+	os.Setenv("HOMEBOX_URL", homebox.URL)
+	os.Setenv("HOMEBOX_TOKEN", "test-token")
+	inventory.Invalidate()
+	defer inventory.Invalidate()
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, &mcp.ServerOptions{CompletionHandler: completeArgument})
+	registerResources(server)
+	registerPrompts(server)
+
+	ctx := context.Background()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	_, err := server.Connect(ctx, serverTransport, nil)
+	assert.NoError(t, err)
+	session, err := mcp.NewClient(&mcp.Implementation{Name: "client"}, nil).Connect(ctx, clientTransport, nil)
+	assert.NoError(t, err)
+	defer session.Close()
+
+	complete := func(ref *mcp.CompleteReference, name, value string) []string {
+		result, err := session.Complete(ctx, &mcp.CompleteParams{Ref: ref, Argument: mcp.CompleteParamsArgument{Name: name, Value: value}})
+		assert.NoError(t, err)
+		return result.Completion.Values
+	}
+	items := &mcp.CompleteReference{Type: "ref/resource", URI: "homebox://items/{id}"}
+	labels := &mcp.CompleteReference{Type: "ref/resource", URI: "homebox://labels/{id}"}
+	audit := &mcp.CompleteReference{Type: "ref/prompt", Name: "audit_location"}
+	find := &mcp.CompleteReference{Type: "ref/prompt", Name: "find_item"}
+
+	assert.Equal(t, []string{"item1"}, complete(items, "id", "dr"))
+	assert.Equal(t, []string{"000-123", "000-124"}, complete(items, "id", "000-12"))
+	assert.Equal(t, []string{"000-124"}, complete(items, "id", "000-124"))
+	assert.Equal(t, []string{"lab2"}, complete(labels, "id", "ELEC"))
+	assert.Equal(t, []string{"loc1", "loc2"}, complete(audit, "location_id", "garage"))
+	assert.Equal(t, []string{"loc2"}, complete(audit, "location_id", "garage/shelf"))
+	assert.Equal(t, []string{"Saw"}, complete(find, "query", "s"))
+	assert.Equal(t, []string{}, complete(&mcp.CompleteReference{Type: "ref/prompt", Name: "plan_maintenance"}, "days", "3"))
+}
@@ -0,0 +1,557 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"math/bits"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Two items count as duplicates if their score reaches
+// defaultMinDuplicateScore, unless the caller asks otherwise. Photos count as
+// similar if their hashes differ in at most maxPhotoHashDistance bits.
+const (
+	defaultMinDuplicateScore = 0.6
+	maxPhotoHashDistance     = 6
+	similarNameThreshold     = 0.85
+)
+
+// duplicateSignal is a way two items can resemble each other, with how
+// likely it alone makes them duplicates.
+type duplicateSignal struct {
+	reason string
+	score  float64
+}
+
+// duplicateSignals are the signals, strongest first.
+var duplicateSignals = []duplicateSignal{
+	{"same serial number", 0.95},
+	{"same manufacturer and model", 0.8},
+	{"same photo", 0.85},
+	{"same name", 0.7},
+	{"similar photos", 0.6},
+	{"similar names", 0.45},
+}
+
+// Input for the find_duplicate_items tool.
+type FindDuplicateItemsInput struct {
+	ComparePhotos   bool    `json:"comparePhotos,omitempty" jsonschema:"Also compare the primary photos by perceptual hash. Every primary photo is downloaded, so this is slower"`
+	MinScore        float64 `json:"minScore,omitempty" jsonschema:"How likely, from 0 to 1, two items must be duplicates to be reported; 0.6 by default"`
+	IncludeArchived bool    `json:"includeArchived,omitempty" jsonschema:"Also compare archived items"`
+}
+
+// DuplicateItem is an item of a duplicate cluster.
+type DuplicateItem struct {
+	ID           string `json:"id" jsonschema:"ID of the item"`
+	Name         string `json:"name" jsonschema:"Name of the item"`
+	AssetID      string `json:"assetId,omitempty" jsonschema:"Asset ID of the item"`
+	Location     string `json:"location,omitempty" jsonschema:"Path of the location of the item"`
+	Quantity     int    `json:"quantity" jsonschema:"Quantity of the item"`
+	Manufacturer string `json:"manufacturer,omitempty" jsonschema:"Manufacturer of the item"`
+	ModelNumber  string `json:"modelNumber,omitempty" jsonschema:"Model number of the item"`
+	SerialNumber string `json:"serialNumber,omitempty" jsonschema:"Serial number of the item"`
+}
+
+// DuplicateCluster is a group of items that are likely the same thing.
+type DuplicateCluster struct {
+	Score   float64         `json:"score" jsonschema:"How likely the items are duplicates, from 0 to 1: the highest score between two of them"`
+	Reasons []string        `json:"reasons" jsonschema:"What the items have in common"`
+	Items   []DuplicateItem `json:"items" jsonschema:"The items"`
+}
+
+// Output for the find_duplicate_items tool.
+type FindDuplicateItemsOutput struct {
+	Compared int                `json:"compared" jsonschema:"Number of items compared"`
+	Clusters []DuplicateCluster `json:"clusters" jsonschema:"Clusters of likely duplicates, most likely first"`
+}
+
+// duplicateCandidate is an item with the keys it is compared on.
+type duplicateCandidate struct {
+	item      ItemOut
+	name      string
+	model     string
+	serial    string
+	photoHash uint64
+	hasPhoto  bool
+}
+
+func newDuplicateCandidate(item ItemOut) *duplicateCandidate {
+	c := &duplicateCandidate{item: item, name: normalizeName(item.Name)}
+	if item.Manufacturer != "" && item.ModelNumber != "" {
+		c.model = normalizeName(item.Manufacturer) + "|" + normalizeKey(item.ModelNumber)
+	}
+	c.serial = normalizeKey(item.SerialNumber)
+	return c
+}
+
+// normalizeKey drops case, spaces and punctuation from an identifier such
+// as a serial or model number.
+func normalizeKey(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case 'a' <= r && r <= 'z', '0' <= r && r <= '9':
+			return r
+		case 'A' <= r && r <= 'Z':
+			return r - 'A' + 'a'
+		}
+		return -1
+	}, s)
+}
+
+// compare scores how likely a and b are duplicates and says why. The signals
+// are combined as independent evidence.
+func (a *duplicateCandidate) compare(b *duplicateCandidate) (float64, []string) {
+	var reasons []string
+	has := map[string]bool{
+		"same serial number":          a.serial != "" && a.serial == b.serial,
+		"same manufacturer and model": a.model != "" && a.model == b.model,
+		"same name":                   a.name != "" && a.name == b.name,
+	}
+	if !has["same name"] && a.name != "" && b.name != "" {
+		has["similar names"] = nameSimilarity(a.name, b.name) >= similarNameThreshold
+	}
+	if a.hasPhoto && b.hasPhoto {
+		distance := bits.OnesCount64(a.photoHash ^ b.photoHash)
+		has["same photo"] = distance == 0
+		has["similar photos"] = distance > 0 && distance <= maxPhotoHashDistance
+	}
+
+	unlikely := 1.0
+	for _, signal := range duplicateSignals {
+		if has[signal.reason] {
+			unlikely *= 1 - signal.score
+			reasons = append(reasons, signal.reason)
+		}
+	}
+	return 1 - unlikely, reasons
+}
+
+// findDuplicateItems is the implementation of the "find_duplicate_items"
+// tool. Items are only compared with items that share a serial number, a
+// manufacturer and model, or the first word of their name, or when photos
+// are compared, with every item that has a photo.
+func findDuplicateItems(ctx context.Context, req *mcp.CallToolRequest, input FindDuplicateItemsInput) (*mcp.CallToolResult, FindDuplicateItemsOutput, error) {
+	minScore := input.MinScore
+	if minScore <= 0 {
+		minScore = defaultMinDuplicateScore
+	}
+
+	query := url.Values{}
+	if input.IncludeArchived {
+		query.Set("includeArchived", "true")
+	}
+	summaries, err := queryAllItems(ctx, query)
+	if err != nil {
+		return nil, FindDuplicateItemsOutput{}, err
+	}
+	snapshot, err := inventory.Snapshot(ctx)
+	if err != nil {
+		return nil, FindDuplicateItemsOutput{}, err
+	}
+	locationPaths := make(map[string]string)
+	for _, loc := range snapshot.Locations {
+		locationPaths[loc.ID] = loc.Path
+	}
+
+	// The search results lack the manufacturer, model and serial number, so
+	// every item is read.
+	progress := newToolProgress(req)
+	candidates := make([]*duplicateCandidate, len(summaries))
+	errs := make([]error, len(summaries))
+	var mu sync.Mutex
+	done := 0
+	forEachBounded(len(summaries), bulkConcurrency(0, len(summaries)), func(i int) {
+		_, item, err := getItem(ctx, nil, GetItemInput{ID: summaries[i].ID})
+		if err == nil {
+			candidates[i] = newDuplicateCandidate(item)
+			if input.ComparePhotos {
+				candidates[i].photoHash, candidates[i].hasPhoto = primaryPhotoHash(ctx, item)
+			}
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		errs[i] = err
+		done++
+		progress.Report(ctx, float64(done), float64(len(summaries)), fmt.Sprintf("Read %d of %d items", done, len(summaries)))
+	})
+	for _, err := range errs {
+		if err != nil {
+			return nil, FindDuplicateItemsOutput{}, err
+		}
+	}
+
+	// Collect the pairs worth comparing.
+	buckets := make(map[string][]int)
+	var withPhoto []int
+	for i, c := range candidates {
+		if c.serial != "" {
+			buckets["serial:"+c.serial] = append(buckets["serial:"+c.serial], i)
+		}
+		if c.model != "" {
+			buckets["model:"+c.model] = append(buckets["model:"+c.model], i)
+		}
+		if first, _, _ := strings.Cut(c.name, " "); first != "" {
+			buckets["name:"+first] = append(buckets["name:"+first], i)
+		}
+		if c.hasPhoto {
+			withPhoto = append(withPhoto, i)
+		}
+	}
+	buckets["photo"] = withPhoto
+	pairs := make(map[[2]int]bool)
+	for _, members := range buckets {
+		for x := range members {
+			for y := x + 1; y < len(members); y++ {
+				pairs[[2]int{members[x], members[y]}] = true
+			}
+		}
+	}
+
+	// Join the pairs that score high enough into clusters.
+	parent := make([]int, len(candidates))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	type link struct {
+		score   float64
+		reasons []string
+	}
+	links := make(map[[2]int]link)
+	for pair := range pairs {
+		score, reasons := candidates[pair[0]].compare(candidates[pair[1]])
+		if score >= minScore {
+			links[pair] = link{score, reasons}
+			parent[find(pair[1])] = find(pair[0])
+		}
+	}
+	clusters := make(map[int]*DuplicateCluster)
+	for pair, l := range links {
+		cluster := clusters[find(pair[0])]
+		if cluster == nil {
+			cluster = &DuplicateCluster{}
+			clusters[find(pair[0])] = cluster
+		}
+		cluster.Score = max(cluster.Score, l.score)
+		for _, reason := range l.reasons {
+			if !slices.Contains(cluster.Reasons, reason) {
+				cluster.Reasons = append(cluster.Reasons, reason)
+			}
+		}
+	}
+
+	out := FindDuplicateItemsOutput{Compared: len(candidates), Clusters: []DuplicateCluster{}}
+	for i, c := range candidates {
+		cluster := clusters[find(i)]
+		if cluster == nil {
+			continue
+		}
+		item := DuplicateItem{
+			ID:           c.item.ID,
+			Name:         c.item.Name,
+			AssetID:      c.item.AssetID,
+			Quantity:     c.item.Quantity,
+			Manufacturer: c.item.Manufacturer,
+			ModelNumber:  c.item.ModelNumber,
+			SerialNumber: c.item.SerialNumber,
+		}
+		if c.item.Location != nil {
+			item.Location = locationPaths[c.item.Location.ID]
+		}
+		cluster.Items = append(cluster.Items, item)
+	}
+	for _, cluster := range clusters {
+		sort.Slice(cluster.Reasons, func(i, j int) bool {
+			return signalRank(cluster.Reasons[i]) < signalRank(cluster.Reasons[j])
+		})
+		out.Clusters = append(out.Clusters, *cluster)
+	}
+	sort.Slice(out.Clusters, func(i, j int) bool {
+		if out.Clusters[i].Score != out.Clusters[j].Score {
+			return out.Clusters[i].Score > out.Clusters[j].Score
+		}
+		return out.Clusters[i].Items[0].Name < out.Clusters[j].Items[0].Name
+	})
+	return nil, out, nil
+}
+
+func signalRank(reason string) int {
+	return slices.IndexFunc(duplicateSignals, func(s duplicateSignal) bool { return s.reason == reason })
+}
+
+// primaryPhotoHash returns the perceptual hash of the primary photo of item.
+// Items without a photo, or whose photo cannot be decoded, have none.
+func primaryPhotoHash(ctx context.Context, item ItemOut) (uint64, bool) {
+	var photo *ItemAttachment
+	for i, attachment := range item.Attachments {
+		if attachment.Type == "photo" && (photo == nil || attachment.Primary) {
+			photo = &item.Attachments[i]
+		}
+	}
+	if photo == nil {
+		return 0, false
+	}
+	data, _, err := getItemAttachmentFile(ctx, item.ID, photo.ID)
+	if err != nil {
+		return 0, false
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return 0, false
+	}
+	return differenceHash(img), true
+}
+
+// differenceHash computes the dHash of img: the image is reduced to 9x8
+// grey cells, and each bit says whether a cell is brighter than the cell to
+// its right. Resized or recompressed copies of a photo have nearly the same
+// hash.
+func differenceHash(img image.Image) uint64 {
+	bounds := img.Bounds()
+	var cells [8][9]float64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 9; x++ {
+			cell := image.Rect(
+				bounds.Min.X+x*bounds.Dx()/9, bounds.Min.Y+y*bounds.Dy()/8,
+				bounds.Min.X+(x+1)*bounds.Dx()/9, bounds.Min.Y+(y+1)*bounds.Dy()/8,
+			)
+			cells[y][x] = meanLuminance(img, cell)
+		}
+	}
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			if cells[y][x] > cells[y][x+1] {
+				hash |= 1 << (y*8 + x)
+			}
+		}
+	}
+	return hash
+}
+
+// meanLuminance averages the grey level of up to 8x8 pixels spread over r.
+func meanLuminance(img image.Image, r image.Rectangle) float64 {
+	if r.Empty() {
+		return 0
+	}
+	stepX, stepY := max(r.Dx()/8, 1), max(r.Dy()/8, 1)
+	var sum float64
+	var n int
+	for y := r.Min.Y; y < r.Max.Y; y += stepY {
+		for x := r.Min.X; x < r.Max.X; x += stepX {
+			sum += float64(color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y)
+			n++
+		}
+	}
+	return sum / float64(n)
+}
+
+// Input for the merge_items tool.
+type MergeItemsInput struct {
+	Target  string   `json:"target" jsonschema:"Item to keep, by ID, asset ID or name"`
+	Sources []string `json:"sources" jsonschema:"Duplicates to merge into the target and then delete, by ID, asset ID or name"`
+	DryRun  bool     `json:"dryRun,omitempty" jsonschema:"Only report what would be merged"`
+}
+
+// MergedItem is an item merged into the target.
+type MergedItem struct {
+	ID                 string   `json:"id" jsonschema:"ID of the item"`
+	Name               string   `json:"name" jsonschema:"Name of the item"`
+	Quantity           int      `json:"quantity" jsonschema:"Quantity added to the target"`
+	Attachments        int      `json:"attachments" jsonschema:"Number of attachments moved to the target"`
+	MaintenanceEntries int      `json:"maintenanceEntries" jsonschema:"Number of maintenance entries moved to the target"`
+	Deleted            bool     `json:"deleted" jsonschema:"Whether the item was deleted"`
+	Error              string   `json:"error,omitempty" jsonschema:"Why the item was kept"`
+	Leftovers          []string `json:"leftovers,omitempty" jsonschema:"Attachments and maintenance entries already copied to the target that could not be removed after the merge of the item failed"`
+}
+
+// Output for the merge_items tool.
+type MergeItemsOutput struct {
+	DryRun     bool         `json:"dryRun" jsonschema:"Whether nothing was changed"`
+	TargetID   string       `json:"targetId" jsonschema:"ID of the kept item"`
+	TargetName string       `json:"targetName" jsonschema:"Name of the kept item"`
+	Quantity   int          `json:"quantity" jsonschema:"Quantity of the kept item after the merge"`
+	Labels     []string     `json:"labels" jsonschema:"Labels of the kept item after the merge"`
+	Sources    []MergedItem `json:"sources" jsonschema:"The merged items"`
+}
+
+// mergeItems is the implementation of the "merge_items" tool. Homebox cannot
+// move attachments or maintenance entries, so they are copied to the target
+// before the source is deleted. A source whose copies fail is kept, the
+// copies already made are removed again, and its quantity and labels are not
+// added to the target.
+func mergeItems(ctx context.Context, req *mcp.CallToolRequest, input MergeItemsInput) (*mcp.CallToolResult, MergeItemsOutput, error) {
+	targetID, sourceIDs, err := resolveMergeRefs(input.Target, input.Sources, func(field, ref string) (string, error) {
+		return resolveItemRef(ctx, field, ref)
+	})
+	if err != nil {
+		return nil, MergeItemsOutput{}, err
+	}
+	_, target, err := getItem(ctx, nil, GetItemInput{ID: targetID})
+	if err != nil {
+		return nil, MergeItemsOutput{}, err
+	}
+
+	sources := make([]ItemOut, len(sourceIDs))
+	logs := make([][]MaintenanceEntryWithDetails, len(sourceIDs))
+	out := MergeItemsOutput{DryRun: input.DryRun, TargetID: target.ID, TargetName: target.Name}
+	for i, id := range sourceIDs {
+		if _, sources[i], err = getItem(ctx, nil, GetItemInput{ID: id}); err != nil {
+			return nil, MergeItemsOutput{}, err
+		}
+		_, log, err := getMaintenanceLog(ctx, nil, GetMaintenanceLogInput{ItemID: id})
+		if err != nil {
+			return nil, MergeItemsOutput{}, err
+		}
+		logs[i] = log.Entries
+		out.Sources = append(out.Sources, MergedItem{
+			ID:                 id,
+			Name:               sources[i].Name,
+			Quantity:           sources[i].Quantity,
+			Attachments:        len(sources[i].Attachments),
+			MaintenanceEntries: len(log.Entries),
+		})
+	}
+
+	if !input.DryRun {
+		hasPrimary := slices.ContainsFunc(target.Attachments, func(a ItemAttachment) bool { return a.Primary })
+		attachments := make(map[string]bool)
+		for _, attachment := range target.Attachments {
+			attachments[attachment.ID] = true
+		}
+		for i, source := range sources {
+			leftovers, err := copyItemHistory(ctx, target.ID, attachments, source, logs[i], !hasPrimary)
+			if err != nil {
+				out.Sources[i].Error = err.Error()
+				out.Sources[i].Leftovers = leftovers
+				continue
+			}
+			hasPrimary = hasPrimary || slices.ContainsFunc(source.Attachments, func(a ItemAttachment) bool { return a.Primary })
+		}
+	}
+
+	// Sum the quantities and join the labels of the sources that are merged.
+	update := updateInputFromItem(target)
+	labels := make(map[string]string)
+	for _, label := range target.Labels {
+		labels[label.ID] = label.Name
+	}
+	for i, source := range sources {
+		if out.Sources[i].Error != "" {
+			continue
+		}
+		update.Quantity += source.Quantity
+		for _, label := range source.Labels {
+			if _, ok := labels[label.ID]; !ok {
+				labels[label.ID] = label.Name
+				update.LabelIDs = append(update.LabelIDs, label.ID)
+			}
+		}
+	}
+	out.Quantity = update.Quantity
+	out.Labels = []string{}
+	for _, id := range update.LabelIDs {
+		out.Labels = append(out.Labels, labels[id])
+	}
+	if input.DryRun {
+		return nil, out, nil
+	}
+
+	defer inventory.Invalidate()
+	if _, _, err := updateItem(ctx, nil, update); err != nil {
+		return nil, MergeItemsOutput{}, fmt.Errorf("failed to update %s, the attachments and maintenance entries were already copied: %w", target.Name, err)
+	}
+	for i := range out.Sources {
+		if out.Sources[i].Error != "" {
+			continue
+		}
+		if _, _, err := deleteItem(ctx, nil, DeleteItemInput{ID: out.Sources[i].ID}); err != nil {
+			out.Sources[i].Error = err.Error()
+			continue
+		}
+		out.Sources[i].Deleted = true
+	}
+	return nil, out, nil
+}
+
+// copyItemHistory copies the attachments and maintenance log of source to
+// the item targetID, whose attachment IDs are in attachments. The primary
+// photo of source stays primary only if keepPrimary is set. If a copy fails,
+// the copies made so far are deleted again, so that retrying the merge does
+// not duplicate them; the ones that cannot be deleted are returned.
+func copyItemHistory(ctx context.Context, targetID string, attachments map[string]bool, source ItemOut, log []MaintenanceEntryWithDetails, keepPrimary bool) ([]string, error) {
+	var copiedAttachments, copiedEntries []string
+	rollback := func(err error) ([]string, error) {
+		var leftovers []string
+		for _, id := range copiedAttachments {
+			if deleteItemAttachment(ctx, targetID, id) != nil {
+				leftovers = append(leftovers, "attachment "+id)
+			}
+		}
+		for _, id := range copiedEntries {
+			if _, _, err := deleteMaintenanceEntry(ctx, nil, DeleteMaintenanceEntryInput{ID: id}); err != nil {
+				leftovers = append(leftovers, "maintenance entry "+id)
+			}
+		}
+		return leftovers, err
+	}
+
+	for _, attachment := range source.Attachments {
+		data, _, err := getItemAttachmentFile(ctx, source.ID, attachment.ID)
+		if err != nil {
+			return rollback(err)
+		}
+		target, err := uploadItemAttachment(ctx, targetID, attachment.Title, data, attachment.Type, attachment.Primary && keepPrimary)
+		if err != nil {
+			return rollback(err)
+		}
+		for _, a := range target.Attachments {
+			if !attachments[a.ID] {
+				attachments[a.ID] = true
+				copiedAttachments = append(copiedAttachments, a.ID)
+			}
+		}
+	}
+	for _, entry := range log {
+		_, created, err := createMaintenanceEntry(ctx, nil, CreateMaintenanceEntryInput{
+			ItemID:        targetID,
+			Name:          entry.Name,
+			CompletedDate: dateOnly(entry.CompletedDate),
+			Cost:          entry.Cost,
+			Description:   entry.Description,
+			ScheduledDate: dateOnly(entry.ScheduledDate),
+		})
+		if err != nil {
+			return rollback(err)
+		}
+		copiedEntries = append(copiedEntries, created.ID)
+	}
+	return nil, nil
+}
+
+// dateOnly returns the YYYY-MM-DD part of a Homebox date or timestamp, or
+// nothing for the zero date Homebox uses for unset dates.
+func dateOnly(s string) string {
+	if len(s) < 10 || s[4] != '-' || strings.HasPrefix(s, "0001-01-01") {
+		return ""
+	}
+	return s[:10]
+}
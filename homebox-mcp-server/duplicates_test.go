@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"math/bits"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// gradient draws a w by h image that gets brighter from left to right, with
+// a dark band in the middle so that the hash has both kinds of bits.
+func gradient(w, h int, invert bool) image.Image {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := uint8(x * 255 / w)
+			if y > h/3 && y < 2*h/3 {
+				v = 255 - v
+			}
+			if invert {
+				v = 255 - v
+			}
+			img.SetGray(x, y, color.Gray{Y: v})
+		}
+	}
+	return img
+}
+
+func TestDifferenceHash(t *testing.T) {
+	original := differenceHash(gradient(360, 240, false))
+	assert.LessOrEqual(t, bits.OnesCount64(original^differenceHash(gradient(90, 60, false))), maxPhotoHashDistance)
+	assert.Greater(t, bits.OnesCount64(original^differenceHash(gradient(360, 240, true))), 32)
+}
+
+func TestFindDuplicateItems(t *testing.T) {
+	store := newFakeItemStore()
+	ids := []string{
+		"00000000-0000-4000-8000-000000000001",
+		"00000000-0000-4000-8000-000000000002",
+		"00000000-0000-4000-8000-000000000003",
+		"00000000-0000-4000-8000-000000000004",
+	}
+	store.items[ids[0]] = ItemOut{ID: ids[0], Name: "Drill", Manufacturer: "Makita", ModelNumber: "DF331", SerialNumber: "SN-1", Quantity: 1}
+	store.items[ids[1]] = ItemOut{ID: ids[1], Name: "drill", SerialNumber: "sn1", Quantity: 1}
+	store.items[ids[2]] = ItemOut{ID: ids[2], Name: "Cordless Drill", Manufacturer: "makita", ModelNumber: "df 331", Quantity: 1}
+	store.items[ids[3]] = ItemOut{ID: ids[3], Name: "Saw", Quantity: 1}
+	homebox := httptest.NewServer(store)
+	defer homebox.Close()
+	os.Setenv("HOMEBOX_URL", homebox.URL)
+	os.Setenv("HOMEBOX_TOKEN", "test-token")
+
+	_, out, err := findDuplicateItems(context.Background(), nil, FindDuplicateItemsInput{})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, out.Compared)
+	if assert.Len(t, out.Clusters, 1) {
+		cluster := out.Clusters[0]
+		assert.InDelta(t, 1-0.05*0.3, cluster.Score, 0.001)
+		assert.Equal(t, []string{"same serial number", "same manufacturer and model", "same name"}, cluster.Reasons)
+		assert.Len(t, cluster.Items, 3)
+	}
+
+	_, out, err = findDuplicateItems(context.Background(), nil, FindDuplicateItemsInput{MinScore: 0.99})
+	assert.NoError(t, err)
+	assert.Empty(t, out.Clusters)
+}
+
+func TestMergeItems(t *testing.T) {
+	store := newFakeItemStore()
+	store.labels = map[string]*LabelOut{
+		toolsID:       {ID: toolsID, Name: "Tools"},
+		electronicsID: {ID: electronicsID, Name: "Electronics"},
+	}
+	store.items[item1ID] = ItemOut{ID: item1ID, Name: "Drill", Quantity: 1, Labels: []LabelSummary{{ID: toolsID, Name: "Tools"}}}
+	store.items[item2ID] = ItemOut{
+		ID: item2ID, Name: "Drill (old)", Quantity: 2,
+		Labels:      []LabelSummary{{ID: toolsID, Name: "Tools"}, {ID: electronicsID, Name: "Electronics"}},
+		Attachments: []ItemAttachment{{ID: "receipt", Title: "receipt.pdf", Type: "receipt"}, {ID: "photo", Title: "drill.jpg", Type: "photo", Primary: true}},
+	}
+	store.files["receipt"], store.files["photo"] = "pdf", "jpeg"
+	store.maintenance[item2ID] = []MaintenanceEntryWithDetails{{ID: "m0", ItemID: item2ID, Name: "Oiled", CompletedDate: "2024-03-01T00:00:00Z", ScheduledDate: "0001-01-01T00:00:00Z"}}
+	homebox := httptest.NewServer(store)
+	defer homebox.Close()
+	os.Setenv("HOMEBOX_URL", homebox.URL)
+	os.Setenv("HOMEBOX_TOKEN", "test-token")
+	inventory.Invalidate()
+	defer inventory.Invalidate()
+	ctx := context.Background()
+
+	_, out, err := mergeItems(ctx, nil, MergeItemsInput{Target: item1ID, Sources: []string{item2ID}, DryRun: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, out.Quantity)
+	assert.Equal(t, []string{"Tools", "Electronics"}, out.Labels)
+	assert.Equal(t, []MergedItem{{ID: item2ID, Name: "Drill (old)", Quantity: 2, Attachments: 2, MaintenanceEntries: 1}}, out.Sources)
+	assert.Len(t, store.items, 2)
+	assert.Empty(t, store.items[item1ID].Attachments)
+
+	_, _, err = mergeItems(ctx, nil, MergeItemsInput{Target: item1ID, Sources: []string{item1ID}})
+	assert.Error(t, err)
+
+	_, out, err = mergeItems(ctx, nil, MergeItemsInput{Target: item1ID, Sources: []string{item2ID}})
+	assert.NoError(t, err)
+	assert.True(t, out.Sources[0].Deleted)
+	assert.Equal(t, []string{item2ID}, store.deleted)
+
+	target := store.items[item1ID]
+	assert.Equal(t, 3, target.Quantity)
+	assert.Equal(t, []LabelSummary{{ID: toolsID, Name: "Tools"}, {ID: electronicsID, Name: "Electronics"}}, target.Labels)
+	if assert.Len(t, target.Attachments, 2) {
+		assert.Equal(t, "pdf", store.files[target.Attachments[0].ID])
+		assert.Equal(t, ItemAttachment{ID: target.Attachments[1].ID, Title: "drill.jpg", Type: "photo", Primary: true}, target.Attachments[1])
+	}
+	if assert.Len(t, store.maintenance[item1ID], 1) {
+		entry := store.maintenance[item1ID][0]
+		assert.Equal(t, "Oiled", entry.Name)
+		assert.Equal(t, "2024-03-01", entry.CompletedDate)
+		assert.Empty(t, entry.ScheduledDate)
+	}
+}
+
+func TestMergeItemsRollback(t *testing.T) {
+	store := newFakeItemStore()
+	store.labels = map[string]*LabelOut{}
+	store.items[item1ID] = ItemOut{ID: item1ID, Name: "Drill", Quantity: 1, Attachments: []ItemAttachment{{ID: "manual", Title: "manual.pdf", Type: "manual"}}}
+	store.items[item2ID] = ItemOut{
+		ID: item2ID, Name: "Drill (old)", Quantity: 2,
+		Attachments: []ItemAttachment{{ID: "receipt", Title: "receipt.pdf", Type: "receipt"}, {ID: "lost", Title: "lost.jpg", Type: "photo"}},
+	}
+	store.files["manual"], store.files["receipt"] = "pdf", "pdf"
+	homebox := httptest.NewServer(store)
+	defer homebox.Close()
+	os.Setenv("HOMEBOX_URL", homebox.URL)
+	os.Setenv("HOMEBOX_TOKEN", "test-token")
+	inventory.Invalidate()
+	defer inventory.Invalidate()
+
+	// The second attachment cannot be downloaded, so the copy of the first is
+	// removed again and a retry starts from a clean target.
+	_, out, err := mergeItems(context.Background(), nil, MergeItemsInput{Target: item1ID, Sources: []string{item2ID}})
+	assert.NoError(t, err)
+	assert.False(t, out.Sources[0].Deleted)
+	assert.NotEmpty(t, out.Sources[0].Error)
+	assert.Empty(t, out.Sources[0].Leftovers)
+	assert.Equal(t, 1, out.Quantity)
+	assert.Equal(t, []ItemAttachment{{ID: "manual", Title: "manual.pdf", Type: "manual"}}, store.items[item1ID].Attachments)
+	assert.Contains(t, store.items, item2ID)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultIndexTTL is how long the inventory index is reused before it is
+// rebuilt, when HOMEBOX_INDEX_TTL is not set.
+const defaultIndexTTL = time.Minute
+
+// assetIDPattern matches a Homebox asset ID such as 000-123.
+var assetIDPattern = regexp.MustCompile(`^\d{3}-\d{3}$`)
+
+// indexEntry is a location, label or item in the inventory index.
+type indexEntry struct {
+	ID   string
+	Name string
+	// Path is the names of a location and its ancestors, joined by "/".
+	Path string
+	// AssetID is the asset ID of an item, if it has one.
+	AssetID string
+}
+
+// indexSnapshot is the inventory as of one rebuild of the index.
+type indexSnapshot struct {
+	Locations []indexEntry
+	Labels    []indexEntry
+	Items     []indexEntry
+}
+
+// inventoryIndex caches the names, paths and IDs of every location, label and
+// item, so they can be looked up without listing the inventory every time.
+type inventoryIndex struct {
+	mu       sync.Mutex
+	snapshot *indexSnapshot
+	built    time.Time
+}
+
+// inventory is the index shared by every session.
+var inventory = &inventoryIndex{}
+
+// Snapshot returns the cached index, rebuilding it once it is older than
+// HOMEBOX_INDEX_TTL (a Go duration, default one minute).
+func (ix *inventoryIndex) Snapshot(ctx context.Context) (*indexSnapshot, error) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ttl := defaultIndexTTL
+	if d, err := time.ParseDuration(os.Getenv("HOMEBOX_INDEX_TTL")); err == nil {
+		ttl = d
+	}
+	if ix.snapshot != nil && time.Since(ix.built) < ttl {
+		return ix.snapshot, nil
+	}
+
+	snapshot, err := buildIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ix.snapshot, ix.built = snapshot, time.Now()
+	return snapshot, nil
+}
+
+// Invalidate drops the cached index, so the next lookup sees changes made
+// since it was built.
+func (ix *inventoryIndex) Invalidate() {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.snapshot = nil
+}
+
+// buildIndex lists the whole inventory.
+func buildIndex(ctx context.Context) (*indexSnapshot, error) {
+	snapshot := &indexSnapshot{}
+
+	tree, err := getLocationTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var walk func(nodes []TreeItem, parent string)
+	walk = func(nodes []TreeItem, parent string) {
+		for _, node := range nodes {
+			if node.Type != "" && node.Type != "location" {
+				continue
+			}
+			path := node.Name
+			if parent != "" {
+				path = parent + "/" + node.Name
+			}
+			snapshot.Locations = append(snapshot.Locations, indexEntry{ID: node.ID, Name: node.Name, Path: path})
+			walk(node.Children, path)
+		}
+	}
+	walk(tree, "")
+
+	_, labels, err := getLabels(ctx, nil, GetLabelsInput{})
+	if err != nil {
+		return nil, err
+	}
+	for _, label := range labels.Labels {
+		snapshot.Labels = append(snapshot.Labels, indexEntry{ID: label.ID, Name: label.Name})
+	}
+
+	for page := 1; ; page++ {
+		out, err := queryItems(ctx, url.Values{
+			"page":     {strconv.Itoa(page)},
+			"pageSize": {strconv.Itoa(resourcePageSize)},
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range out.Items {
+			entry := indexEntry{ID: item.ID, Name: item.Name}
+			if assetIDPattern.MatchString(item.AssetID) && item.AssetID != "000-000" {
+				entry.AssetID = item.AssetID
+			}
+			snapshot.Items = append(snapshot.Items, entry)
+		}
+		if page*resourcePageSize >= out.Total || len(out.Items) == 0 {
+			break
+		}
+	}
+	return snapshot, nil
+}
+
+// resolveAssetID returns the ID of the item with the given asset ID.
+func resolveAssetID(ctx context.Context, assetID string) (string, error) {
+	snapshot, err := inventory.Snapshot(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, item := range snapshot.Items {
+		if item.AssetID == assetID {
+			return item.ID, nil
+		}
+	}
+	return "", fmt.Errorf("no item has asset ID %s", assetID)
+}
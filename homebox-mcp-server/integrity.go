@@ -0,0 +1,381 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// defaultUnsortedLocation is where check_integrity moves orphans.
+const defaultUnsortedLocation = "Unsorted"
+
+// Input for the check_integrity tool.
+type CheckIntegrityInput struct {
+	CheckAttachments bool   `json:"checkAttachments,omitempty" jsonschema:"Also download every attachment to check that its file exists. This is slow on large inventories"`
+	Repair           bool   `json:"repair,omitempty" jsonschema:"Repair what can be repaired: orphaned items and locations move to the unsorted location, broken parents and labels are removed. Without it, the problems are only reported"`
+	UnsortedLocation string `json:"unsortedLocation,omitempty" jsonschema:"Location orphans are moved to, by ID, name or path; created at the top level if missing. Unsorted by default"`
+}
+
+// IntegrityProblem is a broken or inconsistent reference.
+type IntegrityProblem struct {
+	Check     string `json:"check" jsonschema:"Kind of problem, such as dangling_location or parent_cycle"`
+	Kind      string `json:"kind" jsonschema:"item or location"`
+	ID        string `json:"id" jsonschema:"ID of the item or location with the broken reference"`
+	Name      string `json:"name" jsonschema:"Name of the item or location"`
+	Reference string `json:"reference,omitempty" jsonschema:"ID that is referenced"`
+	Message   string `json:"message" jsonschema:"What is wrong"`
+	Repair    string `json:"repair,omitempty" jsonschema:"What repair does, or did, about it. Problems without one are only reported"`
+	Repaired  bool   `json:"repaired" jsonschema:"Whether the problem was repaired"`
+	Error     string `json:"error,omitempty" jsonschema:"Why the repair failed"`
+}
+
+// Output for the check_integrity tool.
+type CheckIntegrityOutput struct {
+	Items       int                `json:"items" jsonschema:"Number of items checked"`
+	Locations   int                `json:"locations" jsonschema:"Number of locations checked"`
+	Labels      int                `json:"labels" jsonschema:"Number of labels checked"`
+	Attachments int                `json:"attachments" jsonschema:"Number of attachment files checked"`
+	Problems    []IntegrityProblem `json:"problems" jsonschema:"The problems found"`
+	Repaired    int                `json:"repaired" jsonschema:"Number of problems repaired"`
+}
+
+// checkIntegrity is the implementation of the "check_integrity" tool. Every
+// item and location is read, so that references missing from the lists and
+// the location tree are found too.
+func checkIntegrity(ctx context.Context, req *mcp.CallToolRequest, input CheckIntegrityInput) (*mcp.CallToolResult, CheckIntegrityOutput, error) {
+	unsorted := input.UnsortedLocation
+	if unsorted == "" {
+		unsorted = defaultUnsortedLocation
+	}
+
+	_, labelList, err := getLabels(ctx, nil, GetLabelsInput{})
+	if err != nil {
+		return nil, CheckIntegrityOutput{}, err
+	}
+	_, locationList, err := getLocations(ctx, nil, GetLocationsInput{})
+	if err != nil {
+		return nil, CheckIntegrityOutput{}, err
+	}
+	tree, err := getLocationTree(ctx)
+	if err != nil {
+		return nil, CheckIntegrityOutput{}, err
+	}
+	summaries, err := queryAllItems(ctx, url.Values{"includeArchived": {"true"}})
+	if err != nil {
+		return nil, CheckIntegrityOutput{}, err
+	}
+
+	progress := newToolProgress(req)
+	total := len(locationList.Locations) + len(summaries)
+	locations := make([]LocationOut, len(locationList.Locations))
+	items := make([]ItemOut, len(summaries))
+	errs := make([]error, total)
+	var mu sync.Mutex
+	done := 0
+	forEachBounded(total, bulkConcurrency(0, total), func(i int) {
+		var err error
+		if i < len(locations) {
+			_, locations[i], err = getLocation(ctx, nil, GetLocationInput{ID: locationList.Locations[i].ID})
+		} else {
+			_, items[i-len(locations)], err = getItem(ctx, nil, GetItemInput{ID: summaries[i-len(locations)].ID})
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		errs[i] = err
+		done++
+		progress.Report(ctx, float64(done), float64(total), fmt.Sprintf("Read %d of %d locations and items", done, total))
+	})
+	for _, err := range errs {
+		if err != nil {
+			return nil, CheckIntegrityOutput{}, err
+		}
+	}
+
+	out := CheckIntegrityOutput{Items: len(items), Locations: len(locations), Labels: len(labelList.Labels), Problems: []IntegrityProblem{}}
+	labelIDs := make(map[string]bool)
+	for _, label := range labelList.Labels {
+		labelIDs[label.ID] = true
+	}
+	locationsByID := make(map[string]*LocationOut)
+	for i := range locations {
+		locationsByID[locations[i].ID] = &locations[i]
+	}
+	itemsByID := make(map[string]*ItemOut)
+	for i := range items {
+		itemsByID[items[i].ID] = &items[i]
+	}
+
+	// Locations: parents and children must exist and agree, the parents
+	// must not loop, and every location must be in the tree.
+	inTree := make(map[string]bool)
+	var walk func([]TreeItem)
+	walk = func(nodes []TreeItem) {
+		for _, node := range nodes {
+			inTree[node.ID] = true
+			walk(node.Children)
+		}
+	}
+	walk(tree)
+	locationParent := func(id string) string {
+		if loc := locationsByID[id]; loc != nil && loc.Parent != nil {
+			return loc.Parent.ID
+		}
+		return ""
+	}
+	moveLocation := make(map[string]*IntegrityProblem)
+	var problems []*IntegrityProblem
+	report := func(p IntegrityProblem) *IntegrityProblem {
+		problems = append(problems, &p)
+		return &p
+	}
+	for _, loc := range locations {
+		problem := IntegrityProblem{Kind: "location", ID: loc.ID, Name: loc.Name}
+		if loc.Parent != nil && locationsByID[loc.Parent.ID] == nil {
+			problem.Check, problem.Reference = "dangling_parent", loc.Parent.ID
+			problem.Message = fmt.Sprintf("parent location %s does not exist", loc.Parent.ID)
+			problem.Repair = "move to " + unsorted
+			moveLocation[loc.ID] = report(problem)
+		} else if loc.Parent != nil && !slices.ContainsFunc(locationsByID[loc.Parent.ID].Children, func(c LocationSummary) bool { return c.ID == loc.ID }) {
+			problem.Check, problem.Reference = "inconsistent_children", loc.Parent.ID
+			problem.Message = fmt.Sprintf("parent location %s does not list it as a child", locationsByID[loc.Parent.ID].Name)
+			report(problem)
+		}
+		for _, child := range loc.Children {
+			problem := IntegrityProblem{Kind: "location", ID: loc.ID, Name: loc.Name, Reference: child.ID}
+			if locationsByID[child.ID] == nil {
+				problem.Check, problem.Message = "dangling_child", fmt.Sprintf("child location %s does not exist", child.ID)
+				report(problem)
+			} else if locationParent(child.ID) != loc.ID {
+				problem.Check, problem.Message = "inconsistent_children", fmt.Sprintf("child location %s has another parent", locationsByID[child.ID].Name)
+				report(problem)
+			}
+		}
+	}
+	for _, cycle := range findCycles(slices.Collect(maps.Keys(locationsByID)), locationParent) {
+		// Moving one location of the loop under the unsorted location breaks
+		// it.
+		names := make([]string, len(cycle))
+		for i, id := range cycle {
+			names[i] = locationsByID[id].Name
+		}
+		moveLocation[cycle[0]] = report(IntegrityProblem{
+			Check: "parent_cycle", Kind: "location", ID: cycle[0], Name: locationsByID[cycle[0]].Name, Reference: locationParent(cycle[0]),
+			Message: "location parents form a loop: " + strings.Join(names, " -> "),
+			Repair:  "move to " + unsorted,
+		})
+	}
+	for _, loc := range locations {
+		// Locations below a broken parent are left out of the tree too, and
+		// are fixed along with it.
+		if !inTree[loc.ID] && reachesRoot(loc.ID, locationParent, func(id string) bool { return locationsByID[id] != nil }) {
+			report(IntegrityProblem{Check: "missing_from_tree", Kind: "location", ID: loc.ID, Name: loc.Name, Message: "is not in the location tree"})
+		}
+	}
+
+	// Items: locations, parents and labels must exist, and parents must not
+	// loop.
+	type itemRepair struct {
+		problems    []*IntegrityProblem
+		move        bool
+		clearParent bool
+		dropLabels  []string
+	}
+	itemRepairs := make(map[string]*itemRepair)
+	repairOf := func(id string) *itemRepair {
+		if itemRepairs[id] == nil {
+			itemRepairs[id] = &itemRepair{}
+		}
+		return itemRepairs[id]
+	}
+	itemParent := func(id string) string {
+		if item := itemsByID[id]; item != nil && item.Parent != nil {
+			return item.Parent.ID
+		}
+		return ""
+	}
+	for _, item := range items {
+		r := repairOf(item.ID)
+		switch {
+		case item.Location == nil:
+			r.move = true
+			r.problems = append(r.problems, report(IntegrityProblem{
+				Check: "missing_location", Kind: "item", ID: item.ID, Name: item.Name,
+				Message: "has no location", Repair: "move to " + unsorted,
+			}))
+		case locationsByID[item.Location.ID] == nil:
+			r.move = true
+			r.problems = append(r.problems, report(IntegrityProblem{
+				Check: "dangling_location", Kind: "item", ID: item.ID, Name: item.Name, Reference: item.Location.ID,
+				Message: fmt.Sprintf("location %s does not exist", item.Location.ID), Repair: "move to " + unsorted,
+			}))
+		}
+		if item.Parent != nil && itemsByID[item.Parent.ID] == nil {
+			r.clearParent = true
+			r.problems = append(r.problems, report(IntegrityProblem{
+				Check: "dangling_parent", Kind: "item", ID: item.ID, Name: item.Name, Reference: item.Parent.ID,
+				Message: fmt.Sprintf("parent item %s does not exist", item.Parent.ID), Repair: "remove the parent",
+			}))
+		}
+		for _, label := range item.Labels {
+			if !labelIDs[label.ID] {
+				r.dropLabels = append(r.dropLabels, label.ID)
+				r.problems = append(r.problems, report(IntegrityProblem{
+					Check: "dangling_label", Kind: "item", ID: item.ID, Name: item.Name, Reference: label.ID,
+					Message: fmt.Sprintf("label %s does not exist", cmp.Or(label.Name, label.ID)), Repair: "remove the label",
+				}))
+			}
+		}
+		if input.CheckAttachments {
+			for _, attachment := range item.Attachments {
+				out.Attachments++
+				if _, _, err := getItemAttachmentFile(ctx, item.ID, attachment.ID); err != nil {
+					report(IntegrityProblem{
+						Check: "missing_attachment_file", Kind: "item", ID: item.ID, Name: item.Name, Reference: attachment.ID,
+						Message: fmt.Sprintf("file of attachment %s cannot be read: %v", cmp.Or(attachment.Title, attachment.ID), err),
+					})
+				}
+			}
+		}
+	}
+	for _, cycle := range findCycles(slices.Collect(maps.Keys(itemsByID)), itemParent) {
+		names := make([]string, len(cycle))
+		for i, id := range cycle {
+			names[i] = itemsByID[id].Name
+		}
+		r := repairOf(cycle[0])
+		r.clearParent = true
+		r.problems = append(r.problems, report(IntegrityProblem{
+			Check: "parent_cycle", Kind: "item", ID: cycle[0], Name: itemsByID[cycle[0]].Name, Reference: itemParent(cycle[0]),
+			Message: "item parents form a loop: " + strings.Join(names, " -> "), Repair: "remove the parent",
+		}))
+	}
+
+	if input.Repair && slices.ContainsFunc(problems, func(p *IntegrityProblem) bool { return p.Repair != "" }) {
+		unsortedID, err := resolveLocationRef(ctx, "unsortedLocation", unsorted, true)
+		if err != nil {
+			return nil, CheckIntegrityOutput{}, err
+		}
+		defer inventory.Invalidate()
+		finish := func(problems []*IntegrityProblem, err error) {
+			for _, p := range problems {
+				if err != nil {
+					p.Error = err.Error()
+				} else {
+					p.Repaired = true
+					out.Repaired++
+				}
+			}
+		}
+		for _, id := range slices.Sorted(maps.Keys(moveLocation)) {
+			// Moving a location under the unsorted location when that is
+			// the location itself or below it would make a new loop, so it
+			// is moved to the top level instead.
+			loc := locationsByID[id]
+			parentID := unsortedID
+			if reachesAncestor(unsortedID, id, locationParent) {
+				parentID = ""
+				moveLocation[id].Repair = "move to the top level"
+			}
+			_, _, err := updateLocation(ctx, nil, UpdateLocationInput{ID: id, Name: loc.Name, Description: loc.Description, ParentID: parentID})
+			if err == nil {
+				loc.Parent = nil
+				if parentID != "" {
+					loc.Parent = &LocationSummary{ID: parentID}
+				}
+			}
+			finish([]*IntegrityProblem{moveLocation[id]}, err)
+		}
+		for _, id := range slices.Sorted(maps.Keys(itemRepairs)) {
+			r := itemRepairs[id]
+			if len(r.problems) == 0 {
+				continue
+			}
+			update := updateInputFromItem(*itemsByID[id])
+			if r.move {
+				update.LocationID = unsortedID
+			}
+			if r.clearParent {
+				update.ParentID = ""
+			}
+			update.LabelIDs = slices.DeleteFunc(update.LabelIDs, func(id string) bool { return slices.Contains(r.dropLabels, id) })
+			_, _, err := updateItem(ctx, nil, update)
+			finish(r.problems, err)
+		}
+	}
+
+	kinds := []string{"location", "item"}
+	slices.SortStableFunc(problems, func(a, b *IntegrityProblem) int {
+		if d := slices.Index(kinds, a.Kind) - slices.Index(kinds, b.Kind); d != 0 {
+			return d
+		}
+		if d := strings.Compare(a.Name, b.Name); d != 0 {
+			return d
+		}
+		return strings.Compare(a.Check, b.Check)
+	})
+	for _, p := range problems {
+		out.Problems = append(out.Problems, *p)
+	}
+	return nil, out, nil
+}
+
+// reachesRoot reports whether following parent from id ends at a top-level
+// node, rather than at a node that does not exist or in a loop.
+func reachesRoot(id string, parent func(string) string, exists func(string) bool) bool {
+	seen := make(map[string]bool)
+	for ; id != ""; id = parent(id) {
+		if seen[id] || !exists(id) {
+			return false
+		}
+		seen[id] = true
+	}
+	return true
+}
+
+// reachesAncestor reports whether following parent from id, id included,
+// passes ancestor.
+func reachesAncestor(id, ancestor string, parent func(string) string) bool {
+	seen := make(map[string]bool)
+	for ; id != "" && !seen[id]; id = parent(id) {
+		if id == ancestor {
+			return true
+		}
+		seen[id] = true
+	}
+	return false
+}
+
+// findCycles returns the loops formed by following parent from ids. Each
+// loop is listed once, from its smallest ID, in the order of its parents.
+func findCycles(ids []string, parent func(string) string) [][]string {
+	slices.Sort(ids)
+	var cycles [][]string
+	done := make(map[string]bool)
+	for _, start := range ids {
+		var path []string
+		onPath := make(map[string]int)
+		id := start
+		for id != "" && !done[id] {
+			if i, ok := onPath[id]; ok {
+				cycle := slices.Clone(path[i:])
+				first := slices.Index(cycle, slices.Min(cycle))
+				cycles = append(cycles, append(cycle[first:], cycle[:first]...))
+				break
+			}
+			onPath[id] = len(path)
+			path = append(path, id)
+			id = parent(id)
+		}
+		for _, id := range path {
+			done[id] = true
+		}
+	}
+	return cycles
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindCycles(t *testing.T) {
+	parents := map[string]string{"a": "b", "b": "c", "c": "a", "d": "a", "e": "f", "f": "e", "g": ""}
+	parent := func(id string) string { return parents[id] }
+	assert.Equal(t, [][]string{{"a", "b", "c"}, {"e", "f"}}, findCycles([]string{"g", "f", "e", "d", "c", "b", "a"}, parent))
+	assert.True(t, reachesRoot("g", parent, func(string) bool { return true }))
+	assert.False(t, reachesRoot("d", parent, func(string) bool { return true }))
+	assert.True(t, reachesAncestor("d", "c", parent))
+	assert.True(t, reachesAncestor("g", "g", parent))
+	assert.False(t, reachesAncestor("d", "e", parent))
+}
+
+func TestCheckIntegrity(t *testing.T) {
+	const (
+		boxID      = "00000000-0000-4000-b000-000000000005"
+		missingID  = "00000000-0000-4000-b000-000000000099"
+		item4ID    = "00000000-0000-4000-8000-000000000004"
+		item5ID    = "00000000-0000-4000-8000-000000000005"
+		item6ID    = "00000000-0000-4000-8000-000000000006"
+		unsortedID = "00000000-0000-4000-b000-000000000101"
+	)
+	items := newFakeItemStore()
+	items.labels = map[string]*LabelOut{electronicsID: {ID: electronicsID, Name: "Electronics"}}
+	garage := &LocationSummary{ID: garageID}
+	items.items[item1ID] = ItemOut{ID: item1ID, Name: "Radio", Location: garage, Labels: []LabelSummary{{ID: electronicsID}},
+		Attachments: []ItemAttachment{{ID: "photo", Type: "photo"}, {ID: "gone", Title: "manual.pdf", Type: "manual"}}}
+	items.files["photo"] = "jpeg"
+	items.items[item2ID] = ItemOut{ID: item2ID, Name: "Lamp", Location: &LocationSummary{ID: missingID}, Labels: []LabelSummary{{ID: lowerID, Name: "Old"}}}
+	items.items[item3ID] = ItemOut{ID: item3ID, Name: "Bag", Location: garage, Parent: &ItemSummary{ID: "00000000-0000-4000-8000-000000000099"}}
+	items.items[item4ID] = ItemOut{ID: item4ID, Name: "Box A", Location: garage, Parent: &ItemSummary{ID: item5ID}}
+	items.items[item5ID] = ItemOut{ID: item5ID, Name: "Box B", Location: garage, Parent: &ItemSummary{ID: item4ID}}
+	items.items[item6ID] = ItemOut{ID: item6ID, Name: "Sock"}
+	locations := newFakeLocationStore(
+		&fakeLocation{ID: garageID, Name: "Garage"},
+		&fakeLocation{ID: garage2ID, Name: "Attic", ParentID: missingID},
+		&fakeLocation{ID: boxID, Name: "Box", ParentID: garage2ID},
+		&fakeLocation{ID: garage3ID, Name: "Loop A", ParentID: shelfID},
+		&fakeLocation{ID: shelfID, Name: "Loop B", ParentID: garage3ID},
+	)
+	locations.next = 100
+	mux := http.NewServeMux()
+	mux.Handle("/api/v1/locations", locations)
+	mux.Handle("/api/v1/locations/", locations)
+	mux.Handle("/", items)
+	homebox := httptest.NewServer(mux)
+	defer homebox.Close()
+	os.Setenv("HOMEBOX_URL", homebox.URL)
+	os.Setenv("HOMEBOX_TOKEN", "test-token")
+	inventory.Invalidate()
+	defer inventory.Invalidate()
+	ctx := context.Background()
+
+	type problem struct{ check, name, repair string }
+	problems := func(out CheckIntegrityOutput) []problem {
+		var got []problem
+		for _, p := range out.Problems {
+			got = append(got, problem{p.Check, p.Name, p.Repair})
+		}
+		return got
+	}
+
+	_, out, err := checkIntegrity(ctx, nil, CheckIntegrityInput{CheckAttachments: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 6, out.Items)
+	assert.Equal(t, 5, out.Locations)
+	assert.Equal(t, 2, out.Attachments)
+	assert.Equal(t, []problem{
+		{"dangling_parent", "Attic", "move to Unsorted"},
+		{"parent_cycle", "Loop A", "move to Unsorted"},
+		{"dangling_parent", "Bag", "remove the parent"},
+		{"parent_cycle", "Box A", "remove the parent"},
+		{"dangling_label", "Lamp", "remove the label"},
+		{"dangling_location", "Lamp", "move to Unsorted"},
+		{"missing_attachment_file", "Radio", ""},
+		{"missing_location", "Sock", "move to Unsorted"},
+	}, problems(out))
+	assert.Equal(t, "location parents form a loop: Loop A -> Loop B", out.Problems[1].Message)
+	assert.Zero(t, out.Repaired)
+	assert.Len(t, locations.locations, 5)
+
+	_, out, err = checkIntegrity(ctx, nil, CheckIntegrityInput{Repair: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 7, out.Repaired)
+	assert.Equal(t, "Unsorted", locations.locations[unsortedID].Name)
+	assert.Equal(t, unsortedID, locations.locations[garage2ID].ParentID)
+	assert.Equal(t, unsortedID, locations.locations[garage3ID].ParentID)
+	assert.Equal(t, unsortedID, items.items[item2ID].Location.ID)
+	assert.Empty(t, items.items[item2ID].Labels)
+	assert.Equal(t, unsortedID, items.items[item6ID].Location.ID)
+	assert.Nil(t, items.items[item3ID].Parent)
+	assert.Nil(t, items.items[item4ID].Parent)
+	assert.Equal(t, item4ID, items.items[item5ID].Parent.ID)
+
+	inventory.Invalidate()
+	_, out, err = checkIntegrity(ctx, nil, CheckIntegrityInput{CheckAttachments: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []problem{{"missing_attachment_file", "Radio", ""}}, problems(out))
+}
+
+func TestCheckIntegrityCycleAboveUnsorted(t *testing.T) {
+	const binID = "00000000-0000-4000-b000-000000000005"
+	for _, unsorted := range []string{shelfID, binID} {
+		locations := newFakeLocationStore(
+			&fakeLocation{ID: garage3ID, Name: "Loop A", ParentID: shelfID},
+			&fakeLocation{ID: shelfID, Name: "Loop B", ParentID: garage3ID},
+			&fakeLocation{ID: binID, Name: "Bin", ParentID: shelfID},
+		)
+		mux := http.NewServeMux()
+		mux.Handle("/api/v1/locations", locations)
+		mux.Handle("/api/v1/locations/", locations)
+		mux.Handle("/", newFakeItemStore())
+		homebox := httptest.NewServer(mux)
+		os.Setenv("HOMEBOX_URL", homebox.URL)
+		os.Setenv("HOMEBOX_TOKEN", "test-token")
+		inventory.Invalidate()
+
+		// The unsorted location is in the loop or below it, so the loop is
+		// broken by moving a location to the top level.
+		_, out, err := checkIntegrity(context.Background(), nil, CheckIntegrityInput{Repair: true, UnsortedLocation: unsorted})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, out.Repaired)
+		if assert.Len(t, out.Problems, 1) {
+			assert.Equal(t, "move to the top level", out.Problems[0].Repair)
+		}
+		assert.Empty(t, locations.locations[garage3ID].ParentID)
+
+		inventory.Invalidate()
+		_, out, err = checkIntegrity(context.Background(), nil, CheckIntegrityInput{UnsortedLocation: unsorted})
+		assert.NoError(t, err)
+		assert.Empty(t, out.Problems)
+		homebox.Close()
+	}
+	inventory.Invalidate()
+}
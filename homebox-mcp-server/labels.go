@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// labelImageResult returns a label maker image as image content. If
+// HOMEBOX_LABEL_DIR is set, the image is also written there as name plus the
+// extension for its MIME type, and linked from the result.
+func labelImageResult(name string, data []byte, contentType string) (*mcp.CallToolResult, GetLabelOutput, error) {
+	mimeType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mimeType == "" || mimeType == "application/octet-stream" {
+		mimeType, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+	}
+
+	result := &mcp.CallToolResult{Content: []mcp.Content{&mcp.ImageContent{Data: data, MIMEType: mimeType}}}
+	out := GetLabelOutput{MimeType: mimeType, Size: len(data)}
+
+	dir := os.Getenv("HOMEBOX_LABEL_DIR")
+	if dir == "" {
+		return result, out, nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, GetLabelOutput{}, fmt.Errorf("failed to create label directory: %w", err)
+	}
+	path, err := filepath.Abs(filepath.Join(dir, labelFileName(name)+imageExtension(mimeType)))
+	if err != nil {
+		return nil, GetLabelOutput{}, err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return nil, GetLabelOutput{}, fmt.Errorf("failed to write label: %w", err)
+	}
+	size := int64(len(data))
+	result.Content = append(result.Content, &mcp.ResourceLink{
+		URI:      (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String(),
+		Name:     filepath.Base(path),
+		MIMEType: mimeType,
+		Size:     &size,
+	})
+	out.File = path
+	return result, out, nil
+}
+
+// labelFileName makes name safe to use as a file name.
+func labelFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r == '.' || ('0' <= r && r <= '9') || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// imageExtension returns the usual file extension for an image MIME type.
+func imageExtension(mimeType string) string {
+	switch mimeType {
+	case "image/png":
+		return ".png"
+	case "image/jpeg":
+		return ".jpg"
+	case "image/gif":
+		return ".gif"
+	case "image/svg+xml":
+		return ".svg"
+	case "image/webp":
+		return ".webp"
+	}
+	if extensions, _ := mime.ExtensionsByType(mimeType); len(extensions) > 0 {
+		return extensions[0]
+	}
+	return ""
+}
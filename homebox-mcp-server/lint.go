@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// defaultWarrantyDays is how soon a warranty must expire to be checked
+	// for a receipt.
+	defaultWarrantyDays = 90
+	// defaultMaxQuantity is the quantity above which a quantity is suspicious.
+	defaultMaxQuantity = 1000
+)
+
+// Severities of lint findings, most severe first.
+var lintSeverities = []string{"error", "warning", "info"}
+
+// labelColorPattern matches the hex colors Homebox shows, as in colorProperty.
+var labelColorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// lintRequirement is a property an item can be required to have by a label
+// rule.
+type lintRequirement struct {
+	check       string
+	message     string
+	has         func(item ItemOut) bool
+	field       string // update_item argument that sets it, if any
+	placeholder string
+}
+
+// lintRequirements are the properties a label rule can require, by the name
+// used in the rule.
+var lintRequirements = map[string]lintRequirement{
+	"photo":           {check: "missing_photo", message: "has no photo", has: func(item ItemOut) bool { return hasAttachment(item, "photo") }},
+	"receipt":         {check: "missing_receipt", message: "has no receipt", has: func(item ItemOut) bool { return hasAttachment(item, "receipt") }},
+	"manual":          {check: "missing_manual", message: "has no manual", has: func(item ItemOut) bool { return hasAttachment(item, "manual") }},
+	"purchasePrice":   {check: "missing_purchase_price", message: "has no purchase price", has: func(item ItemOut) bool { return item.PurchasePrice > 0 }, field: "purchasePrice", placeholder: "<price paid>"},
+	"purchaseTime":    {check: "missing_purchase_time", message: "has no purchase date", has: func(item ItemOut) bool { return dateOnly(item.PurchaseTime) != "" }, field: "purchaseTime", placeholder: "<YYYY-MM-DD>"},
+	"serialNumber":    {check: "missing_serial_number", message: "has no serial number", has: func(item ItemOut) bool { return item.SerialNumber != "" }, field: "serialNumber", placeholder: "<serial number>"},
+	"manufacturer":    {check: "missing_manufacturer", message: "has no manufacturer", has: func(item ItemOut) bool { return item.Manufacturer != "" }, field: "manufacturer", placeholder: "<manufacturer>"},
+	"modelNumber":     {check: "missing_model_number", message: "has no model number", has: func(item ItemOut) bool { return item.ModelNumber != "" }, field: "modelNumber", placeholder: "<model number>"},
+	"warrantyExpires": {check: "missing_warranty", message: "has no warranty expiry date", has: func(item ItemOut) bool { return item.LifetimeWarranty || dateOnly(item.WarrantyExpires) != "" }, field: "warrantyExpires", placeholder: "<YYYY-MM-DD>"},
+}
+
+// LabelRule is the lint configuration of the items with a label.
+type LabelRule struct {
+	Require  []string `json:"require,omitempty" yaml:"require" jsonschema:"What the items must have: photo, receipt, manual, purchasePrice, purchaseTime, serialNumber, manufacturer, modelNumber or warrantyExpires"`
+	Severity string   `json:"severity,omitempty" yaml:"severity" jsonschema:"Severity of a missing requirement: error, warning (the default) or info"`
+	Ignore   []string `json:"ignore,omitempty" yaml:"ignore" jsonschema:"Checks not to run on the items, such as suspicious_quantity or missing_location"`
+}
+
+// Input for the lint_inventory tool.
+type LintInventoryInput struct {
+	Rules        map[string]LabelRule `json:"rules,omitempty" jsonschema:"Rules by label name, replacing the rule of that label from HOMEBOX_LINT_RULES"`
+	MinSeverity  string               `json:"minSeverity,omitempty" jsonschema:"Leave out findings below this severity: error, warning or info (the default)"`
+	WarrantyDays int                  `json:"warrantyDays,omitempty" jsonschema:"Report warranties expiring within this many days that have no receipt; 90 by default"`
+	MaxQuantity  int                  `json:"maxQuantity,omitempty" jsonschema:"Quantities above this are suspicious; 1000 by default"`
+}
+
+// SuggestedFix is a tool call that fixes a finding. Values in angle brackets
+// are placeholders to fill in.
+type SuggestedFix struct {
+	Tool      string         `json:"tool" jsonschema:"Tool to call"`
+	Arguments map[string]any `json:"arguments" jsonschema:"Arguments of the call"`
+}
+
+// LintFinding is a data-quality problem.
+type LintFinding struct {
+	Check    string        `json:"check" jsonschema:"Check that found the problem, such as missing_location"`
+	Severity string        `json:"severity" jsonschema:"error, warning or info"`
+	Kind     string        `json:"kind" jsonschema:"item, location or label"`
+	ID       string        `json:"id" jsonschema:"ID of the item, location or label"`
+	Name     string        `json:"name" jsonschema:"Name of the item or label, or path of the location"`
+	Message  string        `json:"message" jsonschema:"What is wrong"`
+	Fix      *SuggestedFix `json:"fix,omitempty" jsonschema:"Tool call that fixes the problem, if there is one"`
+}
+
+// Output for the lint_inventory tool.
+type LintInventoryOutput struct {
+	Items     int            `json:"items" jsonschema:"Number of items checked"`
+	Locations int            `json:"locations" jsonschema:"Number of locations checked"`
+	Labels    int            `json:"labels" jsonschema:"Number of labels checked"`
+	Counts    map[string]int `json:"counts" jsonschema:"Number of findings by severity"`
+	Findings  []LintFinding  `json:"findings" jsonschema:"The findings, most severe first"`
+}
+
+// lintRulesFromEnv reads the label rules from the YAML file named by
+// HOMEBOX_LINT_RULES, a map from label name to rule.
+func lintRulesFromEnv() (map[string]LabelRule, error) {
+	path := os.Getenv("HOMEBOX_LINT_RULES")
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HOMEBOX_LINT_RULES: %w", err)
+	}
+	var rules map[string]LabelRule
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse HOMEBOX_LINT_RULES: %w", err)
+	}
+	return rules, nil
+}
+
+// validate checks the requirements, severity and checks named by a rule.
+func (r LabelRule) validate(label string) error {
+	for _, name := range r.Require {
+		if _, ok := lintRequirements[name]; !ok {
+			return fmt.Errorf("rule for %s requires unknown %q", label, name)
+		}
+	}
+	if r.Severity != "" && !slices.Contains(lintSeverities, r.Severity) {
+		return fmt.Errorf("rule for %s has unknown severity %q", label, r.Severity)
+	}
+	return nil
+}
+
+// lintInventory is the implementation of the "lint_inventory" tool. Archived
+// items are not checked, but still count as using their labels and location.
+func lintInventory(ctx context.Context, req *mcp.CallToolRequest, input LintInventoryInput) (*mcp.CallToolResult, LintInventoryOutput, error) {
+	minSeverity := input.MinSeverity
+	if minSeverity == "" {
+		minSeverity = "info"
+	}
+	if !slices.Contains(lintSeverities, minSeverity) {
+		return nil, LintInventoryOutput{}, fmt.Errorf("minSeverity must be error, warning or info")
+	}
+	warrantyDays := input.WarrantyDays
+	if warrantyDays <= 0 {
+		warrantyDays = defaultWarrantyDays
+	}
+	maxQuantity := input.MaxQuantity
+	if maxQuantity <= 0 {
+		maxQuantity = defaultMaxQuantity
+	}
+
+	rules, err := lintRulesFromEnv()
+	if err != nil {
+		return nil, LintInventoryOutput{}, err
+	}
+	if rules == nil {
+		rules = make(map[string]LabelRule)
+	}
+	for label, rule := range input.Rules {
+		rules[label] = rule
+	}
+	// Rules apply to labels by name, ignoring case.
+	rulesByLabel := make(map[string]LabelRule)
+	for label, rule := range rules {
+		if err := rule.validate(label); err != nil {
+			return nil, LintInventoryOutput{}, err
+		}
+		rulesByLabel[strings.ToLower(label)] = rule
+	}
+
+	_, labels, err := getLabels(ctx, nil, GetLabelsInput{})
+	if err != nil {
+		return nil, LintInventoryOutput{}, err
+	}
+	forest, err := loadLocationForest(ctx)
+	if err != nil {
+		return nil, LintInventoryOutput{}, err
+	}
+	summaries, err := queryAllItems(ctx, url.Values{"includeArchived": {"true"}})
+	if err != nil {
+		return nil, LintInventoryOutput{}, err
+	}
+
+	items, err := readItems(ctx, req, summaries)
+	if err != nil {
+		return nil, LintInventoryOutput{}, err
+	}
+
+	out := LintInventoryOutput{Items: len(items), Locations: len(forest.Nodes), Labels: len(labels.Labels), Findings: []LintFinding{}}
+	report := func(f LintFinding) {
+		if slices.Index(lintSeverities, f.Severity) <= slices.Index(lintSeverities, minSeverity) {
+			out.Findings = append(out.Findings, f)
+		}
+	}
+
+	usedLabels := make(map[string]bool)
+	usedLocations := make(map[string]bool)
+	today := time.Now().Format(time.DateOnly)
+	warrantyHorizon := time.Now().AddDate(0, 0, warrantyDays).Format(time.DateOnly)
+	for _, item := range items {
+		for _, label := range item.Labels {
+			usedLabels[label.ID] = true
+		}
+		if item.Location != nil {
+			usedLocations[item.Location.ID] = true
+		}
+		if item.Archived {
+			continue
+		}
+
+		ignored := make(map[string]bool)
+		required := make(map[string]string)
+		for _, label := range item.Labels {
+			rule, ok := rulesByLabel[strings.ToLower(label.Name)]
+			if !ok {
+				continue
+			}
+			for _, check := range rule.Ignore {
+				ignored[check] = true
+			}
+			severity := rule.Severity
+			if severity == "" {
+				severity = "warning"
+			}
+			for _, name := range rule.Require {
+				// The most severe rule requiring something wins.
+				if current, ok := required[name]; !ok || slices.Index(lintSeverities, severity) < slices.Index(lintSeverities, current) {
+					required[name] = severity
+				}
+			}
+		}
+		itemFinding := func(check, severity, message string, fix *SuggestedFix) {
+			if !ignored[check] {
+				report(LintFinding{Check: check, Severity: severity, Kind: "item", ID: item.ID, Name: item.Name, Message: message, Fix: fix})
+			}
+		}
+
+		if item.Location == nil {
+			itemFinding("missing_location", "warning", "has no location", &SuggestedFix{
+				Tool:      "update_items_bulk",
+				Arguments: map[string]any{"select": map[string]any{"ids": []string{item.ID}}, "patch": map[string]any{"locationId": "<location>"}},
+			})
+		}
+		for _, name := range slices.Sorted(maps.Keys(required)) {
+			requirement := lintRequirements[name]
+			if requirement.has(item) {
+				continue
+			}
+			var fix *SuggestedFix
+			if requirement.field != "" {
+				fix = updateItemFix(item, requirement.field, requirement.placeholder)
+			}
+			itemFinding(requirement.check, required[name], requirement.message, fix)
+		}
+		if expires := dateOnly(item.WarrantyExpires); !item.LifetimeWarranty && expires >= today && expires <= warrantyHorizon && !hasAttachment(item, "receipt") && !hasAttachment(item, "warranty") {
+			itemFinding("warranty_without_receipt", "warning", fmt.Sprintf("warranty expires on %s and there is no receipt or warranty attachment", expires), nil)
+		}
+		switch {
+		case item.Quantity < 1:
+			itemFinding("suspicious_quantity", "warning", fmt.Sprintf("quantity is %d", item.Quantity), updateItemFix(item, "quantity", "<quantity>"))
+		case item.Quantity > maxQuantity:
+			itemFinding("suspicious_quantity", "info", fmt.Sprintf("quantity is %d, more than %d", item.Quantity, maxQuantity), updateItemFix(item, "quantity", "<quantity>"))
+		}
+	}
+
+	for _, node := range forest.Nodes {
+		if !usedLocations[node.ID] && len(node.Children) == 0 {
+			report(LintFinding{
+				Check: "empty_location", Severity: "info", Kind: "location", ID: node.ID, Name: node.Path,
+				Message: "holds no items and no locations",
+				Fix:     &SuggestedFix{Tool: "delete_location", Arguments: map[string]any{"id": node.ID}},
+			})
+		}
+	}
+	for _, label := range labels.Labels {
+		if !usedLabels[label.ID] {
+			report(LintFinding{
+				Check: "unused_label", Severity: "info", Kind: "label", ID: label.ID, Name: label.Name,
+				Message: "is not used by any item",
+				Fix:     &SuggestedFix{Tool: "delete_label", Arguments: map[string]any{"id": label.ID}},
+			})
+		}
+		if label.Color != "" && !labelColorPattern.MatchString(label.Color) {
+			report(LintFinding{
+				Check: "invalid_label_color", Severity: "warning", Kind: "label", ID: label.ID, Name: label.Name,
+				Message: fmt.Sprintf("color %q is not a hex color such as #3b82f6", label.Color),
+				Fix: &SuggestedFix{Tool: "update_label", Arguments: map[string]any{
+					"id": label.ID, "name": label.Name, "description": label.Description, "color": "<#rrggbb>",
+				}},
+			})
+		}
+	}
+
+	kinds := []string{"item", "location", "label"}
+	slices.SortStableFunc(out.Findings, func(a, b LintFinding) int {
+		if d := slices.Index(lintSeverities, a.Severity) - slices.Index(lintSeverities, b.Severity); d != 0 {
+			return d
+		}
+		if d := slices.Index(kinds, a.Kind) - slices.Index(kinds, b.Kind); d != 0 {
+			return d
+		}
+		if d := strings.Compare(a.Name, b.Name); d != 0 {
+			return d
+		}
+		return strings.Compare(a.Check, b.Check)
+	})
+	out.Counts = make(map[string]int)
+	for _, f := range out.Findings {
+		out.Counts[f.Severity]++
+	}
+	return nil, out, nil
+}
+
+// hasAttachment reports whether item has an attachment of the given type.
+func hasAttachment(item ItemOut, attachmentType string) bool {
+	return slices.ContainsFunc(item.Attachments, func(a ItemAttachment) bool { return a.Type == attachmentType })
+}
+
+// updateItemFix suggests an update_item call that keeps item as it is but
+// sets field to placeholder, since update_item replaces the whole item.
+func updateItemFix(item ItemOut, field, placeholder string) *SuggestedFix {
+	var arguments map[string]any
+	data, _ := json.Marshal(updateInputFromItem(item))
+	json.Unmarshal(data, &arguments)
+	arguments[field] = placeholder
+	return &SuggestedFix{Tool: "update_item", Arguments: arguments}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLintInventory(t *testing.T) {
+	items := newFakeItemStore()
+	items.labels = map[string]*LabelOut{
+		electronicsID: {ID: electronicsID, Name: "Electronics", Color: "blue"},
+		toolsID:       {ID: toolsID, Name: "Tools", Color: "#f80"},
+		lowerID:       {ID: lowerID, Name: "Spare"},
+		singularID:    {ID: singularID, Name: "Old"},
+	}
+	electronics := []LabelSummary{{ID: electronicsID, Name: "Electronics"}}
+	expires := time.Now().AddDate(0, 0, 10).Format(time.DateOnly)
+	items.items[item1ID] = ItemOut{ID: item1ID, Name: "Radio", Quantity: 1, Labels: electronics, Location: &LocationSummary{ID: garageID}}
+	items.items[item2ID] = ItemOut{ID: item2ID, Name: "Screws", Quantity: 5000, Labels: []LabelSummary{{ID: toolsID, Name: "Tools"}}}
+	items.items[item3ID] = ItemOut{ID: item3ID, Name: "TV", SerialNumber: "SN-1", WarrantyExpires: expires, Labels: electronics, Location: &LocationSummary{ID: garageID}}
+	items.items["00000000-0000-4000-8000-000000000004"] = ItemOut{ID: "00000000-0000-4000-8000-000000000004", Name: "Lamp", Archived: true, Labels: []LabelSummary{{ID: singularID, Name: "Old"}}}
+	locations := newFakeLocationStore(
+		&fakeLocation{ID: garageID, Name: "Garage", Items: 2},
+		&fakeLocation{ID: garage2ID, Name: "Attic"},
+		&fakeLocation{ID: shelfID, Name: "Shelf", ParentID: garage2ID},
+	)
+	mux := http.NewServeMux()
+	mux.Handle("/api/v1/locations", locations)
+	mux.Handle("/api/v1/locations/", locations)
+	mux.Handle("/", items)
+	homebox := httptest.NewServer(mux)
+	defer homebox.Close()
+	os.Setenv("HOMEBOX_URL", homebox.URL)
+	os.Setenv("HOMEBOX_TOKEN", "test-token")
+
+	rules := filepath.Join(t.TempDir(), "rules.yaml")
+	os.WriteFile(rules, []byte("electronics:\n  require: [serialNumber]\n  severity: error\nTools:\n  require: [photo]\n"), 0o644)
+	t.Setenv("HOMEBOX_LINT_RULES", rules)
+	ctx := context.Background()
+
+	_, out, err := lintInventory(ctx, nil, LintInventoryInput{Rules: map[string]LabelRule{"Tools": {Ignore: []string{"suspicious_quantity"}}}})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, out.Items)
+	assert.Equal(t, 3, out.Locations)
+	assert.Equal(t, 4, out.Labels)
+
+	type finding struct{ check, severity, name string }
+	var got []finding
+	for _, f := range out.Findings {
+		got = append(got, finding{f.Check, f.Severity, f.Name})
+	}
+	assert.Equal(t, []finding{
+		{"missing_serial_number", "error", "Radio"},
+		{"missing_location", "warning", "Screws"},
+		{"suspicious_quantity", "warning", "TV"},
+		{"warranty_without_receipt", "warning", "TV"},
+		{"invalid_label_color", "warning", "Electronics"},
+		{"empty_location", "info", "Attic/Shelf"},
+		{"unused_label", "info", "Spare"},
+	}, got)
+	assert.Equal(t, map[string]int{"error": 1, "warning": 4, "info": 2}, out.Counts)
+
+	fix := out.Findings[0].Fix
+	assert.Equal(t, "update_item", fix.Tool)
+	assert.Equal(t, "<serial number>", fix.Arguments["serialNumber"])
+	assert.Equal(t, "Radio", fix.Arguments["name"])
+	assert.Equal(t, garageID, fix.Arguments["locationId"])
+	assert.Equal(t, &SuggestedFix{Tool: "delete_location", Arguments: map[string]any{"id": shelfID}}, out.Findings[5].Fix)
+
+	_, out, err = lintInventory(ctx, nil, LintInventoryInput{MinSeverity: "error"})
+	assert.NoError(t, err)
+	assert.Len(t, out.Findings, 1)
+
+	_, _, err = lintInventory(ctx, nil, LintInventoryInput{Rules: map[string]LabelRule{"Tools": {Require: []string{"barcode"}}}})
+	assert.ErrorContains(t, err, `unknown "barcode"`)
+}
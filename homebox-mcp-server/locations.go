@@ -0,0 +1,506 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"gopkg.in/yaml.v3"
+)
+
+// locationNode is a location with its place in the location tree.
+type locationNode struct {
+	ID          string
+	Name        string
+	Description string
+	ParentID    string
+	Path        string
+	Children    []string
+	ItemCount   int
+}
+
+// locationForest is every location by ID, with the top-level IDs in order.
+type locationForest struct {
+	Nodes map[string]*locationNode
+	Roots []string
+}
+
+// loadLocationForest reads the location tree together with the description
+// and item count of each location.
+func loadLocationForest(ctx context.Context) (*locationForest, error) {
+	tree, err := getLocationTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+	_, locations, err := getLocations(ctx, nil, GetLocationsInput{})
+	if err != nil {
+		return nil, err
+	}
+
+	forest := &locationForest{Nodes: make(map[string]*locationNode)}
+	var walk func(items []TreeItem, parentID, prefix string)
+	walk = func(items []TreeItem, parentID, prefix string) {
+		for _, item := range items {
+			if item.Type != "" && item.Type != "location" {
+				continue
+			}
+			node := &locationNode{ID: item.ID, Name: item.Name, ParentID: parentID, Path: prefix + item.Name}
+			forest.Nodes[item.ID] = node
+			if parentID == "" {
+				forest.Roots = append(forest.Roots, item.ID)
+			} else {
+				forest.Nodes[parentID].Children = append(forest.Nodes[parentID].Children, item.ID)
+			}
+			walk(item.Children, item.ID, node.Path+"/")
+		}
+	}
+	walk(tree, "", "")
+	for _, loc := range locations.Locations {
+		if node, ok := forest.Nodes[loc.ID]; ok {
+			node.Description, node.ItemCount = loc.Description, loc.ItemCount
+		}
+	}
+	return forest, nil
+}
+
+// children returns the IDs of the locations directly under id, or of the
+// top-level locations if id is empty.
+func (f *locationForest) children(id string) []string {
+	if id == "" {
+		return f.Roots
+	}
+	return f.Nodes[id].Children
+}
+
+// isWithin reports whether id is ancestor or one of its descendants.
+func (f *locationForest) isWithin(id, ancestor string) bool {
+	for ; id != ""; id = f.Nodes[id].ParentID {
+		if id == ancestor {
+			return true
+		}
+		if f.Nodes[id] == nil {
+			return false
+		}
+	}
+	return false
+}
+
+// Input for the move_location tool.
+type MoveLocationInput struct {
+	ID       string `json:"id" jsonschema:"Location to move, by ID, name or path"`
+	ParentID string `json:"parentId,omitempty" jsonschema:"New parent location, by ID, name or path. Leave empty to make the location top-level"`
+}
+
+// moveLocation is the implementation of the "move_location" tool. The
+// locations and items below the location move with it.
+func moveLocation(ctx context.Context, req *mcp.CallToolRequest, input MoveLocationInput) (*mcp.CallToolResult, LocationOut, error) {
+	id, err := resolveLocationRef(ctx, "id", input.ID, false)
+	if err != nil {
+		return nil, LocationOut{}, err
+	}
+	parentID, err := resolveLocationRef(ctx, "parentId", input.ParentID, false)
+	if err != nil {
+		return nil, LocationOut{}, err
+	}
+
+	forest, err := loadLocationForest(ctx)
+	if err != nil {
+		return nil, LocationOut{}, err
+	}
+	node, ok := forest.Nodes[id]
+	if !ok {
+		return nil, LocationOut{}, fmt.Errorf("location %s not found", input.ID)
+	}
+	if parentID != "" {
+		parent, ok := forest.Nodes[parentID]
+		if !ok {
+			return nil, LocationOut{}, fmt.Errorf("location %s not found", input.ParentID)
+		}
+		if forest.isWithin(parentID, id) {
+			return nil, LocationOut{}, fmt.Errorf("cannot move %s into %s: the new parent is inside the location being moved", node.Path, parent.Path)
+		}
+	}
+
+	defer inventory.Invalidate()
+	return updateLocation(ctx, nil, UpdateLocationInput{ID: id, Name: node.Name, Description: node.Description, ParentID: parentID})
+}
+
+// Input for the restructure_locations tool.
+type RestructureLocationsInput struct {
+	Outline       string `json:"outline" jsonschema:"The desired location tree as a YAML or JSON outline. Each entry is a name, a name mapped to its children, or an object with name, id, description and children"`
+	Root          string `json:"root,omitempty" jsonschema:"Location the outline describes the contents of, by ID, name or path. Leave empty for the whole tree"`
+	DeleteMissing bool   `json:"deleteMissing,omitempty" jsonschema:"Delete locations below the root that the outline leaves out, if they hold no items"`
+	Apply         bool   `json:"apply,omitempty" jsonschema:"Apply the plan. Without it, the plan is only returned"`
+}
+
+// LocationChange is one step of a restructure plan.
+type LocationChange struct {
+	Action string `json:"action" jsonschema:"create, rename, move, describe, delete or keep"`
+	ID     string `json:"id,omitempty" jsonschema:"ID of the location; empty for locations still to be created"`
+	Path   string `json:"path" jsonschema:"Path of the location once the plan is applied, or its current path for delete and keep"`
+	From   string `json:"from,omitempty" jsonschema:"Previous name, path or description"`
+	Reason string `json:"reason,omitempty" jsonschema:"Why a location that the outline leaves out is kept"`
+}
+
+// Output for the restructure_locations tool.
+type RestructureLocationsOutput struct {
+	Applied bool             `json:"applied" jsonschema:"Whether the plan was applied"`
+	Changes []LocationChange `json:"changes" jsonschema:"The plan, in the order it is applied"`
+}
+
+// outlineNode is an entry of a restructure outline.
+type outlineNode struct {
+	Name        string
+	Ref         string
+	Description string
+	Children    []*outlineNode
+
+	// Set while planning.
+	id       string
+	existing *locationNode
+	parent   *outlineNode
+	path     string
+}
+
+// parseOutline parses a YAML or JSON outline. The top level is a list of
+// entries, or a mapping from names to their children.
+func parseOutline(text string) ([]*outlineNode, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(text), &doc); err != nil {
+		return nil, fmt.Errorf("invalid outline: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, fmt.Errorf("outline is empty")
+	}
+	return parseOutlineList(doc.Content[0])
+}
+
+func parseOutlineList(n *yaml.Node) ([]*outlineNode, error) {
+	switch n.Kind {
+	case yaml.SequenceNode:
+		var nodes []*outlineNode
+		for _, entry := range n.Content {
+			parsed, err := parseOutlineEntry(entry)
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, parsed...)
+		}
+		return nodes, nil
+	case yaml.MappingNode:
+		return parseOutlineEntry(n)
+	case yaml.ScalarNode:
+		if n.Tag == "!!null" {
+			return nil, nil
+		}
+	}
+	return nil, fmt.Errorf("line %d: expected a list of locations", n.Line)
+}
+
+// parseOutlineEntry parses one list entry, which a mapping from names to
+// children may expand to several locations.
+func parseOutlineEntry(n *yaml.Node) ([]*outlineNode, error) {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		node, err := newOutlineNode(n.Value, n.Line)
+		return []*outlineNode{node}, err
+	case yaml.MappingNode:
+		if isOutlineObject(n) {
+			node, err := parseOutlineObject(n)
+			return []*outlineNode{node}, err
+		}
+		var nodes []*outlineNode
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			node, err := newOutlineNode(n.Content[i].Value, n.Content[i].Line)
+			if err != nil {
+				return nil, err
+			}
+			if node.Children, err = parseOutlineList(n.Content[i+1]); err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, node)
+		}
+		return nodes, nil
+	}
+	return nil, fmt.Errorf("line %d: expected a location name or object", n.Line)
+}
+
+// isOutlineObject reports whether a mapping is an object with a name key
+// rather than a mapping from names to children.
+func isOutlineObject(n *yaml.Node) bool {
+	for i := 0; i < len(n.Content); i += 2 {
+		if n.Content[i].Value == "name" && n.Content[i+1].Kind == yaml.ScalarNode {
+			return true
+		}
+	}
+	return false
+}
+
+func parseOutlineObject(n *yaml.Node) (*outlineNode, error) {
+	var fields struct {
+		Name        string `yaml:"name"`
+		ID          string `yaml:"id"`
+		Description string `yaml:"description"`
+	}
+	if err := n.Decode(&fields); err != nil {
+		return nil, fmt.Errorf("line %d: %w", n.Line, err)
+	}
+	node, err := newOutlineNode(fields.Name, n.Line)
+	if err != nil {
+		return nil, err
+	}
+	node.Ref, node.Description = strings.TrimSpace(fields.ID), fields.Description
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		switch key := n.Content[i].Value; key {
+		case "name", "id", "description":
+		case "children":
+			if node.Children, err = parseOutlineList(n.Content[i+1]); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("line %d: unknown key %q", n.Content[i].Line, key)
+		}
+	}
+	return node, nil
+}
+
+func newOutlineNode(name string, line int) (*outlineNode, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("line %d: location name is empty", line)
+	}
+	if strings.Contains(name, "/") {
+		return nil, fmt.Errorf("line %d: location name %q contains a slash; nest it in the outline instead", line, name)
+	}
+	return &outlineNode{Name: name}, nil
+}
+
+// restructurePlan is the plan for applying an outline below a root.
+type restructurePlan struct {
+	forest  *locationForest
+	rootID  string
+	nodes   []*outlineNode // in outline order, parents first
+	deletes []*locationNode
+	changes []LocationChange
+}
+
+// planRestructure works out how to turn the tree below rootID into outline.
+// Each entry is matched to an existing location: the one its id names, else
+// a child of its parent with the same name, else the one location below the
+// root with that name. Entries that match nothing are created.
+func planRestructure(ctx context.Context, outline []*outlineNode, rootID string, deleteMissing bool) (*restructurePlan, error) {
+	forest, err := loadLocationForest(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rootPath := ""
+	if rootID != "" {
+		root, ok := forest.Nodes[rootID]
+		if !ok {
+			return nil, fmt.Errorf("location %s not found", rootID)
+		}
+		rootPath = root.Path + "/"
+	}
+	plan := &restructurePlan{forest: forest, rootID: rootID}
+	claimed := make(map[string]*outlineNode)
+
+	var walk func(nodes []*outlineNode, parent *outlineNode) error
+	walk = func(nodes []*outlineNode, parent *outlineNode) error {
+		for _, node := range nodes {
+			node.parent = parent
+			if parent == nil {
+				node.path = rootPath + node.Name
+			} else {
+				node.path = parent.path + "/" + node.Name
+			}
+			existing, err := plan.match(ctx, node, claimed)
+			if err != nil {
+				return err
+			}
+			plan.nodes = append(plan.nodes, node)
+			if existing == nil {
+				plan.changes = append(plan.changes, LocationChange{Action: "create", Path: node.path})
+			} else {
+				if rootID != "" && forest.isWithin(rootID, existing.ID) {
+					return fmt.Errorf("%s cannot be placed inside itself", existing.Path)
+				}
+				if other := claimed[existing.ID]; other != nil {
+					return fmt.Errorf("%s and %s both refer to %s", other.path, node.path, existing.Path)
+				}
+				claimed[existing.ID] = node
+				node.existing, node.id = existing, existing.ID
+				plan.changes = append(plan.changes, node.updates(rootID)...)
+			}
+			if err := walk(node.Children, node); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(outline, nil); err != nil {
+		return nil, err
+	}
+
+	if deleteMissing {
+		var visit func(id string) bool
+		visit = func(id string) bool {
+			node := forest.Nodes[id]
+			deletable := true
+			for _, child := range node.Children {
+				if !visit(child) && claimed[child] == nil {
+					deletable = false
+				}
+			}
+			if claimed[id] != nil {
+				return false
+			}
+			switch {
+			case node.ItemCount > 0:
+				plan.changes = append(plan.changes, LocationChange{Action: "keep", ID: id, Path: node.Path, Reason: fmt.Sprintf("holds %d item(s)", node.ItemCount)})
+			case !deletable:
+				plan.changes = append(plan.changes, LocationChange{Action: "keep", ID: id, Path: node.Path, Reason: "holds locations that are kept"})
+			default:
+				plan.deletes = append(plan.deletes, node)
+				plan.changes = append(plan.changes, LocationChange{Action: "delete", ID: id, Path: node.Path})
+				return true
+			}
+			return false
+		}
+		for _, id := range forest.children(rootID) {
+			visit(id)
+		}
+	}
+	return plan, nil
+}
+
+// match returns the existing location node refers to, or nil.
+func (p *restructurePlan) match(ctx context.Context, node *outlineNode, claimed map[string]*outlineNode) (*locationNode, error) {
+	if node.Ref != "" {
+		id, err := resolveLocationRef(ctx, "outline "+node.path, node.Ref, false)
+		if err != nil {
+			return nil, err
+		}
+		existing, ok := p.forest.Nodes[id]
+		if !ok {
+			return nil, fmt.Errorf("location %s not found", node.Ref)
+		}
+		return existing, nil
+	}
+
+	parentID := p.rootID
+	if node.parent != nil {
+		parentID = node.parent.id
+	}
+	if node.parent == nil || node.parent.existing != nil {
+		for _, id := range p.forest.children(parentID) {
+			if claimed[id] == nil && strings.EqualFold(p.forest.Nodes[id].Name, node.Name) {
+				return p.forest.Nodes[id], nil
+			}
+		}
+	}
+
+	var matches []indexEntry
+	for _, existing := range p.forest.Nodes {
+		if claimed[existing.ID] == nil && strings.EqualFold(existing.Name, node.Name) && (p.rootID == "" || (existing.ID != p.rootID && p.forest.isWithin(existing.ID, p.rootID))) {
+			matches = append(matches, indexEntry{ID: existing.ID, Name: existing.Name, Path: existing.Path})
+		}
+	}
+	if len(matches) > 1 {
+		return nil, matchReference("outline "+node.path, node.Name, matches)
+	}
+	if len(matches) == 1 {
+		return p.forest.Nodes[matches[0].ID], nil
+	}
+	return nil, nil
+}
+
+// updates returns the changes that turn the existing location of node into
+// the one the outline describes.
+func (node *outlineNode) updates(rootID string) []LocationChange {
+	var changes []LocationChange
+	existing := node.existing
+	if existing.Name != node.Name {
+		changes = append(changes, LocationChange{Action: "rename", ID: existing.ID, Path: node.path, From: existing.Name})
+	}
+	if node.parentID(rootID) != existing.ParentID || (node.parent != nil && node.parent.existing == nil) {
+		changes = append(changes, LocationChange{Action: "move", ID: existing.ID, Path: node.path, From: existing.Path})
+	}
+	if node.Description != "" && node.Description != existing.Description {
+		changes = append(changes, LocationChange{Action: "describe", ID: existing.ID, Path: node.path, From: existing.Description})
+	}
+	return changes
+}
+
+// parentID returns the ID of the location node is to be placed in.
+func (node *outlineNode) parentID(rootID string) string {
+	if node.parent == nil {
+		return rootID
+	}
+	return node.parent.id
+}
+
+// apply carries out the plan: locations are created, renamed and moved
+// parents first, so that every new parent exists before its children are
+// placed in it, and deleted children first.
+func (p *restructurePlan) apply(ctx context.Context, progress *toolProgress) error {
+	defer inventory.Invalidate()
+	total := float64(len(p.nodes) + len(p.deletes))
+	for i, node := range p.nodes {
+		description := node.Description
+		if node.existing == nil {
+			_, created, err := createLocation(ctx, nil, CreateLocationInput{Name: node.Name, Description: description, ParentID: node.parentID(p.rootID)})
+			if err != nil {
+				return fmt.Errorf("failed to create %s: %w", node.path, err)
+			}
+			node.id = created.ID
+		} else if len(node.updates(p.rootID)) > 0 {
+			if description == "" {
+				description = node.existing.Description
+			}
+			_, _, err := updateLocation(ctx, nil, UpdateLocationInput{ID: node.id, Name: node.Name, Description: description, ParentID: node.parentID(p.rootID)})
+			if err != nil {
+				return fmt.Errorf("failed to update %s: %w", node.path, err)
+			}
+		}
+		progress.Report(ctx, float64(i+1), total, "Restructuring locations")
+	}
+	for i, node := range p.deletes {
+		if _, _, err := deleteLocation(ctx, nil, DeleteLocationInput{ID: node.ID}); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", node.Path, err)
+		}
+		progress.Report(ctx, float64(len(p.nodes)+i+1), total, "Deleting locations")
+	}
+	return nil
+}
+
+// restructureLocations is the implementation of the "restructure_locations"
+// tool. Without apply it only returns the plan, so the caller can review it
+// and call again with apply.
+func restructureLocations(ctx context.Context, req *mcp.CallToolRequest, input RestructureLocationsInput) (*mcp.CallToolResult, RestructureLocationsOutput, error) {
+	outline, err := parseOutline(input.Outline)
+	if err != nil {
+		return nil, RestructureLocationsOutput{}, err
+	}
+	rootID, err := resolveLocationRef(ctx, "root", input.Root, false)
+	if err != nil {
+		return nil, RestructureLocationsOutput{}, err
+	}
+	plan, err := planRestructure(ctx, outline, rootID, input.DeleteMissing)
+	if err != nil {
+		return nil, RestructureLocationsOutput{}, err
+	}
+	out := RestructureLocationsOutput{Changes: plan.changes}
+	if out.Changes == nil {
+		out.Changes = []LocationChange{}
+	}
+	if !input.Apply {
+		return nil, out, nil
+	}
+
+	if err := plan.apply(ctx, newToolProgress(req)); err != nil {
+		return nil, RestructureLocationsOutput{}, fmt.Errorf("%w; the changes before it were applied", err)
+	}
+	out.Applied = true
+	return nil, out, nil
+}
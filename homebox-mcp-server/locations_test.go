@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeLocation is a location of fakeLocationStore.
+type fakeLocation struct {
+	ID, Name, Description, ParentID string
+	Items                           int
+}
+
+// fakeLocationStore is a fake Homebox that keeps locations in memory.
+type fakeLocationStore struct {
+	mu        sync.Mutex
+	locations map[string]*fakeLocation
+	next      int
+}
+
+func newFakeLocationStore(locations ...*fakeLocation) *fakeLocationStore {
+	s := &fakeLocationStore{locations: make(map[string]*fakeLocation)}
+	for _, loc := range locations {
+		s.locations[loc.ID] = loc
+	}
+	return s
+}
+
+// path returns the path of the location with the given ID.
+func (s *fakeLocationStore) path(id string) string {
+	loc := s.locations[id]
+	if loc.ParentID == "" {
+		return loc.Name
+	}
+	return s.path(loc.ParentID) + "/" + loc.Name
+}
+
+// paths returns the path of every location, sorted.
+func (s *fakeLocationStore) paths() []string {
+	var paths []string
+	for id := range s.locations {
+		paths = append(paths, s.path(id))
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func (s *fakeLocationStore) tree(parentID string) []TreeItem {
+	items := []TreeItem{}
+	for _, loc := range s.locations {
+		if loc.ParentID == parentID {
+			items = append(items, TreeItem{ID: loc.ID, Name: loc.Name, Type: "location", Children: s.tree(loc.ID)})
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+	return items
+}
+
+func (s *fakeLocationStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, _ := strings.CutPrefix(r.URL.Path, "/api/v1/locations/")
+	switch {
+	case r.URL.Path == "/api/v1/locations/tree":
+		json.NewEncoder(w).Encode(s.tree(""))
+	case r.URL.Path == "/api/v1/locations" && r.Method == http.MethodGet:
+		locations := []LocationOutCount{}
+		for _, loc := range s.locations {
+			locations = append(locations, LocationOutCount{ID: loc.ID, Name: loc.Name, Description: loc.Description, ItemCount: loc.Items})
+		}
+		json.NewEncoder(w).Encode(locations)
+	case r.URL.Path == "/api/v1/locations" && r.Method == http.MethodPost:
+		var input CreateLocationInput
+		json.NewDecoder(r.Body).Decode(&input)
+		s.next++
+		loc := &fakeLocation{ID: fmt.Sprintf("00000000-0000-4000-b000-%012d", s.next), Name: input.Name, Description: input.Description, ParentID: input.ParentID}
+		s.locations[loc.ID] = loc
+		json.NewEncoder(w).Encode(LocationSummary{ID: loc.ID, Name: loc.Name})
+	case s.locations[id] != nil && r.Method == http.MethodGet:
+		loc := s.locations[id]
+		out := LocationOut{ID: loc.ID, Name: loc.Name, Description: loc.Description}
+		if loc.ParentID != "" {
+			out.Parent = &LocationSummary{ID: loc.ParentID}
+		}
+		for _, child := range s.locations {
+			if child.ParentID == loc.ID {
+				out.Children = append(out.Children, LocationSummary{ID: child.ID, Name: child.Name})
+			}
+		}
+		json.NewEncoder(w).Encode(out)
+	case s.locations[id] != nil && r.Method == http.MethodPut:
+		var input UpdateLocationInput
+		json.NewDecoder(r.Body).Decode(&input)
+		loc := s.locations[id]
+		loc.Name, loc.Description, loc.ParentID = input.Name, input.Description, input.ParentID
+		json.NewEncoder(w).Encode(LocationOut{ID: loc.ID, Name: loc.Name, Description: loc.Description})
+	case s.locations[id] != nil && r.Method == http.MethodDelete:
+		delete(s.locations, id)
+		w.WriteHeader(http.StatusNoContent)
+	case r.URL.Path == "/api/v1/labels":
+		w.Write([]byte(`[]`))
+	case r.URL.Path == "/api/v1/items":
+		w.Write([]byte(`{"items":[],"page":1,"pageSize":100,"total":0}`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestParseOutline(t *testing.T) {
+	nodes, err := parseOutline(`
+- Garage:
+    - Shelf 1
+    - Shelf 2:
+        - Bin A
+- name: Cellar
+  id: Basement
+  description: Under the stairs
+  children: [Wine rack]
+`)
+	assert.NoError(t, err)
+	assert.Len(t, nodes, 2)
+	assert.Equal(t, "Garage", nodes[0].Name)
+	assert.Equal(t, "Bin A", nodes[0].Children[1].Children[0].Name)
+	assert.Equal(t, &outlineNode{Name: "Cellar", Ref: "Basement", Description: "Under the stairs", Children: []*outlineNode{{Name: "Wine rack"}}}, nodes[1])
+
+	nodes, err = parseOutline(`{"Garage": ["Shelf 1", {"Shelf 2": null}], "Attic": null}`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Garage", "Attic"}, []string{nodes[0].Name, nodes[1].Name})
+	assert.Equal(t, "Shelf 2", nodes[0].Children[1].Name)
+
+	_, err = parseOutline(`- Garage/Shelf 1`)
+	assert.ErrorContains(t, err, "contains a slash")
+	_, err = parseOutline(`- name: Garage
+  colour: red`)
+	assert.ErrorContains(t, err, `unknown key "colour"`)
+}
+
+func TestMoveLocation(t *testing.T) {
+	store := newFakeLocationStore(
+		&fakeLocation{ID: "00000000-0000-4000-b000-000000000101", Name: "Garage"},
+		&fakeLocation{ID: "00000000-0000-4000-b000-000000000102", Name: "Shelf", ParentID: "00000000-0000-4000-b000-000000000101", Description: "Metal"},
+		&fakeLocation{ID: "00000000-0000-4000-b000-000000000103", Name: "Bin", ParentID: "00000000-0000-4000-b000-000000000102"},
+		&fakeLocation{ID: "00000000-0000-4000-b000-000000000104", Name: "Basement"},
+	)
+	homebox := httptest.NewServer(store)
+	defer homebox.Close()
+
+	os.Setenv("HOMEBOX_URL", homebox.URL)
+	os.Setenv("HOMEBOX_TOKEN", "test-token")
+	inventory.Invalidate()
+	defer inventory.Invalidate()
+	ctx := context.Background()
+
+	_, _, err := moveLocation(ctx, nil, MoveLocationInput{ID: "Garage/Shelf", ParentID: "Garage/Shelf/Bin"})
+	assert.ErrorContains(t, err, "inside the location being moved")
+	_, _, err = moveLocation(ctx, nil, MoveLocationInput{ID: "Garage/Shelf", ParentID: "Garage/Shelf"})
+	assert.ErrorContains(t, err, "inside the location being moved")
+
+	_, _, err = moveLocation(ctx, nil, MoveLocationInput{ID: "Garage/Shelf", ParentID: "Basement"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Basement", "Basement/Shelf", "Basement/Shelf/Bin", "Garage"}, store.paths())
+	assert.Equal(t, "Metal", store.locations["00000000-0000-4000-b000-000000000102"].Description)
+
+	_, _, err = moveLocation(ctx, nil, MoveLocationInput{ID: "Basement/Shelf"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Basement", "Garage", "Shelf", "Shelf/Bin"}, store.paths())
+}
+
+func TestRestructureLocations(t *testing.T) {
+	store := newFakeLocationStore(
+		&fakeLocation{ID: "00000000-0000-4000-b000-000000000101", Name: "Garage"},
+		&fakeLocation{ID: "00000000-0000-4000-b000-000000000102", Name: "Shelf 1", ParentID: "00000000-0000-4000-b000-000000000101"},
+		&fakeLocation{ID: "00000000-0000-4000-b000-000000000103", Name: "Old bin", ParentID: "00000000-0000-4000-b000-000000000101"},
+		&fakeLocation{ID: "00000000-0000-4000-b000-000000000104", Name: "Basement", Description: "Damp"},
+		&fakeLocation{ID: "00000000-0000-4000-b000-000000000105", Name: "Shelf 2", ParentID: "00000000-0000-4000-b000-000000000104", Items: 3},
+		&fakeLocation{ID: "00000000-0000-4000-b000-000000000106", Name: "Attic", Items: 1},
+		&fakeLocation{ID: "00000000-0000-4000-b000-000000000107", Name: "Shed"},
+	)
+	homebox := httptest.NewServer(store)
+	defer homebox.Close()
+
+	os.Setenv("HOMEBOX_URL", homebox.URL)
+	os.Setenv("HOMEBOX_TOKEN", "test-token")
+	inventory.Invalidate()
+	defer inventory.Invalidate()
+	ctx := context.Background()
+
+	input := RestructureLocationsInput{
+		Outline: `
+- Garage:
+    - Shelf 1
+    - Shelf 2
+    - Rack:
+        - Box A
+- name: Cellar
+  id: Basement
+`,
+		DeleteMissing: true,
+	}
+	_, out, err := restructureLocations(ctx, nil, input)
+	assert.NoError(t, err)
+	assert.False(t, out.Applied)
+	assert.Equal(t, []LocationChange{
+		{Action: "move", ID: "00000000-0000-4000-b000-000000000105", Path: "Garage/Shelf 2", From: "Basement/Shelf 2"},
+		{Action: "create", Path: "Garage/Rack"},
+		{Action: "create", Path: "Garage/Rack/Box A"},
+		{Action: "rename", ID: "00000000-0000-4000-b000-000000000104", Path: "Cellar", From: "Basement"},
+		{Action: "keep", ID: "00000000-0000-4000-b000-000000000106", Path: "Attic", Reason: "holds 1 item(s)"},
+		{Action: "delete", ID: "00000000-0000-4000-b000-000000000103", Path: "Garage/Old bin"},
+		{Action: "delete", ID: "00000000-0000-4000-b000-000000000107", Path: "Shed"},
+	}, out.Changes)
+	assert.Len(t, store.locations, 7)
+
+	input.Apply = true
+	_, out, err = restructureLocations(ctx, nil, input)
+	assert.NoError(t, err)
+	assert.True(t, out.Applied)
+	assert.Equal(t, []string{"Attic", "Cellar", "Garage", "Garage/Rack", "Garage/Rack/Box A", "Garage/Shelf 1", "Garage/Shelf 2"}, store.paths())
+	assert.Equal(t, "Damp", store.locations["00000000-0000-4000-b000-000000000104"].Description)
+
+	// The tree now matches, so there is nothing left to do.
+	inventory.Invalidate()
+	input.DeleteMissing = false
+	input.Outline = strings.Replace(input.Outline, "id: Basement", "id: 00000000-0000-4000-b000-000000000104", 1)
+	_, out, err = restructureLocations(ctx, nil, input)
+	assert.NoError(t, err)
+	assert.Empty(t, out.Changes)
+
+	_, _, err = restructureLocations(ctx, nil, RestructureLocationsInput{Outline: "- name: Everything\n  id: Garage", Root: "Garage/Rack"})
+	assert.ErrorContains(t, err, "inside itself")
+}
@@ -10,9 +10,12 @@ import (
 	"log"
 	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // This struct represents a single item from your Homebox,
@@ -60,15 +63,29 @@ type ItemSummary struct {
 }
 
 type ItemAttachment struct {
-	ID        string          `json:"id"`
-	CreatedAt string          `json:"createdAt"`
-	MimeType  string          `json:"mimeType"`
-	Path      string          `json:"path"`
-	Primary   bool            `json:"primary"`
-	Thumbnail *ItemAttachment `json:"thumbnail,omitempty"`
-	Title     string          `json:"title"`
-	Type      string          `json:"type"`
-	UpdatedAt string          `json:"updatedAt"`
+	ID        string               `json:"id"`
+	CreatedAt string               `json:"createdAt"`
+	MimeType  string               `json:"mimeType"`
+	Path      string               `json:"path"`
+	Primary   bool                 `json:"primary"`
+	Thumbnail *AttachmentThumbnail `json:"thumbnail,omitempty"`
+	Title     string               `json:"title"`
+	Type      string               `json:"type"`
+	UpdatedAt string               `json:"updatedAt"`
+}
+
+// AttachmentThumbnail is the thumbnail generated for an ItemAttachment. It is
+// kept separate from ItemAttachment because output schemas cannot be derived
+// for self-referencing types.
+type AttachmentThumbnail struct {
+	ID        string `json:"id"`
+	CreatedAt string `json:"createdAt"`
+	MimeType  string `json:"mimeType"`
+	Path      string `json:"path"`
+	Primary   bool   `json:"primary"`
+	Title     string `json:"title"`
+	Type      string `json:"type"`
+	UpdatedAt string `json:"updatedAt"`
 }
 
 type ItemField struct {
@@ -134,6 +151,15 @@ type LocationOut struct {
 	UpdatedAt   string            `json:"updatedAt"`
 }
 
+// TreeItem is a node of the location tree. Locations are nested under their
+// parent location.
+type TreeItem struct {
+	ID       string     `json:"id"`
+	Name     string     `json:"name"`
+	Type     string     `json:"type"`
+	Children []TreeItem `json:"children"`
+}
+
 type LabelOut struct {
 	ID          string `json:"id"`
 	Name        string `json:"name"`
@@ -275,59 +301,70 @@ type BarcodeProduct struct {
 
 // Input for the create_item tool.
 type CreateItemInput struct {
-	Name        string   `json:"name" jsonschema:"minLength:1,maxLength:255"`
-	Description string   `json:"description,omitempty" jsonschema:"maxLength:1000"`
-	LabelIDs    []string `json:"labelIds,omitempty"`
-	LocationID  string   `json:"locationId,omitempty"`
-	ParentID    string   `json:"parentId,omitempty"`
-	Quantity    int      `json:"quantity,omitempty"`
+	Name        string   `json:"name" jsonschema:"Name of the item"`
+	Description string   `json:"description,omitempty" jsonschema:"Free-text description of the item"`
+	LabelIDs    []string `json:"labelIds,omitempty" jsonschema:"Labels to attach, by ID or name"`
+	LocationID  string   `json:"locationId,omitempty" jsonschema:"Location of the item, by ID, name or path such as Garage/Shelf 2"`
+	ParentID    string   `json:"parentId,omitempty" jsonschema:"Item to nest this item under, by ID, asset ID or name"`
+	Quantity    int      `json:"quantity,omitempty" jsonschema:"How many of the item there are"`
+
+	// CreateMissing creates the locations and labels named by LocationID
+	// and LabelIDs that do not exist yet.
+	CreateMissing bool `json:"createMissing,omitempty" jsonschema:"Create the locations and labels that do not exist yet"`
 }
 
 // Input for the get_item tool.
 type GetItemInput struct {
-	ID string `json:"id" jsonschema:"required"`
+	ID string `json:"id" jsonschema:"ID of the item"`
+	OutputOptions
 }
 
 // Input for the update_item tool.
 type UpdateItemInput struct {
-	ID                      string      `json:"id" jsonschema:"required"`
-	Archived                bool        `json:"archived,omitempty"`
-	AssetID                 string      `json:"assetId,omitempty"`
-	Description             string      `json:"description,omitempty"`
-	Fields                  []ItemField `json:"fields,omitempty"`
-	Insured                 bool        `json:"insured,omitempty"`
-	LabelIDs                []string    `json:"labelIds,omitempty"`
-	LifetimeWarranty        bool        `json:"lifetimeWarranty,omitempty"`
-	LocationID              string      `json:"locationId,omitempty"`
-	Manufacturer            string      `json:"manufacturer,omitempty"`
-	ModelNumber             string      `json:"modelNumber,omitempty"`
-	Name                    string      `json:"name"`
-	Notes                   string      `json:"notes,omitempty"`
-	ParentID                string      `json:"parentId,omitempty"`
-	PurchaseFrom            string      `json:"purchaseFrom,omitempty"`
-	PurchasePrice           float64     `json:"purchasePrice,omitempty"`
-	PurchaseTime            string      `json:"purchaseTime,omitempty"`
-	Quantity                int         `json:"quantity,omitempty"`
-	SerialNumber            string      `json:"serialNumber,omitempty"`
-	SoldNotes               string      `json:"soldNotes,omitempty"`
-	SoldPrice               float64     `json:"soldPrice,omitempty"`
-	SoldTime                string      `json:"soldTime,omitempty"`
-	SoldTo                  string      `json:"soldTo,omitempty"`
-	SyncChildItemsLocations bool        `json:"syncChildItemsLocations,omitempty"`
-	WarrantyDetails         string      `json:"warrantyDetails,omitempty"`
-	WarrantyExpires         string      `json:"warrantyExpires,omitempty"`
+	ID                      string      `json:"id" jsonschema:"ID or asset ID of the item to update"`
+	Archived                bool        `json:"archived,omitempty" jsonschema:"Whether the item is archived"`
+	AssetID                 string      `json:"assetId,omitempty" jsonschema:"Asset ID of the item, such as 000-042"`
+	Description             string      `json:"description,omitempty" jsonschema:"Free-text description of the item"`
+	Fields                  []ItemField `json:"fields,omitempty" jsonschema:"Custom fields of the item"`
+	Insured                 bool        `json:"insured,omitempty" jsonschema:"Whether the item is insured"`
+	LabelIDs                []string    `json:"labelIds,omitempty" jsonschema:"Labels of the item, by ID or name. Replaces the current labels"`
+	LifetimeWarranty        bool        `json:"lifetimeWarranty,omitempty" jsonschema:"Whether the warranty never expires"`
+	LocationID              string      `json:"locationId,omitempty" jsonschema:"Location of the item, by ID, name or path such as Garage/Shelf 2"`
+	Manufacturer            string      `json:"manufacturer,omitempty" jsonschema:"Manufacturer of the item"`
+	ModelNumber             string      `json:"modelNumber,omitempty" jsonschema:"Model number of the item"`
+	Name                    string      `json:"name" jsonschema:"Name of the item. The update replaces the whole item, so always send the name"`
+	Notes                   string      `json:"notes,omitempty" jsonschema:"Free-text notes"`
+	ParentID                string      `json:"parentId,omitempty" jsonschema:"Item to nest this item under, by ID, asset ID or name"`
+	PurchaseFrom            string      `json:"purchaseFrom,omitempty" jsonschema:"Where the item was bought"`
+	PurchasePrice           float64     `json:"purchasePrice,omitempty" jsonschema:"Price paid for the item"`
+	PurchaseTime            string      `json:"purchaseTime,omitempty" jsonschema:"Date the item was bought, as YYYY-MM-DD"`
+	Quantity                int         `json:"quantity,omitempty" jsonschema:"How many of the item there are"`
+	SerialNumber            string      `json:"serialNumber,omitempty" jsonschema:"Serial number of the item"`
+	SoldNotes               string      `json:"soldNotes,omitempty" jsonschema:"Notes about the sale"`
+	SoldPrice               float64     `json:"soldPrice,omitempty" jsonschema:"Price the item was sold for"`
+	SoldTime                string      `json:"soldTime,omitempty" jsonschema:"Date the item was sold, as YYYY-MM-DD"`
+	SoldTo                  string      `json:"soldTo,omitempty" jsonschema:"Who the item was sold to"`
+	SyncChildItemsLocations bool        `json:"syncChildItemsLocations,omitempty" jsonschema:"Move the items nested under this item along with it"`
+	WarrantyDetails         string      `json:"warrantyDetails,omitempty" jsonschema:"Warranty terms and contact details"`
+	WarrantyExpires         string      `json:"warrantyExpires,omitempty" jsonschema:"Date the warranty expires, as YYYY-MM-DD"`
+
+	// CreateMissing creates the locations and labels named by LocationID
+	// and LabelIDs that do not exist yet.
+	CreateMissing bool `json:"createMissing,omitempty" jsonschema:"Create the locations and labels that do not exist yet"`
 }
 
 // Input for the delete_item tool.
 type DeleteItemInput struct {
-	ID string `json:"id" jsonschema:"required"`
+	ID string `json:"id" jsonschema:"ID of the item to delete"`
 }
 
 // Output for the delete_item tool.
 type DeleteItemOutput struct{}
 
-// Input for the get_items tool. It takes no parameters.
-type GetItemsInput struct{}
+// Input for the get_items tool. It only takes output options.
+type GetItemsInput struct {
+	OutputOptions
+}
 
 // Output for the get_items tool. It returns a list of items.
 type GetItemsOutput struct {
@@ -335,7 +372,9 @@ type GetItemsOutput struct {
 }
 
 // Input for get_locations tool.
-type GetLocationsInput struct{}
+type GetLocationsInput struct {
+	OutputOptions
+}
 
 // Output for get_locations tool.
 type GetLocationsOutput struct {
@@ -344,34 +383,45 @@ type GetLocationsOutput struct {
 
 // Input for create_location tool.
 type CreateLocationInput struct {
-	Name        string `json:"name"`
-	Description string `json:"description,omitempty"`
-	ParentID    string `json:"parentId,omitempty"`
+	Name        string `json:"name" jsonschema:"Name of the location"`
+	Description string `json:"description,omitempty" jsonschema:"Free-text description of the location"`
+	ParentID    string `json:"parentId,omitempty" jsonschema:"Location to nest this location under, by ID, name or path"`
+
+	// CreateMissing creates the locations along ParentID that do not exist
+	// yet.
+	CreateMissing bool `json:"createMissing,omitempty" jsonschema:"Create the locations along the parent path that do not exist yet"`
 }
 
 // Input for get_location tool.
 type GetLocationInput struct {
-	ID string `json:"id" jsonschema:"required"`
+	ID string `json:"id" jsonschema:"ID of the location"`
+	OutputOptions
 }
 
 // Input for update_location tool.
 type UpdateLocationInput struct {
-	ID          string `json:"id" jsonschema:"required"`
-	Name        string `json:"name"`
-	Description string `json:"description,omitempty"`
-	ParentID    string `json:"parentId,omitempty"`
+	ID          string `json:"id" jsonschema:"Location to update, by ID, name or path"`
+	Name        string `json:"name" jsonschema:"Name of the location"`
+	Description string `json:"description,omitempty" jsonschema:"Free-text description of the location"`
+	ParentID    string `json:"parentId,omitempty" jsonschema:"Location to nest this location under, by ID, name or path"`
+
+	// CreateMissing creates the locations along ParentID that do not exist
+	// yet.
+	CreateMissing bool `json:"createMissing,omitempty" jsonschema:"Create the locations along the parent path that do not exist yet"`
 }
 
 // Input for delete_location tool.
 type DeleteLocationInput struct {
-	ID string `json:"id" jsonschema:"required"`
+	ID string `json:"id" jsonschema:"ID of the location to delete"`
 }
 
 // Output for delete_location tool.
 type DeleteLocationOutput struct{}
 
 // Input for get_labels tool.
-type GetLabelsInput struct{}
+type GetLabelsInput struct {
+	OutputOptions
+}
 
 // Output for get_labels tool.
 type GetLabelsOutput struct {
@@ -380,27 +430,28 @@ type GetLabelsOutput struct {
 
 // Input for create_label tool.
 type CreateLabelInput struct {
-	Name        string `json:"name" jsonschema:"minLength:1,maxLength:255"`
-	Description string `json:"description,omitempty" jsonschema:"maxLength:1000"`
-	Color       string `json:"color,omitempty"`
+	Name        string `json:"name" jsonschema:"Name of the label"`
+	Description string `json:"description,omitempty" jsonschema:"Free-text description of the label"`
+	Color       string `json:"color,omitempty" jsonschema:"Color of the label as a hex code"`
 }
 
 // Input for get_label tool.
 type GetLabelInput struct {
-	ID string `json:"id" jsonschema:"required"`
+	ID string `json:"id" jsonschema:"ID of the label"`
+	OutputOptions
 }
 
 // Input for update_label tool.
 type UpdateLabelInput struct {
-	ID          string `json:"id" jsonschema:"required"`
-	Name        string `json:"name" jsonschema:"minLength:1,maxLength:255"`
-	Description string `json:"description,omitempty" jsonschema:"maxLength:1000"`
-	Color       string `json:"color,omitempty"`
+	ID          string `json:"id" jsonschema:"ID of the label to update"`
+	Name        string `json:"name" jsonschema:"Name of the label"`
+	Description string `json:"description,omitempty" jsonschema:"Free-text description of the label"`
+	Color       string `json:"color,omitempty" jsonschema:"Color of the label as a hex code"`
 }
 
 // Input for delete_label tool.
 type DeleteLabelInput struct {
-	ID string `json:"id" jsonschema:"required"`
+	ID string `json:"id" jsonschema:"ID of the label to delete"`
 }
 
 // Output for delete_label tool.
@@ -408,7 +459,8 @@ type DeleteLabelOutput struct{}
 
 // Input for get_maintenance_log tool.
 type GetMaintenanceLogInput struct {
-	ItemID string `json:"item_id" jsonschema:"required"`
+	ItemID string `json:"item_id" jsonschema:"ID of the item"`
+	OutputOptions
 }
 
 // Output for get_maintenance_log tool.
@@ -418,26 +470,27 @@ type GetMaintenanceLogOutput struct {
 
 // Input for create_maintenance_entry tool.
 type CreateMaintenanceEntryInput struct {
-	ItemID        string `json:"item_id" jsonschema:"required"`
-	Name          string `json:"name" jsonschema:"required"`
-	CompletedDate string `json:"completedDate,omitempty"`
-	Cost          string `json:"cost,omitempty"`
-	Description   string `json:"description,omitempty"`
-	ScheduledDate string `json:"scheduledDate,omitempty"`
+	ItemID        string `json:"item_id" jsonschema:"ID of the item the maintenance is for"`
+	Name          string `json:"name" jsonschema:"Short name of the maintenance task"`
+	CompletedDate string `json:"completedDate,omitempty" jsonschema:"Date the task was done, as YYYY-MM-DD. Leave empty for scheduled work"`
+	Cost          string `json:"cost,omitempty" jsonschema:"Cost of the task"`
+	Description   string `json:"description,omitempty" jsonschema:"Details of the task"`
+	ScheduledDate string `json:"scheduledDate,omitempty" jsonschema:"Date the task is due, as YYYY-MM-DD"`
 }
 
 // Input for duplicate_item tool.
 type DuplicateItemInput struct {
-	ID               string `json:"id" jsonschema:"required"`
-	CopyAttachments  bool   `json:"copyAttachments,omitempty"`
-	CopyCustomFields bool   `json:"copyCustomFields,omitempty"`
-	CopyMaintenance  bool   `json:"copyMaintenance,omitempty"`
-	CopyPrefix       string `json:"copyPrefix,omitempty"`
+	ID               string `json:"id" jsonschema:"ID of the item to copy"`
+	CopyAttachments  bool   `json:"copyAttachments,omitempty" jsonschema:"Copy the attachments too"`
+	CopyCustomFields bool   `json:"copyCustomFields,omitempty" jsonschema:"Copy the custom fields too"`
+	CopyMaintenance  bool   `json:"copyMaintenance,omitempty" jsonschema:"Copy the maintenance log too"`
+	CopyPrefix       string `json:"copyPrefix,omitempty" jsonschema:"Prefix for the name of the copy"`
 }
 
 // Input for get_item_path tool.
 type GetItemPathInput struct {
-	ID string `json:"id" jsonschema:"required"`
+	ID string `json:"id" jsonschema:"ID of the item"`
+	OutputOptions
 }
 
 // Output for get_item_path tool.
@@ -450,7 +503,7 @@ type ExportItemsInput struct{}
 
 // Output for export_items tool.
 type ExportItemsOutput struct {
-	CSVData string `json:"csv_data"`
+	CSVData string `json:"csv_data" jsonschema:"The exported items in the Homebox CSV format"`
 }
 
 // Input for get_item_fields tool.
@@ -471,7 +524,8 @@ type GetItemFieldValuesOutput struct {
 
 // Input for get_item_by_asset_id tool.
 type GetItemByAssetIDInput struct {
-	ID string `json:"id" jsonschema:"required"`
+	ID string `json:"id" jsonschema:"Asset ID of the item, such as 000-042"`
+	OutputOptions
 }
 
 // Action Inputs
@@ -485,72 +539,72 @@ type GetCurrencyInput struct{}
 
 // Maintenance Inputs
 type UpdateMaintenanceEntryInput struct {
-	ID            string `json:"id" jsonschema:"required"`
-	Name          string `json:"name,omitempty"`
-	Description   string `json:"description,omitempty"`
-	CompletedDate string `json:"completedDate,omitempty"`
-	Cost          string `json:"cost,omitempty"`
-	ScheduledDate string `json:"scheduledDate,omitempty"`
+	ID            string `json:"id" jsonschema:"ID of the maintenance entry"`
+	Name          string `json:"name,omitempty" jsonschema:"Short name of the maintenance task"`
+	Description   string `json:"description,omitempty" jsonschema:"Details of the task"`
+	CompletedDate string `json:"completedDate,omitempty" jsonschema:"Date the task was done, as YYYY-MM-DD"`
+	Cost          string `json:"cost,omitempty" jsonschema:"Cost of the task"`
+	ScheduledDate string `json:"scheduledDate,omitempty" jsonschema:"Date the task is due, as YYYY-MM-DD"`
 }
 
 type DeleteMaintenanceEntryInput struct {
-	ID string `json:"id" jsonschema:"required"`
+	ID string `json:"id" jsonschema:"ID of the maintenance entry to delete"`
 }
 
 type DeleteMaintenanceEntryOutput struct{}
 
 // Item Attachment Inputs
 type DeleteItemAttachmentInput struct {
-	ItemID       string `json:"item_id" jsonschema:"required"`
-	AttachmentID string `json:"attachment_id" jsonschema:"required"`
+	ItemID       string `json:"item_id" jsonschema:"ID of the item"`
+	AttachmentID string `json:"attachment_id" jsonschema:"ID of the attachment"`
 }
 
 type DeleteItemAttachmentOutput struct{}
 
 type UpdateItemAttachmentInput struct {
-	ItemID       string `json:"item_id" jsonschema:"required"`
-	AttachmentID string `json:"attachment_id" jsonschema:"required"`
-	Primary      bool   `json:"primary,omitempty"`
-	Title        string `json:"title,omitempty"`
-	Type         string `json:"type,omitempty"`
+	ItemID       string `json:"item_id" jsonschema:"ID of the item"`
+	AttachmentID string `json:"attachment_id" jsonschema:"ID of the attachment"`
+	Primary      bool   `json:"primary,omitempty" jsonschema:"Make this the primary photo of the item"`
+	Title        string `json:"title,omitempty" jsonschema:"Title of the attachment"`
+	Type         string `json:"type,omitempty" jsonschema:"Kind of attachment"`
 }
 
 type GetItemAttachmentInput struct {
-	ItemID       string `json:"item_id" jsonschema:"required"`
-	AttachmentID string `json:"attachment_id" jsonschema:"required"`
+	ItemID       string `json:"item_id" jsonschema:"ID of the item"`
+	AttachmentID string `json:"attachment_id" jsonschema:"ID of the attachment"`
 }
 
 type GetItemAttachmentOutput struct {
-	FileContent string `json:"file_content"`
+	FileContent string `json:"file_content" jsonschema:"Base64 encoded file content"`
 }
 
 type CreateItemAttachmentInput struct {
-	ItemID      string `json:"item_id" jsonschema:"required"`
-	FileContent string `json:"file_content" jsonschema:"required,description:Base64 encoded file content"`
-	FileName    string `json:"file_name" jsonschema:"required"`
-	Type        string `json:"type,omitempty"`
-	Primary     bool   `json:"primary,omitempty"`
+	ItemID      string `json:"item_id" jsonschema:"ID of the item"`
+	FileContent string `json:"file_content" jsonschema:"Base64 encoded file content"`
+	FileName    string `json:"file_name" jsonschema:"Name of the file, including its extension"`
+	Type        string `json:"type,omitempty" jsonschema:"Kind of attachment"`
+	Primary     bool   `json:"primary,omitempty" jsonschema:"Make this the primary photo of the item"`
 }
 
 // Input for the import_items tool.
 type ImportItemsInput struct {
-	FileContent string `json:"file_content" jsonschema:"required,description:Base64 encoded file content"`
-	FileName    string `json:"file_name" jsonschema:"required"`
+	FileContent string `json:"file_content" jsonschema:"Base64 encoded CSV file in the Homebox import format"`
+	FileName    string `json:"file_name" jsonschema:"Name of the file"`
 }
 
 // Output for the import_items tool.
 type ImportItemsOutput struct {
-	Completed int `json:"completed"`
+	Completed int `json:"completed" jsonschema:"Number of items imported"`
 }
 
 // Group Inputs
 type GetGroupInput struct{}
 type UpdateGroupInput struct {
-	Name     string `json:"name,omitempty"`
-	Currency string `json:"currency,omitempty"`
+	Name     string `json:"name,omitempty" jsonschema:"Name of the group"`
+	Currency string `json:"currency,omitempty" jsonschema:"Currency code, such as USD"`
 }
 type CreateGroupInvitationInput struct {
-    Email string `json:"email" jsonschema:"required,format:email"`
+    Email string `json:"email" jsonschema:"Email address to invite"`
 }
 type GroupInvitation struct {
     ID      string `json:"id"`
@@ -561,38 +615,38 @@ type GetGroupStatisticsInput struct{}
 type GetLabelStatisticsInput struct{}
 type GetLocationStatisticsInput struct{}
 type GetPurchasePriceStatisticsInput struct {
-	Start string `json:"start,omitempty"`
-	End   string `json:"end,omitempty"`
+	Start string `json:"start,omitempty" jsonschema:"Start of the period, as YYYY-MM-DD"`
+	End   string `json:"end,omitempty" jsonschema:"End of the period, as YYYY-MM-DD"`
 }
 
 // Notifier Inputs
 type GetNotifiersInput struct{}
 type CreateNotifierInput struct {
-	Name     string `json:"name" jsonschema:"required"`
-	URL      string `json:"url" jsonschema:"required"`
-	IsActive bool   `json:"isActive,omitempty"`
+	Name     string `json:"name" jsonschema:"Name of the notifier"`
+	URL      string `json:"url" jsonschema:"Shoutrrr URL to send notifications to"`
+	IsActive bool   `json:"isActive,omitempty" jsonschema:"Whether the notifier is active"`
 }
 type UpdateNotifierInput struct {
-	ID       string `json:"id" jsonschema:"required"`
-	Name     string `json:"name,omitempty"`
-	URL      string `json:"url,omitempty"`
-	IsActive bool   `json:"isActive,omitempty"`
+	ID       string `json:"id" jsonschema:"ID of the notifier"`
+	Name     string `json:"name,omitempty" jsonschema:"Name of the notifier"`
+	URL      string `json:"url,omitempty" jsonschema:"Shoutrrr URL to send notifications to"`
+	IsActive bool   `json:"isActive,omitempty" jsonschema:"Whether the notifier is active"`
 }
 type DeleteNotifierInput struct {
-	ID string `json:"id" jsonschema:"required"`
+	ID string `json:"id" jsonschema:"ID of the notifier to delete"`
 }
 type TestNotifierInput struct {
-	URL string `json:"url" jsonschema:"required"`
+	URL string `json:"url" jsonschema:"Shoutrrr URL to send a test notification to"`
 }
 
 // Product Inputs
 type SearchFromBarcodeInput struct {
-	Data string `json:"data" jsonschema:"required"`
+	Data string `json:"data" jsonschema:"Barcode to look up"`
 }
 
 // QR Code Inputs
 type CreateQRCodeInput struct {
-	Data string `json:"data" jsonschema:"required"`
+	Data string `json:"data" jsonschema:"Text to encode"`
 }
 
 // Reporting Inputs
@@ -600,20 +654,22 @@ type ExportBillOfMaterialsInput struct{}
 
 // Label Maker Inputs
 type GetAssetLabelInput struct {
-	ID string `json:"id" jsonschema:"required"`
+	ID string `json:"id" jsonschema:"Asset ID of the item, such as 000-042"`
 }
 
 type GetItemLabelInput struct {
-	ID string `json:"id" jsonschema:"required"`
+	ID string `json:"id" jsonschema:"ID of the item"`
 }
 
 type GetLocationLabelInput struct {
-	ID string `json:"id" jsonschema:"required"`
+	ID string `json:"id" jsonschema:"ID of the location"`
 }
 
-// Label Maker Output
+// Label Maker Output. The image itself is returned as image content.
 type GetLabelOutput struct {
-	Image string `json:"image" jsonschema:"required,description:Base64 encoded image data"`
+	MimeType string `json:"mimeType" jsonschema:"MIME type of the image"`
+	Size     int    `json:"size" jsonschema:"Size of the image in bytes"`
+	File     string `json:"file,omitempty" jsonschema:"Path the image was written to, when an output directory is configured"`
 }
 
 
@@ -628,14 +684,14 @@ func getItems(ctx context.Context, req *mcp.CallToolRequest, input GetItemsInput
 	}
 
 	// Create a new HTTP request to the Homebox API.
-	httpReq, err := http.NewRequest("GET", fmt.Sprintf("%s/api/v1/items", homeboxURL), nil)
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/api/v1/items", homeboxURL), nil)
 	if err != nil {
 		return nil, GetItemsOutput{}, err
 	}
 	httpReq.Header.Set("Authorization", "Bearer "+homeboxToken)
 
 	// Execute the request.
-	client := &http.Client{}
+	client := homeboxClient
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, GetItemsOutput{}, err
@@ -658,6 +714,46 @@ func getItems(ctx context.Context, req *mcp.CallToolRequest, input GetItemsInput
 	return nil, GetItemsOutput{Items: items}, nil
 }
 
+// queryItems retrieves one page of items matching query, which may hold the
+// Homebox search parameters q, labels, locations, parentIds, page and pageSize.
+func queryItems(ctx context.Context, query url.Values) (PaginationResult_ItemSummary, error) {
+	homeboxURL := os.Getenv("HOMEBOX_URL")
+	homeboxToken := os.Getenv("HOMEBOX_TOKEN")
+
+	if homeboxURL == "" || homeboxToken == "" {
+		return PaginationResult_ItemSummary{}, fmt.Errorf("HOMEBOX_URL and HOMEBOX_TOKEN environment variables must be set")
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/api/v1/items?%s", homeboxURL, query.Encode()), nil)
+	if err != nil {
+		return PaginationResult_ItemSummary{}, err
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+homeboxToken)
+
+	client := homeboxClient
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return PaginationResult_ItemSummary{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return PaginationResult_ItemSummary{}, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return PaginationResult_ItemSummary{}, fmt.Errorf("failed to query items, status code: %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var result PaginationResult_ItemSummary
+	if err := json.Unmarshal(body, &result); err != nil {
+		return PaginationResult_ItemSummary{}, err
+	}
+
+	return result, nil
+}
+
 // createItem is the implementation of the "create_item" tool.
 func createItem(ctx context.Context, req *mcp.CallToolRequest, input CreateItemInput) (*mcp.CallToolResult, ItemSummary, error) {
 	// Get the Homebox API URL and token from environment variables.
@@ -668,6 +764,12 @@ func createItem(ctx context.Context, req *mcp.CallToolRequest, input CreateItemI
 		return nil, ItemSummary{}, fmt.Errorf("HOMEBOX_URL and HOMEBOX_TOKEN environment variables must be set")
 	}
 
+	// Accept location paths, label names and asset IDs in place of IDs.
+	if err := resolveItemReferences(ctx, &input.LocationID, input.LabelIDs, &input.ParentID, input.CreateMissing); err != nil {
+		return nil, ItemSummary{}, err
+	}
+	input.CreateMissing = false
+
 	// Marshal the input to JSON
 	reqBody, err := json.Marshal(input)
 	if err != nil {
@@ -675,7 +777,7 @@ func createItem(ctx context.Context, req *mcp.CallToolRequest, input CreateItemI
 	}
 
 	// Create a new HTTP request to the Homebox API.
-	httpReq, err := http.NewRequest("POST", fmt.Sprintf("%s/api/v1/items", homeboxURL), bytes.NewBuffer(reqBody))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/api/v1/items", homeboxURL), bytes.NewBuffer(reqBody))
 	if err != nil {
 		return nil, ItemSummary{}, err
 	}
@@ -683,7 +785,7 @@ func createItem(ctx context.Context, req *mcp.CallToolRequest, input CreateItemI
 	httpReq.Header.Set("Content-Type", "application/json")
 
 	// Execute the request.
-	client := &http.Client{}
+	client := homeboxClient
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, ItemSummary{}, err
@@ -721,14 +823,14 @@ func getItem(ctx context.Context, req *mcp.CallToolRequest, input GetItemInput)
 	}
 
 	// Create a new HTTP request to the Homebox API.
-	httpReq, err := http.NewRequest("GET", fmt.Sprintf("%s/api/v1/items/%s", homeboxURL, input.ID), nil)
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/api/v1/items/%s", homeboxURL, input.ID), nil)
 	if err != nil {
 		return nil, ItemOut{}, err
 	}
 	httpReq.Header.Set("Authorization", "Bearer "+homeboxToken)
 
 	// Execute the request.
-	client := &http.Client{}
+	client := homeboxClient
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, ItemOut{}, err
@@ -765,6 +867,17 @@ func updateItem(ctx context.Context, req *mcp.CallToolRequest, input UpdateItemI
 		return nil, ItemOut{}, fmt.Errorf("HOMEBOX_URL and HOMEBOX_TOKEN environment variables must be set")
 	}
 
+	// Accept asset IDs, location paths and label names in place of IDs.
+	id, err := resolveItemRef(ctx, "id", input.ID)
+	if err != nil {
+		return nil, ItemOut{}, err
+	}
+	input.ID = id
+	if err := resolveItemReferences(ctx, &input.LocationID, input.LabelIDs, &input.ParentID, input.CreateMissing); err != nil {
+		return nil, ItemOut{}, err
+	}
+	input.CreateMissing = false
+
 	// Marshal the input to JSON
 	reqBody, err := json.Marshal(input)
 	if err != nil {
@@ -772,7 +885,7 @@ func updateItem(ctx context.Context, req *mcp.CallToolRequest, input UpdateItemI
 	}
 
 	// Create a new HTTP request to the Homebox API.
-	httpReq, err := http.NewRequest("PUT", fmt.Sprintf("%s/api/v1/items/%s", homeboxURL, input.ID), bytes.NewBuffer(reqBody))
+	httpReq, err := http.NewRequestWithContext(ctx, "PUT", fmt.Sprintf("%s/api/v1/items/%s", homeboxURL, input.ID), bytes.NewBuffer(reqBody))
 	if err != nil {
 		return nil, ItemOut{}, err
 	}
@@ -780,7 +893,7 @@ func updateItem(ctx context.Context, req *mcp.CallToolRequest, input UpdateItemI
 	httpReq.Header.Set("Content-Type", "application/json")
 
 	// Execute the request.
-	client := &http.Client{}
+	client := homeboxClient
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, ItemOut{}, err
@@ -818,14 +931,14 @@ func deleteItem(ctx context.Context, req *mcp.CallToolRequest, input DeleteItemI
 	}
 
 	// Create a new HTTP request to the Homebox API.
-	httpReq, err := http.NewRequest("DELETE", fmt.Sprintf("%s/api/v1/items/%s", homeboxURL, input.ID), nil)
+	httpReq, err := http.NewRequestWithContext(ctx, "DELETE", fmt.Sprintf("%s/api/v1/items/%s", homeboxURL, input.ID), nil)
 	if err != nil {
 		return nil, DeleteItemOutput{}, err
 	}
 	httpReq.Header.Set("Authorization", "Bearer "+homeboxToken)
 
 	// Execute the request.
-	client := &http.Client{}
+	client := homeboxClient
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, DeleteItemOutput{}, err
@@ -850,13 +963,13 @@ func getLocations(ctx context.Context, req *mcp.CallToolRequest, input GetLocati
 		return nil, GetLocationsOutput{}, fmt.Errorf("HOMEBOX_URL and HOMEBOX_TOKEN environment variables must be set")
 	}
 
-	httpReq, err := http.NewRequest("GET", fmt.Sprintf("%s/api/v1/locations", homeboxURL), nil)
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/api/v1/locations", homeboxURL), nil)
 	if err != nil {
 		return nil, GetLocationsOutput{}, err
 	}
 	httpReq.Header.Set("Authorization", "Bearer "+homeboxToken)
 
-	client := &http.Client{}
+	client := homeboxClient
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, GetLocationsOutput{}, err
@@ -880,6 +993,45 @@ func getLocations(ctx context.Context, req *mcp.CallToolRequest, input GetLocati
 	return nil, GetLocationsOutput{Locations: locations}, nil
 }
 
+// getLocationTree returns every location, nested under its parent.
+func getLocationTree(ctx context.Context) ([]TreeItem, error) {
+	homeboxURL := os.Getenv("HOMEBOX_URL")
+	homeboxToken := os.Getenv("HOMEBOX_TOKEN")
+
+	if homeboxURL == "" || homeboxToken == "" {
+		return nil, fmt.Errorf("HOMEBOX_URL and HOMEBOX_TOKEN environment variables must be set")
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/api/v1/locations/tree", homeboxURL), nil)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+homeboxToken)
+
+	client := homeboxClient
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get location tree, status code: %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var tree []TreeItem
+	if err := json.Unmarshal(body, &tree); err != nil {
+		return nil, err
+	}
+
+	return tree, nil
+}
+
 // createLocation is the implementation of the "create_location" tool.
 func createLocation(ctx context.Context, req *mcp.CallToolRequest, input CreateLocationInput) (*mcp.CallToolResult, LocationSummary, error) {
 	homeboxURL := os.Getenv("HOMEBOX_URL")
@@ -889,19 +1041,26 @@ func createLocation(ctx context.Context, req *mcp.CallToolRequest, input CreateL
 		return nil, LocationSummary{}, fmt.Errorf("HOMEBOX_URL and HOMEBOX_TOKEN environment variables must be set")
 	}
 
+	// Accept a location path in place of the parent ID.
+	parentID, err := resolveLocationRef(ctx, "parentId", input.ParentID, input.CreateMissing)
+	if err != nil {
+		return nil, LocationSummary{}, err
+	}
+	input.ParentID, input.CreateMissing = parentID, false
+
 	reqBody, err := json.Marshal(input)
 	if err != nil {
 		return nil, LocationSummary{}, err
 	}
 
-	httpReq, err := http.NewRequest("POST", fmt.Sprintf("%s/api/v1/locations", homeboxURL), bytes.NewBuffer(reqBody))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/api/v1/locations", homeboxURL), bytes.NewBuffer(reqBody))
 	if err != nil {
 		return nil, LocationSummary{}, err
 	}
 	httpReq.Header.Set("Authorization", "Bearer "+homeboxToken)
 	httpReq.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
+	client := homeboxClient
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, LocationSummary{}, err
@@ -934,13 +1093,13 @@ func getLocation(ctx context.Context, req *mcp.CallToolRequest, input GetLocatio
 		return nil, LocationOut{}, fmt.Errorf("HOMEBOX_URL and HOMEBOX_TOKEN environment variables must be set")
 	}
 
-	httpReq, err := http.NewRequest("GET", fmt.Sprintf("%s/api/v1/locations/%s", homeboxURL, input.ID), nil)
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/api/v1/locations/%s", homeboxURL, input.ID), nil)
 	if err != nil {
 		return nil, LocationOut{}, err
 	}
 	httpReq.Header.Set("Authorization", "Bearer "+homeboxToken)
 
-	client := &http.Client{}
+	client := homeboxClient
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, LocationOut{}, err
@@ -973,19 +1132,30 @@ func updateLocation(ctx context.Context, req *mcp.CallToolRequest, input UpdateL
 		return nil, LocationOut{}, fmt.Errorf("HOMEBOX_URL and HOMEBOX_TOKEN environment variables must be set")
 	}
 
+	// Accept location paths in place of IDs.
+	id, err := resolveLocationRef(ctx, "id", input.ID, false)
+	if err != nil {
+		return nil, LocationOut{}, err
+	}
+	parentID, err := resolveLocationRef(ctx, "parentId", input.ParentID, input.CreateMissing)
+	if err != nil {
+		return nil, LocationOut{}, err
+	}
+	input.ID, input.ParentID, input.CreateMissing = id, parentID, false
+
 	reqBody, err := json.Marshal(input)
 	if err != nil {
 		return nil, LocationOut{}, err
 	}
 
-	httpReq, err := http.NewRequest("PUT", fmt.Sprintf("%s/api/v1/locations/%s", homeboxURL, input.ID), bytes.NewBuffer(reqBody))
+	httpReq, err := http.NewRequestWithContext(ctx, "PUT", fmt.Sprintf("%s/api/v1/locations/%s", homeboxURL, input.ID), bytes.NewBuffer(reqBody))
 	if err != nil {
 		return nil, LocationOut{}, err
 	}
 	httpReq.Header.Set("Authorization", "Bearer "+homeboxToken)
 	httpReq.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
+	client := homeboxClient
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, LocationOut{}, err
@@ -1018,13 +1188,13 @@ func deleteLocation(ctx context.Context, req *mcp.CallToolRequest, input DeleteL
 		return nil, DeleteLocationOutput{}, fmt.Errorf("HOMEBOX_URL and HOMEBOX_TOKEN environment variables must be set")
 	}
 
-	httpReq, err := http.NewRequest("DELETE", fmt.Sprintf("%s/api/v1/locations/%s", homeboxURL, input.ID), nil)
+	httpReq, err := http.NewRequestWithContext(ctx, "DELETE", fmt.Sprintf("%s/api/v1/locations/%s", homeboxURL, input.ID), nil)
 	if err != nil {
 		return nil, DeleteLocationOutput{}, err
 	}
 	httpReq.Header.Set("Authorization", "Bearer "+homeboxToken)
 
-	client := &http.Client{}
+	client := homeboxClient
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, DeleteLocationOutput{}, err
@@ -1048,13 +1218,13 @@ func getLabels(ctx context.Context, req *mcp.CallToolRequest, input GetLabelsInp
 		return nil, GetLabelsOutput{}, fmt.Errorf("HOMEBOX_URL and HOMEBOX_TOKEN environment variables must be set")
 	}
 
-	httpReq, err := http.NewRequest("GET", fmt.Sprintf("%s/api/v1/labels", homeboxURL), nil)
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/api/v1/labels", homeboxURL), nil)
 	if err != nil {
 		return nil, GetLabelsOutput{}, err
 	}
 	httpReq.Header.Set("Authorization", "Bearer "+homeboxToken)
 
-	client := &http.Client{}
+	client := homeboxClient
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, GetLabelsOutput{}, err
@@ -1092,14 +1262,14 @@ func createLabel(ctx context.Context, req *mcp.CallToolRequest, input CreateLabe
 		return nil, LabelSummary{}, err
 	}
 
-	httpReq, err := http.NewRequest("POST", fmt.Sprintf("%s/api/v1/labels", homeboxURL), bytes.NewBuffer(reqBody))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/api/v1/labels", homeboxURL), bytes.NewBuffer(reqBody))
 	if err != nil {
 		return nil, LabelSummary{}, err
 	}
 	httpReq.Header.Set("Authorization", "Bearer "+homeboxToken)
 	httpReq.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
+	client := homeboxClient
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, LabelSummary{}, err
@@ -1132,13 +1302,13 @@ func getLabel(ctx context.Context, req *mcp.CallToolRequest, input GetLabelInput
 		return nil, LabelOut{}, fmt.Errorf("HOMEBOX_URL and HOMEBOX_TOKEN environment variables must be set")
 	}
 
-	httpReq, err := http.NewRequest("GET", fmt.Sprintf("%s/api/v1/labels/%s", homeboxURL, input.ID), nil)
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/api/v1/labels/%s", homeboxURL, input.ID), nil)
 	if err != nil {
 		return nil, LabelOut{}, err
 	}
 	httpReq.Header.Set("Authorization", "Bearer "+homeboxToken)
 
-	client := &http.Client{}
+	client := homeboxClient
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, LabelOut{}, err
@@ -1176,14 +1346,14 @@ func updateLabel(ctx context.Context, req *mcp.CallToolRequest, input UpdateLabe
 		return nil, LabelOut{}, err
 	}
 
-	httpReq, err := http.NewRequest("PUT", fmt.Sprintf("%s/api/v1/labels/%s", homeboxURL, input.ID), bytes.NewBuffer(reqBody))
+	httpReq, err := http.NewRequestWithContext(ctx, "PUT", fmt.Sprintf("%s/api/v1/labels/%s", homeboxURL, input.ID), bytes.NewBuffer(reqBody))
 	if err != nil {
 		return nil, LabelOut{}, err
 	}
 	httpReq.Header.Set("Authorization", "Bearer "+homeboxToken)
 	httpReq.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
+	client := homeboxClient
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, LabelOut{}, err
@@ -1216,13 +1386,13 @@ func deleteLabel(ctx context.Context, req *mcp.CallToolRequest, input DeleteLabe
 		return nil, DeleteLabelOutput{}, fmt.Errorf("HOMEBOX_URL and HOMEBOX_TOKEN environment variables must be set")
 	}
 
-	httpReq, err := http.NewRequest("DELETE", fmt.Sprintf("%s/api/v1/labels/%s", homeboxURL, input.ID), nil)
+	httpReq, err := http.NewRequestWithContext(ctx, "DELETE", fmt.Sprintf("%s/api/v1/labels/%s", homeboxURL, input.ID), nil)
 	if err != nil {
 		return nil, DeleteLabelOutput{}, err
 	}
 	httpReq.Header.Set("Authorization", "Bearer "+homeboxToken)
 
-	client := &http.Client{}
+	client := homeboxClient
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, DeleteLabelOutput{}, err
@@ -1246,13 +1416,13 @@ func getMaintenanceLog(ctx context.Context, req *mcp.CallToolRequest, input GetM
 		return nil, GetMaintenanceLogOutput{}, fmt.Errorf("HOMEBOX_URL and HOMEBOX_TOKEN environment variables must be set")
 	}
 
-	httpReq, err := http.NewRequest("GET", fmt.Sprintf("%s/api/v1/items/%s/maintenance", homeboxURL, input.ItemID), nil)
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/api/v1/items/%s/maintenance", homeboxURL, input.ItemID), nil)
 	if err != nil {
 		return nil, GetMaintenanceLogOutput{}, err
 	}
 	httpReq.Header.Set("Authorization", "Bearer "+homeboxToken)
 
-	client := &http.Client{}
+	client := homeboxClient
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, GetMaintenanceLogOutput{}, err
@@ -1276,6 +1446,46 @@ func getMaintenanceLog(ctx context.Context, req *mcp.CallToolRequest, input GetM
 	return nil, GetMaintenanceLogOutput{Entries: entries}, nil
 }
 
+// getGroupMaintenance is a helper function to get the maintenance entries
+// of every item in the group. status is scheduled, completed or both.
+func getGroupMaintenance(ctx context.Context, status string) ([]MaintenanceEntryWithDetails, error) {
+	homeboxURL := os.Getenv("HOMEBOX_URL")
+	homeboxToken := os.Getenv("HOMEBOX_TOKEN")
+
+	if homeboxURL == "" || homeboxToken == "" {
+		return nil, fmt.Errorf("HOMEBOX_URL and HOMEBOX_TOKEN environment variables must be set")
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/api/v1/maintenance?status=%s", homeboxURL, url.QueryEscape(status)), nil)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+homeboxToken)
+
+	client := homeboxClient
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get maintenance entries, status code: %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var entries []MaintenanceEntryWithDetails
+	if err := json.Unmarshal(body, &entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
 // createMaintenanceEntry is the implementation of the "create_maintenance_entry" tool.
 func createMaintenanceEntry(ctx context.Context, req *mcp.CallToolRequest, input CreateMaintenanceEntryInput) (*mcp.CallToolResult, MaintenanceEntry, error) {
 	homeboxURL := os.Getenv("HOMEBOX_URL")
@@ -1306,14 +1516,14 @@ func createMaintenanceEntry(ctx context.Context, req *mcp.CallToolRequest, input
 		return nil, MaintenanceEntry{}, err
 	}
 
-	httpReq, err := http.NewRequest("POST", fmt.Sprintf("%s/api/v1/items/%s/maintenance", homeboxURL, input.ItemID), bytes.NewBuffer(reqBody))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/api/v1/items/%s/maintenance", homeboxURL, input.ItemID), bytes.NewBuffer(reqBody))
 	if err != nil {
 		return nil, MaintenanceEntry{}, err
 	}
 	httpReq.Header.Set("Authorization", "Bearer "+homeboxToken)
 	httpReq.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
+	client := homeboxClient
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, MaintenanceEntry{}, err
@@ -1337,6 +1547,82 @@ func createMaintenanceEntry(ctx context.Context, req *mcp.CallToolRequest, input
 	return nil, entry, nil
 }
 
+// updateMaintenanceEntry updates a maintenance entry. Homebox replaces the
+// whole entry, so every field must be sent.
+func updateMaintenanceEntry(ctx context.Context, req *mcp.CallToolRequest, input UpdateMaintenanceEntryInput) (*mcp.CallToolResult, MaintenanceEntry, error) {
+	homeboxURL := os.Getenv("HOMEBOX_URL")
+	homeboxToken := os.Getenv("HOMEBOX_TOKEN")
+
+	if homeboxURL == "" || homeboxToken == "" {
+		return nil, MaintenanceEntry{}, fmt.Errorf("HOMEBOX_URL and HOMEBOX_TOKEN environment variables must be set")
+	}
+
+	reqBody, err := json.Marshal(input)
+	if err != nil {
+		return nil, MaintenanceEntry{}, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "PUT", fmt.Sprintf("%s/api/v1/maintenance/%s", homeboxURL, input.ID), bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, MaintenanceEntry{}, err
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+homeboxToken)
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	client := homeboxClient
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, MaintenanceEntry{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, MaintenanceEntry{}, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, MaintenanceEntry{}, fmt.Errorf("failed to update maintenance entry, status code: %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var entry MaintenanceEntry
+	if err := json.Unmarshal(body, &entry); err != nil {
+		return nil, MaintenanceEntry{}, err
+	}
+
+	return nil, entry, nil
+}
+
+// deleteMaintenanceEntry deletes a maintenance entry.
+func deleteMaintenanceEntry(ctx context.Context, req *mcp.CallToolRequest, input DeleteMaintenanceEntryInput) (*mcp.CallToolResult, DeleteMaintenanceEntryOutput, error) {
+	homeboxURL := os.Getenv("HOMEBOX_URL")
+	homeboxToken := os.Getenv("HOMEBOX_TOKEN")
+
+	if homeboxURL == "" || homeboxToken == "" {
+		return nil, DeleteMaintenanceEntryOutput{}, fmt.Errorf("HOMEBOX_URL and HOMEBOX_TOKEN environment variables must be set")
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "DELETE", fmt.Sprintf("%s/api/v1/maintenance/%s", homeboxURL, input.ID), nil)
+	if err != nil {
+		return nil, DeleteMaintenanceEntryOutput{}, err
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+homeboxToken)
+
+	client := homeboxClient
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, DeleteMaintenanceEntryOutput{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, DeleteMaintenanceEntryOutput{}, fmt.Errorf("failed to delete maintenance entry, status code: %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	return nil, DeleteMaintenanceEntryOutput{}, nil
+}
+
 // duplicateItem is the implementation of the "duplicate_item" tool.
 func duplicateItem(ctx context.Context, req *mcp.CallToolRequest, input DuplicateItemInput) (*mcp.CallToolResult, ItemOut, error) {
 	homeboxURL := os.Getenv("HOMEBOX_URL")
@@ -1351,14 +1637,14 @@ func duplicateItem(ctx context.Context, req *mcp.CallToolRequest, input Duplicat
 		return nil, ItemOut{}, err
 	}
 
-	httpReq, err := http.NewRequest("POST", fmt.Sprintf("%s/api/v1/items/%s/duplicate", homeboxURL, input.ID), bytes.NewBuffer(reqBody))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/api/v1/items/%s/duplicate", homeboxURL, input.ID), bytes.NewBuffer(reqBody))
 	if err != nil {
 		return nil, ItemOut{}, err
 	}
 	httpReq.Header.Set("Authorization", "Bearer "+homeboxToken)
 	httpReq.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
+	client := homeboxClient
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, ItemOut{}, err
@@ -1391,13 +1677,13 @@ func getItemPath(ctx context.Context, req *mcp.CallToolRequest, input GetItemPat
 		return nil, GetItemPathOutput{}, fmt.Errorf("HOMEBOX_URL and HOMEBOX_TOKEN environment variables must be set")
 	}
 
-	httpReq, err := http.NewRequest("GET", fmt.Sprintf("%s/api/v1/items/%s/path", homeboxURL, input.ID), nil)
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/api/v1/items/%s/path", homeboxURL, input.ID), nil)
 	if err != nil {
 		return nil, GetItemPathOutput{}, err
 	}
 	httpReq.Header.Set("Authorization", "Bearer "+homeboxToken)
 
-	client := &http.Client{}
+	client := homeboxClient
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, GetItemPathOutput{}, err
@@ -1430,27 +1716,36 @@ func exportItems(ctx context.Context, req *mcp.CallToolRequest, input ExportItem
 		return nil, ExportItemsOutput{}, fmt.Errorf("HOMEBOX_URL and HOMEBOX_TOKEN environment variables must be set")
 	}
 
-	httpReq, err := http.NewRequest("GET", fmt.Sprintf("%s/api/v1/items/export", homeboxURL), nil)
+	progress := newToolProgress(req)
+	progress.Logf(ctx, "info", "Exporting items")
+
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/api/v1/items/export", homeboxURL), nil)
 	if err != nil {
 		return nil, ExportItemsOutput{}, err
 	}
 	httpReq.Header.Set("Authorization", "Bearer "+homeboxToken)
 
-	client := &http.Client{}
+	client := homeboxClient
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, ExportItemsOutput{}, err
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, ExportItemsOutput{}, fmt.Errorf("failed to export items, status code: %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	// Stream the export so the client sees progress on large inventories.
+	body, err := readAllWithProgress(ctx, resp.Body, resp.ContentLength, progress)
 	if err != nil {
 		return nil, ExportItemsOutput{}, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, ExportItemsOutput{}, fmt.Errorf("failed to export items, status code: %d, body: %s", resp.StatusCode, string(body))
-	}
+	rows := countCSVRows(body)
+	progress.Report(ctx, float64(len(body)), float64(len(body)), fmt.Sprintf("Exported %d items", rows))
+	progress.Logf(ctx, "info", "Exported %d items (%d bytes)", rows, len(body))
 
 	return nil, ExportItemsOutput{CSVData: string(body)}, nil
 }
@@ -1469,6 +1764,12 @@ func importItems(ctx context.Context, req *mcp.CallToolRequest, input ImportItem
 		return nil, ImportItemsOutput{}, fmt.Errorf("failed to decode file content: %w", err)
 	}
 
+	// Report the number of rows being imported before the upload starts.
+	rows := countCSVRows(fileContent)
+	progress := newToolProgress(req)
+	progress.Report(ctx, 0, float64(rows), fmt.Sprintf("Importing %d items", rows))
+	progress.Logf(ctx, "info", "Importing %d items from %s", rows, input.FileName)
+
 	// Create a new multipart writer
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
@@ -1502,7 +1803,7 @@ func importItems(ctx context.Context, req *mcp.CallToolRequest, input ImportItem
 	httpReq.Header.Set("Authorization", "Bearer "+homeboxToken)
 
 	// Send the request
-	client := &http.Client{}
+	client := homeboxClient
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, ImportItemsOutput{}, fmt.Errorf("failed to send request: %w", err)
@@ -1521,6 +1822,9 @@ func importItems(ctx context.Context, req *mcp.CallToolRequest, input ImportItem
 		return nil, ImportItemsOutput{}, fmt.Errorf("failed to decode response body: %w", err)
 	}
 
+	progress.Report(ctx, float64(rows), float64(rows), fmt.Sprintf("Imported %d items", result.Completed))
+	progress.Logf(ctx, "info", "Imported %d of %d items", result.Completed, rows)
+
 	// Return the output
 	return nil, ImportItemsOutput{
 		Completed: result.Completed,
@@ -1536,13 +1840,13 @@ func getItemFields(ctx context.Context, req *mcp.CallToolRequest, input GetItemF
 		return nil, GetItemFieldsOutput{}, fmt.Errorf("HOMEBOX_URL and HOMEBOX_TOKEN environment variables must be set")
 	}
 
-	httpReq, err := http.NewRequest("GET", fmt.Sprintf("%s/api/v1/items/fields", homeboxURL), nil)
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/api/v1/items/fields", homeboxURL), nil)
 	if err != nil {
 		return nil, GetItemFieldsOutput{}, err
 	}
 	httpReq.Header.Set("Authorization", "Bearer "+homeboxToken)
 
-	client := &http.Client{}
+	client := homeboxClient
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, GetItemFieldsOutput{}, err
@@ -1575,13 +1879,13 @@ func getItemFieldValues(ctx context.Context, req *mcp.CallToolRequest, input Get
 		return nil, GetItemFieldValuesOutput{}, fmt.Errorf("HOMEBOX_URL and HOMEBOX_TOKEN environment variables must be set")
 	}
 
-	httpReq, err := http.NewRequest("GET", fmt.Sprintf("%s/api/v1/items/fields/values", homeboxURL), nil)
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/api/v1/items/fields/values", homeboxURL), nil)
 	if err != nil {
 		return nil, GetItemFieldValuesOutput{}, err
 	}
 	httpReq.Header.Set("Authorization", "Bearer "+homeboxToken)
 
-	client := &http.Client{}
+	client := homeboxClient
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, GetItemFieldValuesOutput{}, err
@@ -1614,13 +1918,13 @@ func getItemByAssetID(ctx context.Context, req *mcp.CallToolRequest, input GetIt
 		return nil, PaginationResult_ItemSummary{}, fmt.Errorf("HOMEBOX_URL and HOMEBOX_TOKEN environment variables must be set")
 	}
 
-	httpReq, err := http.NewRequest("GET", fmt.Sprintf("%s/api/v1/assets/%s", homeboxURL, input.ID), nil)
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/api/v1/assets/%s", homeboxURL, input.ID), nil)
 	if err != nil {
 		return nil, PaginationResult_ItemSummary{}, err
 	}
 	httpReq.Header.Set("Authorization", "Bearer "+homeboxToken)
 
-	client := &http.Client{}
+	client := homeboxClient
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, PaginationResult_ItemSummary{}, err
@@ -1651,12 +1955,15 @@ func createMissingThumbnails(ctx context.Context, req *mcp.CallToolRequest, inpu
 	if homeboxURL == "" || homeboxToken == "" {
 		return nil, ActionAmountResult{}, fmt.Errorf("HOMEBOX_URL and HOMEBOX_TOKEN must be set")
 	}
-	httpReq, err := http.NewRequest("POST", fmt.Sprintf("%s/api/v1/actions/create-missing-thumbnails", homeboxURL), nil)
+	progress := newToolProgress(req)
+	progress.Report(ctx, 0, 1, "Creating missing thumbnails")
+	progress.Logf(ctx, "info", "Creating missing thumbnails")
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/api/v1/actions/create-missing-thumbnails", homeboxURL), nil)
 	if err != nil {
 		return nil, ActionAmountResult{}, err
 	}
 	httpReq.Header.Set("Authorization", "Bearer "+homeboxToken)
-	client := &http.Client{}
+	client := homeboxClient
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, ActionAmountResult{}, err
@@ -1673,6 +1980,8 @@ func createMissingThumbnails(ctx context.Context, req *mcp.CallToolRequest, inpu
 	if err := json.Unmarshal(body, &result); err != nil {
 		return nil, ActionAmountResult{}, err
 	}
+	progress.Report(ctx, 1, 1, fmt.Sprintf("Created %d thumbnails", result.Completed))
+	progress.Logf(ctx, "info", "Created %d thumbnails", result.Completed)
 	return nil, result, nil
 }
 
@@ -1683,12 +1992,15 @@ func ensureAssetIDs(ctx context.Context, req *mcp.CallToolRequest, input EnsureA
 	if homeboxURL == "" || homeboxToken == "" {
 		return nil, ActionAmountResult{}, fmt.Errorf("HOMEBOX_URL and HOMEBOX_TOKEN must be set")
 	}
-	httpReq, err := http.NewRequest("POST", fmt.Sprintf("%s/api/v1/actions/ensure-asset-ids", homeboxURL), nil)
+	progress := newToolProgress(req)
+	progress.Report(ctx, 0, 1, "Assigning missing asset IDs")
+	progress.Logf(ctx, "info", "Assigning missing asset IDs")
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/api/v1/actions/ensure-asset-ids", homeboxURL), nil)
 	if err != nil {
 		return nil, ActionAmountResult{}, err
 	}
 	httpReq.Header.Set("Authorization", "Bearer "+homeboxToken)
-	client := &http.Client{}
+	client := homeboxClient
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, ActionAmountResult{}, err
@@ -1705,6 +2017,8 @@ func ensureAssetIDs(ctx context.Context, req *mcp.CallToolRequest, input EnsureA
 	if err := json.Unmarshal(body, &result); err != nil {
 		return nil, ActionAmountResult{}, err
 	}
+	progress.Report(ctx, 1, 1, fmt.Sprintf("Assigned %d asset IDs", result.Completed))
+	progress.Logf(ctx, "info", "Assigned %d asset IDs", result.Completed)
 	return nil, result, nil
 }
 
@@ -1715,12 +2029,12 @@ func ensureImportRefs(ctx context.Context, req *mcp.CallToolRequest, input Ensur
 	if homeboxURL == "" || homeboxToken == "" {
 		return nil, ActionAmountResult{}, fmt.Errorf("HOMEBOX_URL and HOMEBOX_TOKEN must be set")
 	}
-	httpReq, err := http.NewRequest("POST", fmt.Sprintf("%s/api/v1/actions/ensure-import-refs", homeboxURL), nil)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/api/v1/actions/ensure-import-refs", homeboxURL), nil)
 	if err != nil {
 		return nil, ActionAmountResult{}, err
 	}
 	httpReq.Header.Set("Authorization", "Bearer "+homeboxToken)
-	client := &http.Client{}
+	client := homeboxClient
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, ActionAmountResult{}, err
@@ -1747,12 +2061,12 @@ func setPrimaryPhotos(ctx context.Context, req *mcp.CallToolRequest, input SetPr
 	if homeboxURL == "" || homeboxToken == "" {
 		return nil, ActionAmountResult{}, fmt.Errorf("HOMEBOX_URL and HOMEBOX_TOKEN must be set")
 	}
-	httpReq, err := http.NewRequest("POST", fmt.Sprintf("%s/api/v1/actions/set-primary-photos", homeboxURL), nil)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/api/v1/actions/set-primary-photos", homeboxURL), nil)
 	if err != nil {
 		return nil, ActionAmountResult{}, err
 	}
 	httpReq.Header.Set("Authorization", "Bearer "+homeboxToken)
-	client := &http.Client{}
+	client := homeboxClient
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, ActionAmountResult{}, err
@@ -1779,12 +2093,12 @@ func zeroItemTimeFields(ctx context.Context, req *mcp.CallToolRequest, input Zer
 	if homeboxURL == "" || homeboxToken == "" {
 		return nil, ActionAmountResult{}, fmt.Errorf("HOMEBOX_URL and HOMEBOX_TOKEN must be set")
 	}
-	httpReq, err := http.NewRequest("POST", fmt.Sprintf("%s/api/v1/actions/zero-item-time-fields", homeboxURL), nil)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/api/v1/actions/zero-item-time-fields", homeboxURL), nil)
 	if err != nil {
 		return nil, ActionAmountResult{}, err
 	}
 	httpReq.Header.Set("Authorization", "Bearer "+homeboxToken)
-	client := &http.Client{}
+	client := homeboxClient
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, ActionAmountResult{}, err
@@ -1811,12 +2125,12 @@ func getStatus(ctx context.Context, req *mcp.CallToolRequest, input GetStatusInp
 	if homeboxURL == "" || homeboxToken == "" {
 		return nil, APISummary{}, fmt.Errorf("HOMEBOX_URL and HOMEBOX_TOKEN must be set")
 	}
-	httpReq, err := http.NewRequest("GET", fmt.Sprintf("%s/api/v1/status", homeboxURL), nil)
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/api/v1/status", homeboxURL), nil)
 	if err != nil {
 		return nil, APISummary{}, err
 	}
 	httpReq.Header.Set("Authorization", "Bearer "+homeboxToken)
-	client := &http.Client{}
+	client := homeboxClient
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, APISummary{}, err
@@ -1843,12 +2157,12 @@ func getCurrency(ctx context.Context, req *mcp.CallToolRequest, input GetCurrenc
 	if homeboxURL == "" || homeboxToken == "" {
 		return nil, Currency{}, fmt.Errorf("HOMEBOX_URL and HOMEBOX_TOKEN must be set")
 	}
-	httpReq, err := http.NewRequest("GET", fmt.Sprintf("%s/api/v1/currency", homeboxURL), nil)
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/api/v1/currency", homeboxURL), nil)
 	if err != nil {
 		return nil, Currency{}, err
 	}
 	httpReq.Header.Set("Authorization", "Bearer "+homeboxToken)
-	client := &http.Client{}
+	client := homeboxClient
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, Currency{}, err
@@ -1884,7 +2198,7 @@ func createGroupInvitation(ctx context.Context, req *mcp.CallToolRequest, input
     }
 
     // Create a new HTTP request to the Homebox API.
-    httpReq, err := http.NewRequest("POST", fmt.Sprintf("%s/api/v1/groups/invitations", homeboxURL), bytes.NewBuffer(reqBody))
+    httpReq, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/api/v1/groups/invitations", homeboxURL), bytes.NewBuffer(reqBody))
     if err != nil {
         return nil, GroupInvitation{}, err
     }
@@ -1892,7 +2206,7 @@ func createGroupInvitation(ctx context.Context, req *mcp.CallToolRequest, input
     httpReq.Header.Set("Content-Type", "application/json")
 
     // Execute the request.
-    client := &http.Client{}
+    client := homeboxClient
     resp, err := client.Do(httpReq)
     if err != nil {
         return nil, GroupInvitation{}, err
@@ -1919,8 +2233,128 @@ func createGroupInvitation(ctx context.Context, req *mcp.CallToolRequest, input
     return nil, invitation, nil
 }
 
+// getItemAttachmentFile is a helper function to download an item attachment
+// from the Homebox API. It returns the file content and its MIME type.
+func getItemAttachmentFile(ctx context.Context, itemID, attachmentID string) ([]byte, string, error) {
+	homeboxURL := os.Getenv("HOMEBOX_URL")
+	homeboxToken := os.Getenv("HOMEBOX_TOKEN")
+
+	if homeboxURL == "" || homeboxToken == "" {
+		return nil, "", fmt.Errorf("HOMEBOX_URL and HOMEBOX_TOKEN environment variables must be set")
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/api/v1/items/%s/attachments/%s", homeboxURL, itemID, attachmentID), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+homeboxToken)
+
+	client := homeboxClient
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, "", fmt.Errorf("failed to get item attachment, status code: %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return body, resp.Header.Get("Content-Type"), nil
+}
+
+// uploadItemAttachment is a helper function to upload a file to an item as
+// an attachment of the given type. It returns the item with the new
+// attachment.
+func uploadItemAttachment(ctx context.Context, itemID, fileName string, data []byte, attachmentType string, primary bool) (ItemOut, error) {
+	homeboxURL := os.Getenv("HOMEBOX_URL")
+	homeboxToken := os.Getenv("HOMEBOX_TOKEN")
+
+	if homeboxURL == "" || homeboxToken == "" {
+		return ItemOut{}, fmt.Errorf("HOMEBOX_URL and HOMEBOX_TOKEN environment variables must be set")
+	}
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", fileName)
+	if err != nil {
+		return ItemOut{}, fmt.Errorf("failed to create form file: %w", err)
+	}
+	if _, err := part.Write(data); err != nil {
+		return ItemOut{}, fmt.Errorf("failed to write file content to form file: %w", err)
+	}
+	writer.WriteField("name", fileName)
+	writer.WriteField("type", attachmentType)
+	writer.WriteField("primary", strconv.FormatBool(primary))
+	if err := writer.Close(); err != nil {
+		return ItemOut{}, fmt.Errorf("failed to close multipart writer: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/api/v1/items/%s/attachments", homeboxURL, itemID), body)
+	if err != nil {
+		return ItemOut{}, err
+	}
+	httpReq.Header.Set("Content-Type", writer.FormDataContentType())
+	httpReq.Header.Set("Authorization", "Bearer "+homeboxToken)
+
+	client := homeboxClient
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return ItemOut{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		respBody, _ := io.ReadAll(resp.Body)
+		return ItemOut{}, fmt.Errorf("failed to upload item attachment, status code: %d, body: %s", resp.StatusCode, string(respBody))
+	}
+	var item ItemOut
+	if err := json.NewDecoder(resp.Body).Decode(&item); err != nil {
+		return ItemOut{}, err
+	}
+	return item, nil
+}
+
+// deleteItemAttachment is a helper function to delete an attachment of an
+// item.
+func deleteItemAttachment(ctx context.Context, itemID, attachmentID string) error {
+	homeboxURL := os.Getenv("HOMEBOX_URL")
+	homeboxToken := os.Getenv("HOMEBOX_TOKEN")
+
+	if homeboxURL == "" || homeboxToken == "" {
+		return fmt.Errorf("HOMEBOX_URL and HOMEBOX_TOKEN environment variables must be set")
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "DELETE", fmt.Sprintf("%s/api/v1/items/%s/attachments/%s", homeboxURL, itemID, attachmentID), nil)
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+homeboxToken)
+
+	client := homeboxClient
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to delete item attachment, status code: %d, body: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
 // getLabelImage is a helper function to get a label image from the Homebox API.
-func getLabelImage(ctx context.Context, endpoint string) (*mcp.CallToolResult, GetLabelOutput, error) {
+// The image is returned as image content; name is used for the file written
+// to HOMEBOX_LABEL_DIR.
+func getLabelImage(ctx context.Context, endpoint string, name string) (*mcp.CallToolResult, GetLabelOutput, error) {
 	homeboxURL := os.Getenv("HOMEBOX_URL")
 	homeboxToken := os.Getenv("HOMEBOX_TOKEN")
 
@@ -1928,13 +2362,13 @@ func getLabelImage(ctx context.Context, endpoint string) (*mcp.CallToolResult, G
 		return nil, GetLabelOutput{}, fmt.Errorf("HOMEBOX_URL and HOMEBOX_TOKEN environment variables must be set")
 	}
 
-	httpReq, err := http.NewRequest("GET", fmt.Sprintf("%s/api/v1/%s", homeboxURL, endpoint), nil)
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/api/v1/%s", homeboxURL, endpoint), nil)
 	if err != nil {
 		return nil, GetLabelOutput{}, err
 	}
 	httpReq.Header.Set("Authorization", "Bearer "+homeboxToken)
 
-	client := &http.Client{}
+	client := homeboxClient
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, GetLabelOutput{}, err
@@ -1951,188 +2385,413 @@ func getLabelImage(ctx context.Context, endpoint string) (*mcp.CallToolResult, G
 		return nil, GetLabelOutput{}, err
 	}
 
-	encodedString := base64.StdEncoding.EncodeToString(body)
-	return nil, GetLabelOutput{Image: encodedString}, nil
+	return labelImageResult(name, body, resp.Header.Get("Content-Type"))
 }
 
 // getAssetLabel is the implementation of the "get_asset_label" tool.
 func getAssetLabel(ctx context.Context, req *mcp.CallToolRequest, input GetAssetLabelInput) (*mcp.CallToolResult, GetLabelOutput, error) {
-	return getLabelImage(ctx, fmt.Sprintf("labelmaker/assets/%s", input.ID))
+	return getLabelImage(ctx, fmt.Sprintf("labelmaker/assets/%s", input.ID), "asset-"+input.ID)
 }
 
 // getItemLabel is the implementation of the "get_item_label" tool.
 func getItemLabel(ctx context.Context, req *mcp.CallToolRequest, input GetItemLabelInput) (*mcp.CallToolResult, GetLabelOutput, error) {
-	return getLabelImage(ctx, fmt.Sprintf("labelmaker/item/%s", input.ID))
+	return getLabelImage(ctx, fmt.Sprintf("labelmaker/item/%s", input.ID), "item-"+input.ID)
 }
 
 // getLocationLabel is the implementation of the "get_location_label" tool.
 func getLocationLabel(ctx context.Context, req *mcp.CallToolRequest, input GetLocationLabelInput) (*mcp.CallToolResult, GetLabelOutput, error) {
-	return getLabelImage(ctx, fmt.Sprintf("labelmaker/location/%s", input.ID))
+	return getLabelImage(ctx, fmt.Sprintf("labelmaker/location/%s", input.ID), "location-"+input.ID)
+}
+
+// createQRCode is the implementation of the "create_qr_code" tool.
+func createQRCode(ctx context.Context, req *mcp.CallToolRequest, input CreateQRCodeInput) (*mcp.CallToolResult, GetLabelOutput, error) {
+	return getLabelImage(ctx, "qrcode?data="+url.QueryEscape(input.Data), "qrcode")
 }
 
 func main() {
+	// Subscribed resources are polled for changes, since Homebox cannot push them.
+	pollInterval, err := pollIntervalFromEnv()
+	if err != nil {
+		log.Fatalf("Subscription error: %v", err)
+	}
+	poller := newResourcePoller(pollInterval)
+
+	// Maintenance schedules are checked for completed entries in the background.
+	scheduleInterval, err := scheduleIntervalFromEnv()
+	if err != nil {
+		log.Fatalf("Maintenance schedule error: %v", err)
+	}
+
 	// Create a new MCP server.
-	server := mcp.NewServer(&mcp.Implementation{Name: "homebox-mcp-server", Version: "v0.0.1"}, nil)
+	server := mcp.NewServer(&mcp.Implementation{Name: "homebox-mcp-server", Version: "v0.0.1"}, &mcp.ServerOptions{
+		CompletionHandler:  completeArgument,
+		SubscribeHandler:   poller.subscribe,
+		UnsubscribeHandler: poller.unsubscribe,
+	})
+	poller.server = server
+
+	// Project, summarise and cap the size of tool results.
+	maxResponseBytes, err := maxResponseBytesFromEnv()
+	if err != nil {
+		log.Fatalf("Output error: %v", err)
+	}
+	shaper := newOutputShaper(maxResponseBytes)
+	server.AddReceivingMiddleware(shaper.middleware())
+
+	// If HOMEBOX_MCP_HTTP_ADDR is set, serve MCP over HTTP instead of stdio.
+	httpAddr := os.Getenv("HOMEBOX_MCP_HTTP_ADDR")
+
+	// Audit every tool call if an audit log is configured.
+	auditLog, err := newAuditLoggerFromEnv(httpAddr == "")
+	if err != nil {
+		log.Fatalf("Audit log error: %v", err)
+	}
+	if auditLog != nil {
+		server.AddReceivingMiddleware(auditLog.middleware())
+	}
+	server.AddReceivingMiddleware(metricsMiddleware())
+
+	// Trace tool calls and Homebox requests if an exporter is configured.
+	shutdownTracing, err := setupTracing(context.Background(), httpAddr == "")
+	if err != nil {
+		log.Fatalf("Tracing error: %v", err)
+	}
+	server.AddReceivingMiddleware(tracingMiddleware())
 
 	// Item tools
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(server, &mcp.Tool{
 		Name:        "get_items",
 		Description: "Retrieves all items from the Homebox inventory.",
+		Annotations: readOnlyTool,
 	}, getItems)
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(server, &mcp.Tool{
 		Name:        "create_item",
-		Description: "Creates a new item in the Homebox inventory.",
+		Description: "Creates a new item in the Homebox inventory. The location, labels and parent can be given by name or path instead of ID.",
+		Annotations: createTool,
 	}, createItem)
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(server, &mcp.Tool{
 		Name:        "get_item",
-		Description: "Retrieves a single item from the Homebox inventory by its ID.",
+		Description: "Retrieves a single item from the Homebox inventory by its ID, with its attachments, custom fields and warranty details.",
+		Annotations: readOnlyTool,
 	}, getItem)
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(server, &mcp.Tool{
 		Name:        "update_item",
-		Description: "Updates an existing item in the Homebox inventory.",
+		Description: "Updates an existing item in the Homebox inventory. The whole item is replaced, so fields that are not sent are cleared.",
+		Annotations: updateTool,
 	}, updateItem)
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(server, &mcp.Tool{
 		Name:        "delete_item",
-		Description: "Deletes an item from the Homebox inventory.",
+		Description: "Permanently deletes an item from the Homebox inventory.",
+		Annotations: deleteTool,
 	}, deleteItem)
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(server, &mcp.Tool{
 		Name:        "duplicate_item",
-		Description: "Duplicates an existing item.",
+		Description: "Duplicates an existing item, optionally with its attachments, custom fields and maintenance log.",
+		Annotations: createTool,
 	}, duplicateItem)
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(server, &mcp.Tool{
 		Name:        "get_item_path",
-		Description: "Retrieves the path of an item.",
+		Description: "Retrieves the path of an item: the locations and parent items it is stored in.",
+		Annotations: readOnlyTool,
 	}, getItemPath)
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(server, &mcp.Tool{
 		Name:        "export_items",
 		Description: "Exports all items as a CSV string.",
+		Annotations: readOnlyTool,
 	}, exportItems)
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(server, &mcp.Tool{
 		Name:        "import_items",
-		Description: "Imports items from a CSV file.",
+		Description: "Imports items from a CSV file. Rows with the import ref of an existing item update that item.",
+		Annotations: cumulativeBulkTool,
 	}, importItems)
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(server, &mcp.Tool{
+		Name:        "create_items_bulk",
+		Description: "Creates many items in one call, with their labels, location, custom fields and photo, and reports the outcome of each row. With rollbackOnError, a failed row deletes every item created by the call.",
+		Annotations: createTool,
+	}, createItemsBulk)
+	addTool(server, &mcp.Tool{
+		Name:        "update_items_bulk",
+		Description: "Updates every item selected by IDs or a search (text, labels, location, parent item): moves them, adds or removes labels, sets insured or archived, or sets custom fields. Each item is read and written back, so other fields are kept. Use dryRun to preview; more than maxAffected (default 50) selected items fails without changes.",
+		Annotations: bulkActionTool,
+	}, updateItemsBulk)
+	addTool(server, &mcp.Tool{
+		Name:        "find_duplicate_items",
+		Description: "Finds items that are likely entered more than once, by comparing normalized names, manufacturer and model number, serial numbers and optionally primary photos. Returns clusters of likely duplicates with their locations, most likely first.",
+		Annotations: readOnlyTool,
+	}, findDuplicateItems)
+	addTool(server, &mcp.Tool{
+		Name:        "merge_items",
+		Description: "Merges duplicate items into one: keeps the target, adds up the quantities, joins the labels, moves the attachments and maintenance entries of the sources to it, and deletes the sources. Use dryRun to preview.",
+		Annotations: cumulativeBulkTool,
+	}, mergeItems)
+	addTool(server, &mcp.Tool{
 		Name:        "get_item_fields",
 		Description: "Gets all custom field names.",
+		Annotations: readOnlyTool,
 	}, getItemFields)
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(server, &mcp.Tool{
 		Name:        "get_item_field_values",
 		Description: "Gets all custom field values.",
+		Annotations: readOnlyTool,
 	}, getItemFieldValues)
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(server, &mcp.Tool{
 		Name:        "get_item_by_asset_id",
-		Description: "Retrieves an item by its asset ID.",
+		Description: "Retrieves an item by its asset ID, such as 000-042.",
+		Annotations: readOnlyTool,
 	}, getItemByAssetID)
 
+	addTool(server, &mcp.Tool{
+		Name:        "get_more_results",
+		Description: "Returns the next part of a result that was truncated to fit the response size limit.",
+		Annotations: readOnlyTool,
+	}, shaper.getMoreResults)
+
 	// Location tools
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(server, &mcp.Tool{
 		Name:        "get_locations",
-		Description: "Retrieves all locations from the Homebox inventory.",
+		Description: "Retrieves all locations from the Homebox inventory, with the number of items in each.",
+		Annotations: readOnlyTool,
 	}, getLocations)
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(server, &mcp.Tool{
 		Name:        "create_location",
-		Description: "Creates a new location in the Homebox inventory.",
+		Description: "Creates a new location in the Homebox inventory. The parent can be given by name or path.",
+		Annotations: createTool,
 	}, createLocation)
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(server, &mcp.Tool{
 		Name:        "get_location",
-		Description: "Retrieves a single location from the Homebox inventory by its ID.",
+		Description: "Retrieves a single location from the Homebox inventory by its ID, with its parent and children.",
+		Annotations: readOnlyTool,
 	}, getLocation)
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(server, &mcp.Tool{
 		Name:        "update_location",
 		Description: "Updates an existing location in the Homebox inventory.",
+		Annotations: updateTool,
 	}, updateLocation)
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(server, &mcp.Tool{
 		Name:        "delete_location",
-		Description: "Deletes a location from the Homebox inventory.",
+		Description: "Permanently deletes a location from the Homebox inventory.",
+		Annotations: deleteTool,
 	}, deleteLocation)
+	addTool(server, &mcp.Tool{
+		Name:        "move_location",
+		Description: "Moves a location, with the locations and items inside it, under a new parent location or to the top level. Moving a location into itself or one of its own sub-locations is refused.",
+		Annotations: updateTool,
+	}, moveLocation)
+	addTool(server, &mcp.Tool{
+		Name:        "restructure_locations",
+		Description: "Makes the location tree match a YAML or JSON outline by creating, renaming, moving and optionally deleting empty locations. Returns the plan without changing anything unless apply is set; review the plan before applying it.",
+		Annotations: bulkActionTool,
+	}, restructureLocations)
+	addTool(server, &mcp.Tool{
+		Name:        "merge_locations",
+		Description: "Merges duplicate locations into a target: moves the items and child locations of each source into the target, combines the descriptions and deletes the sources. Use dryRun to preview the counts.",
+		Annotations: bulkActionTool,
+	}, mergeLocations)
 
 	// Label tools
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(server, &mcp.Tool{
 		Name:        "get_labels",
 		Description: "Retrieves all labels from the Homebox inventory.",
+		Annotations: readOnlyTool,
 	}, getLabels)
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(server, &mcp.Tool{
 		Name:        "create_label",
 		Description: "Creates a new label in the Homebox inventory.",
+		Annotations: createTool,
 	}, createLabel)
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(server, &mcp.Tool{
 		Name:        "get_label",
 		Description: "Retrieve a single label from the Homebox inventory by its ID.",
+		Annotations: readOnlyTool,
 	}, getLabel)
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(server, &mcp.Tool{
 		Name:        "update_label",
 		Description: "Updates an existing label in the Homebox inventory.",
+		Annotations: updateTool,
 	}, updateLabel)
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(server, &mcp.Tool{
 		Name:        "delete_label",
-		Description: "Deletes a label from the Homebox inventory.",
+		Description: "Permanently deletes a label from the Homebox inventory and removes it from every item.",
+		Annotations: deleteTool,
 	}, deleteLabel)
+	addTool(server, &mcp.Tool{
+		Name:        "merge_labels",
+		Description: "Merges duplicate labels into a target: gives every item with a source label the target label instead, combines the descriptions and deletes the sources. Use dryRun to preview the counts.",
+		Annotations: bulkActionTool,
+	}, mergeLabels)
+	addTool(server, &mcp.Tool{
+		Name:        "suggest_merges",
+		Description: "Finds labels, and locations within the same parent, whose names are likely duplicates, such as Electronics and electronic, and suggests which to merge into which.",
+		Annotations: readOnlyTool,
+	}, suggestMerges)
 
 	// Maintenance tools
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(server, &mcp.Tool{
 		Name:        "get_maintenance_log",
 		Description: "Retrieves the maintenance log for a specific item.",
+		Annotations: readOnlyTool,
 	}, getMaintenanceLog)
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(server, &mcp.Tool{
 		Name:        "create_maintenance_entry",
-		Description: "Creates a new maintenance entry for an item.",
+		Description: "Creates a new maintenance entry for an item. Set completedDate for work that was done, or scheduledDate for work that is due.",
+		Annotations: createTool,
 	}, createMaintenanceEntry)
+	addTool(server, &mcp.Tool{
+		Name:        "list_maintenance",
+		Description: "Lists the maintenance entries of every item in one call, with each item's name, location path and labels, sorted by due date. Filter by status (scheduled, completed, overdue or due within some days), item, location or label. Includes cost totals per item, location and label.",
+		Annotations: readOnlyTool,
+	}, listMaintenance)
+	addTool(server, &mcp.Tool{
+		Name:        "create_maintenance_schedule",
+		Description: "Creates a recurring maintenance schedule for an item, repeating by an RRULE such as FREQ=DAILY;INTERVAL=90, by usage counted in a custom field (every 500 hours), or whichever comes first. The next maintenance entry is scheduled in Homebox, and once it is completed the one after it is scheduled automatically.",
+		Annotations: createTool,
+	}, createMaintenanceSchedule)
+	addTool(server, &mcp.Tool{
+		Name:        "list_maintenance_schedules",
+		Description: "Lists the recurring maintenance schedules, soonest due first.",
+		Annotations: readOnlyTool,
+	}, listMaintenanceSchedules)
+	addTool(server, &mcp.Tool{
+		Name:        "pause_maintenance_schedule",
+		Description: "Pauses a recurring maintenance schedule, or resumes it with resume. A paused schedule schedules no new entries.",
+		Annotations: updateTool,
+	}, pauseMaintenanceSchedule)
+	addTool(server, &mcp.Tool{
+		Name:        "delete_maintenance_schedule",
+		Description: "Deletes a recurring maintenance schedule, and optionally its pending maintenance entry.",
+		Annotations: deleteTool,
+	}, deleteMaintenanceSchedule)
 
 	// Action tools
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(server, &mcp.Tool{
 		Name:        "create_missing_thumbnails",
 		Description: "Creates thumbnails for items that are missing them.",
+		Annotations: bulkActionTool,
 	}, createMissingThumbnails)
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(server, &mcp.Tool{
 		Name:        "ensure_asset_ids",
 		Description: "Ensures all items in the database have an asset ID.",
+		Annotations: bulkActionTool,
 	}, ensureAssetIDs)
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(server, &mcp.Tool{
 		Name:        "ensure_import_refs",
 		Description: "Ensures all items in the database have an import ref.",
+		Annotations: bulkActionTool,
 	}, ensureImportRefs)
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(server, &mcp.Tool{
 		Name:        "set_primary_photos",
 		Description: "Sets the first photo of each item as the primary photo.",
+		Annotations: bulkActionTool,
 	}, setPrimaryPhotos)
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(server, &mcp.Tool{
 		Name:        "zero_item_time_fields",
 		Description: "Resets all item date fields to the beginning of the day.",
+		Annotations: bulkActionTool,
 	}, zeroItemTimeFields)
 
+	// Report tools
+	addTool(server, &mcp.Tool{
+		Name:        "lint_inventory",
+		Description: "Reports data-quality problems in the inventory, such as items without a location, empty locations, unused labels, invalid label colors, suspicious quantities and expiring warranties without a receipt. Labels can require their items to have a photo, receipt, serial number and so on. Each finding has a severity and, where possible, a tool call that fixes it.",
+		Annotations: readOnlyTool,
+	}, lintInventory)
+	addTool(server, &mcp.Tool{
+		Name:        "check_integrity",
+		Description: "Checks every item and location for broken references: locations, parents and labels that do not exist, parent loops, locations missing from the tree and, optionally, attachment files that cannot be read. With repair, orphaned items and locations are moved to an unsorted location, and broken parents and labels are removed.",
+		Annotations: bulkActionTool,
+	}, checkIntegrity)
+	addTool(server, &mcp.Tool{
+		Name:        "warranty_report",
+		Description: "Lists items whose warranty expires within a number of days, and optionally those whose warranty has lapsed, with the purchase date, price and retailer and whether a receipt or warranty document is attached. Can schedule a maintenance entry as a reminder ahead of each expiry.",
+		Annotations: reportTool,
+	}, warrantyReport)
+	addTool(server, &mcp.Tool{
+		Name:        "valuation",
+		Description: "Estimates what each item is worth today from its purchase price and date, using straight-line or declining-balance depreciation with a useful life per label. Totals the value per location (including the locations below it) and label, counts child items towards their parent, and returns a monthly value-over-time series.",
+		Annotations: readOnlyTool,
+	}, valuation)
+	addTool(server, &mcp.Tool{
+		Name:        "generate_insurance_report",
+		Description: "Generates an inventory report for an insurer: the items grouped by location, with their primary photo, manufacturer, model and serial number, purchase date, place and price, receipts and estimated current value. Returns the report as a self-contained HTML document and a PDF, as embedded resources.",
+		Annotations: reportTool,
+	}, generateInsuranceReport)
+	addTool(server, &mcp.Tool{
+		Name:        "export_calendar",
+		Description: "Exports maintenance due dates, recurring maintenance schedules and warranty expiry dates as an iCalendar (.ics) document. Event UIDs are stable, so importing the calendar again updates the events instead of duplicating them.",
+		Annotations: readOnlyTool,
+	}, exportCalendar)
+
 	// Status and Currency tools
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(server, &mcp.Tool{
 		Name:        "get_status",
 		Description: "Gets application status information.",
+		Annotations: readOnlyTool,
 	}, getStatus)
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(server, &mcp.Tool{
 		Name:        "get_currency",
 		Description: "Gets currency information.",
+		Annotations: readOnlyTool,
 	}, getCurrency)
-	
-    // Group tools
-	mcp.AddTool(server, &mcp.Tool{
-	    Name:        "create_group_invitation",
-	    Description: "Creates a new group invitation.",
+
+	// Group tools
+	addTool(server, &mcp.Tool{
+		Name:        "create_group_invitation",
+		Description: "Creates a new group invitation.",
+		Annotations: createTool,
 	}, createGroupInvitation)
 
-// Label Maker tools
-mcp.AddTool(server, &mcp.Tool{
-	Name:        "get_asset_label",
-	Description: "Generates a label for an asset.",
-}, getAssetLabel)
-mcp.AddTool(server, &mcp.Tool{
-	Name:        "get_item_label",
-	Description: "Generates a label for an item.",
-}, getItemLabel)
-mcp.AddTool(server, &mcp.Tool{
-	Name:        "get_location_label",
-	Description: "Generates a label for a location.",
-}, getLocationLabel)
-
-	// Start the server, which will listen for connections on stdin/stdout.
-	log.Println("Starting Homebox MCP server...")
-	if err := server.Run(context.Background(), &mcp.StdioTransport{}); err != nil {
+	// Label Maker tools
+	addTool(server, &mcp.Tool{
+		Name:        "get_asset_label",
+		Description: "Generates a printable label for an asset.",
+		Annotations: readOnlyTool,
+	}, getAssetLabel)
+	addTool(server, &mcp.Tool{
+		Name:        "get_item_label",
+		Description: "Generates a printable label for an item.",
+		Annotations: readOnlyTool,
+	}, getItemLabel)
+	addTool(server, &mcp.Tool{
+		Name:        "get_location_label",
+		Description: "Generates a printable label for a location.",
+		Annotations: readOnlyTool,
+	}, getLocationLabel)
+	addTool(server, &mcp.Tool{
+		Name:        "create_qr_code",
+		Description: "Generates a QR code image that encodes the given text.",
+		Annotations: readOnlyTool,
+	}, createQRCode)
+
+	// Inventory resources
+	registerResources(server)
+	go poller.run(context.Background())
+	go schedules.run(context.Background(), scheduleInterval)
+
+	// Prompts for common inventory workflows
+	registerPrompts(server)
+
+	// Serve the streamable HTTP transport and Prometheus metrics.
+	if httpAddr != "" {
+		registerSessionGauge(server)
+		mux := http.NewServeMux()
+		mux.Handle("/mcp", mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil))
+		mux.Handle("/metrics", promhttp.Handler())
+		if token := os.Getenv("HOMEBOX_CALENDAR_TOKEN"); token != "" {
+			mux.Handle("/calendar.ics", calendarFeedHandler(token))
+		}
+		log.Printf("Starting Homebox MCP server on %s...", httpAddr)
+		err = http.ListenAndServe(httpAddr, mux)
+	} else {
+		// Start the server, which will listen for connections on stdin/stdout.
+		log.Println("Starting Homebox MCP server...")
+		err = server.Run(context.Background(), &mcp.StdioTransport{})
+	}
+
+	// Flush the spans before exiting, which log.Fatalf would skip.
+	if shutdownErr := shutdownTracing(context.Background()); shutdownErr != nil {
+		log.Printf("Tracing error: %v", shutdownErr)
+	}
+	if err != nil {
 		log.Fatalf("MCP server error: %v", err)
 	}
 }
@@ -2,12 +2,13 @@ package main
 
 import (
 	"context"
-	"encoding/base64"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -15,6 +16,7 @@ func TestGetAssetLabel(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		assert.Equal(t, "/api/v1/labelmaker/assets/123", r.URL.Path)
 		assert.Equal(t, "Bearer test-token", r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "image/png")
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("image data"))
 	}))
@@ -23,15 +25,17 @@ func TestGetAssetLabel(t *testing.T) {
 	os.Setenv("HOMEBOX_URL", server.URL)
 	os.Setenv("HOMEBOX_TOKEN", "test-token")
 
-	_, output, err := getAssetLabel(context.Background(), nil, GetAssetLabelInput{ID: "123"})
+	result, output, err := getAssetLabel(context.Background(), nil, GetAssetLabelInput{ID: "123"})
 	assert.NoError(t, err)
-	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("image data")), output.Image)
+	assert.Equal(t, &mcp.ImageContent{Data: []byte("image data"), MIMEType: "image/png"}, result.Content[0])
+	assert.Equal(t, GetLabelOutput{MimeType: "image/png", Size: 10}, output)
 }
 
 func TestGetItemLabel(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		assert.Equal(t, "/api/v1/labelmaker/item/456", r.URL.Path)
 		assert.Equal(t, "Bearer test-token", r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "image/png")
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("image data"))
 	}))
@@ -40,15 +44,17 @@ func TestGetItemLabel(t *testing.T) {
 	os.Setenv("HOMEBOX_URL", server.URL)
 	os.Setenv("HOMEBOX_TOKEN", "test-token")
 
-	_, output, err := getItemLabel(context.Background(), nil, GetItemLabelInput{ID: "456"})
+	result, output, err := getItemLabel(context.Background(), nil, GetItemLabelInput{ID: "456"})
 	assert.NoError(t, err)
-	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("image data")), output.Image)
+	assert.Equal(t, &mcp.ImageContent{Data: []byte("image data"), MIMEType: "image/png"}, result.Content[0])
+	assert.Equal(t, GetLabelOutput{MimeType: "image/png", Size: 10}, output)
 }
 
 func TestGetLocationLabel(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		assert.Equal(t, "/api/v1/labelmaker/location/789", r.URL.Path)
 		assert.Equal(t, "Bearer test-token", r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "image/png")
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("image data"))
 	}))
@@ -57,7 +63,31 @@ func TestGetLocationLabel(t *testing.T) {
 	os.Setenv("HOMEBOX_URL", server.URL)
 	os.Setenv("HOMEBOX_TOKEN", "test-token")
 
-	_, output, err := getLocationLabel(context.Background(), nil, GetLocationLabelInput{ID: "789"})
+	result, output, err := getLocationLabel(context.Background(), nil, GetLocationLabelInput{ID: "789"})
 	assert.NoError(t, err)
-	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("image data")), output.Image)
+	assert.Equal(t, &mcp.ImageContent{Data: []byte("image data"), MIMEType: "image/png"}, result.Content[0])
+	assert.Equal(t, GetLabelOutput{MimeType: "image/png", Size: 10}, output)
+}
+
+func TestGetLabelWritesFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Write([]byte("image data"))
+	}))
+	defer server.Close()
+
+	os.Setenv("HOMEBOX_URL", server.URL)
+	os.Setenv("HOMEBOX_TOKEN", "test-token")
+	dir := t.TempDir()
+	t.Setenv("HOMEBOX_LABEL_DIR", dir)
+
+	result, output, err := getItemLabel(context.Background(), nil, GetItemLabelInput{ID: "../456"})
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "item-.._456.jpg"), output.File)
+	data, err := os.ReadFile(output.File)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("image data"), data)
+	if assert.Len(t, result.Content, 2) {
+		assert.Equal(t, "file://"+filepath.ToSlash(output.File), result.Content[1].(*mcp.ResourceLink).URI)
+	}
 }
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// defaultDueWithinDays is how far ahead list_maintenance looks for entries
+// that are due, when dueWithinDays is not set.
+const defaultDueWithinDays = 30
+
+// Input for the list_maintenance tool.
+type ListMaintenanceInput struct {
+	Status        string `json:"status,omitempty" jsonschema:"Which entries to list: scheduled (not completed yet), completed, overdue, due (overdue or due within dueWithinDays) or all (the default)"`
+	DueWithinDays int    `json:"dueWithinDays,omitempty" jsonschema:"Days ahead the due status looks; 30 by default"`
+	Item          string `json:"item,omitempty" jsonschema:"Only list the entries of this item, by ID, asset ID or name"`
+	Location      string `json:"location,omitempty" jsonschema:"Only list the entries of items in this location or below it, by ID, name or path"`
+	Label         string `json:"label,omitempty" jsonschema:"Only list the entries of items with this label, by ID or name"`
+	Descending    bool   `json:"descending,omitempty" jsonschema:"Sort the latest due date first"`
+}
+
+// MaintenanceListEntry is a maintenance entry with its item.
+type MaintenanceListEntry struct {
+	ID            string   `json:"id" jsonschema:"ID of the maintenance entry"`
+	Name          string   `json:"name" jsonschema:"Name of the maintenance task"`
+	Description   string   `json:"description,omitempty" jsonschema:"Details of the task"`
+	Status        string   `json:"status" jsonschema:"scheduled, overdue or completed"`
+	ScheduledDate string   `json:"scheduledDate,omitempty" jsonschema:"Date the task is due"`
+	CompletedDate string   `json:"completedDate,omitempty" jsonschema:"Date the task was done"`
+	Cost          float64  `json:"cost" jsonschema:"Cost of the task"`
+	ItemID        string   `json:"itemId" jsonschema:"ID of the item"`
+	ItemName      string   `json:"itemName" jsonschema:"Name of the item"`
+	Location      string   `json:"location,omitempty" jsonschema:"Path of the location of the item"`
+	Labels        []string `json:"labels,omitempty" jsonschema:"Labels of the item"`
+}
+
+// CostTotal is the maintenance cost of an item, location or label.
+type CostTotal struct {
+	ID      string  `json:"id" jsonschema:"ID of the item, location or label"`
+	Name    string  `json:"name" jsonschema:"Name of the item or label, or path of the location"`
+	Entries int     `json:"entries" jsonschema:"Number of entries"`
+	Cost    float64 `json:"cost" jsonschema:"Total cost of the entries"`
+}
+
+// Output for the list_maintenance tool.
+type ListMaintenanceOutput struct {
+	Entries    []MaintenanceListEntry `json:"entries" jsonschema:"The entries, by due date"`
+	TotalCost  float64                `json:"totalCost" jsonschema:"Total cost of the entries"`
+	ByItem     []CostTotal            `json:"byItem" jsonschema:"Cost by item, highest first"`
+	ByLocation []CostTotal            `json:"byLocation" jsonschema:"Cost by the location of the items, highest first"`
+	ByLabel    []CostTotal            `json:"byLabel" jsonschema:"Cost by label, highest first. An entry counts towards every label of its item"`
+}
+
+// parseCost reads a maintenance cost, which Homebox stores as text. Costs
+// that are not numbers count as zero.
+func parseCost(cost string) float64 {
+	value, err := strconv.ParseFloat(strings.TrimSpace(cost), 64)
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
+// listMaintenance is the implementation of the "list_maintenance" tool. The
+// entries come from the group-wide maintenance endpoint, and the locations
+// and labels from one listing of the items.
+func listMaintenance(ctx context.Context, req *mcp.CallToolRequest, input ListMaintenanceInput) (*mcp.CallToolResult, ListMaintenanceOutput, error) {
+	status := input.Status
+	if status == "" {
+		status = "all"
+	}
+	query := "both"
+	switch status {
+	case "completed":
+		query = "completed"
+	case "scheduled", "overdue", "due":
+		query = "scheduled"
+	case "all":
+	default:
+		return nil, ListMaintenanceOutput{}, fmt.Errorf("status must be scheduled, completed, overdue, due or all")
+	}
+	days := input.DueWithinDays
+	if days <= 0 {
+		days = defaultDueWithinDays
+	}
+	today := time.Now().Format(time.DateOnly)
+	horizon := time.Now().AddDate(0, 0, days).Format(time.DateOnly)
+
+	itemID, err := resolveItemRef(ctx, "item", input.Item)
+	if err != nil {
+		return nil, ListMaintenanceOutput{}, err
+	}
+	locationID, err := resolveLocationRef(ctx, "location", input.Location, false)
+	if err != nil {
+		return nil, ListMaintenanceOutput{}, err
+	}
+	labelIDs := []string{}
+	if input.Label != "" {
+		labelIDs = append(labelIDs, input.Label)
+		if err := resolveLabelRefs(ctx, "label", labelIDs, false); err != nil {
+			return nil, ListMaintenanceOutput{}, err
+		}
+	}
+
+	entries, err := getGroupMaintenance(ctx, query)
+	if err != nil {
+		return nil, ListMaintenanceOutput{}, err
+	}
+	summaries, err := queryAllItems(ctx, url.Values{"includeArchived": {"true"}})
+	if err != nil {
+		return nil, ListMaintenanceOutput{}, err
+	}
+	forest, err := loadLocationForest(ctx)
+	if err != nil {
+		return nil, ListMaintenanceOutput{}, err
+	}
+	items := make(map[string]ItemSummary)
+	for _, item := range summaries {
+		items[item.ID] = item
+	}
+
+	out := ListMaintenanceOutput{Entries: []MaintenanceListEntry{}}
+	for _, e := range entries {
+		entry := MaintenanceListEntry{
+			ID:            e.ID,
+			Name:          e.Name,
+			Description:   e.Description,
+			Status:        "scheduled",
+			ScheduledDate: dateOnly(e.ScheduledDate),
+			CompletedDate: dateOnly(e.CompletedDate),
+			Cost:          parseCost(e.Cost),
+			ItemID:        e.ItemID,
+			ItemName:      e.ItemName,
+		}
+		switch {
+		case entry.CompletedDate != "":
+			entry.Status = "completed"
+		case entry.ScheduledDate != "" && entry.ScheduledDate < today:
+			entry.Status = "overdue"
+		}
+		item := items[e.ItemID]
+		var labels []string
+		for _, label := range item.Labels {
+			entry.Labels = append(entry.Labels, label.Name)
+			labels = append(labels, label.ID)
+		}
+		if item.Location != nil && forest.Nodes[item.Location.ID] != nil {
+			entry.Location = forest.Nodes[item.Location.ID].Path
+		}
+
+		switch status {
+		case "completed", "scheduled":
+			if (entry.Status == "completed") != (status == "completed") {
+				continue
+			}
+		case "overdue":
+			if entry.Status != "overdue" {
+				continue
+			}
+		case "due":
+			if entry.Status == "completed" || entry.ScheduledDate == "" || entry.ScheduledDate > horizon {
+				continue
+			}
+		}
+		if itemID != "" && e.ItemID != itemID {
+			continue
+		}
+		// Locations are compared by ID, as names need not be unique and may
+		// contain "/".
+		if locationID != "" && (item.Location == nil || !forest.isWithin(item.Location.ID, locationID)) {
+			continue
+		}
+		if len(labelIDs) > 0 && !slices.Contains(labels, labelIDs[0]) {
+			continue
+		}
+		out.Entries = append(out.Entries, entry)
+	}
+
+	// Entries without a due date are sorted by completion date.
+	dueDate := func(e MaintenanceListEntry) string { return cmp.Or(e.ScheduledDate, e.CompletedDate) }
+	slices.SortStableFunc(out.Entries, func(a, b MaintenanceListEntry) int {
+		d := strings.Compare(dueDate(a), dueDate(b))
+		if input.Descending {
+			d = -d
+		}
+		if d != 0 {
+			return d
+		}
+		return strings.Compare(a.ItemName+a.Name, b.ItemName+b.Name)
+	})
+
+	byItem, byLocation, byLabel := costTotals{}, costTotals{}, costTotals{}
+	for _, entry := range out.Entries {
+		out.TotalCost += entry.Cost
+		byItem.add(entry.ItemID, entry.ItemName, entry.Cost)
+		item := items[entry.ItemID]
+		if item.Location != nil {
+			byLocation.add(item.Location.ID, entry.Location, entry.Cost)
+		}
+		for _, label := range item.Labels {
+			byLabel.add(label.ID, label.Name, entry.Cost)
+		}
+	}
+	out.ByItem, out.ByLocation, out.ByLabel = byItem.sorted(), byLocation.sorted(), byLabel.sorted()
+	return nil, out, nil
+}
+
+// costTotals adds up costs by ID.
+type costTotals map[string]*CostTotal
+
+func (t costTotals) add(id, name string, cost float64) {
+	if t[id] == nil {
+		t[id] = &CostTotal{ID: id, Name: name}
+	}
+	t[id].Entries++
+	t[id].Cost += cost
+}
+
+// sorted returns the totals, highest cost first.
+func (t costTotals) sorted() []CostTotal {
+	totals := []CostTotal{}
+	for _, total := range t {
+		totals = append(totals, *total)
+	}
+	slices.SortFunc(totals, func(a, b CostTotal) int {
+		return cmp.Or(cmp.Compare(b.Cost, a.Cost), strings.Compare(a.Name, b.Name))
+	})
+	return totals
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListMaintenance(t *testing.T) {
+	const garageTreeID = "00000000-0000-4000-9000-000000000001"
+	store := newFakeItemStore()
+	store.labels = map[string]*LabelOut{
+		toolsID:       {ID: toolsID, Name: "Tools"},
+		electronicsID: {ID: electronicsID, Name: "Electronics"},
+	}
+	store.items[item1ID] = ItemOut{ID: item1ID, Name: "Mower", Location: &LocationSummary{ID: garageTreeID}, Labels: []LabelSummary{{ID: toolsID, Name: "Tools"}}}
+	store.items[item2ID] = ItemOut{ID: item2ID, Name: "Heater", Labels: []LabelSummary{{ID: electronicsID, Name: "Electronics"}}}
+	day := func(days int) string { return time.Now().AddDate(0, 0, days).Format(time.DateOnly) }
+	store.maintenance[item1ID] = []MaintenanceEntryWithDetails{
+		{ID: "m1", ItemID: item1ID, Name: "Sharpen blade", Cost: "25.50", CompletedDate: day(-300) + "T00:00:00Z", ScheduledDate: "0001-01-01T00:00:00Z"},
+		{ID: "m2", ItemID: item1ID, Name: "Oil change", Cost: "40", ScheduledDate: day(-3)},
+		{ID: "m3", ItemID: item1ID, Name: "Clean deck", Cost: "free", ScheduledDate: day(10)},
+	}
+	store.maintenance[item2ID] = []MaintenanceEntryWithDetails{
+		{ID: "m4", ItemID: item2ID, Name: "Service", Cost: "100", ScheduledDate: day(60)},
+		{ID: "m5", ItemID: item2ID, Name: "Filter", Cost: "10", CompletedDate: day(-400)},
+	}
+	homebox := httptest.NewServer(store)
+	defer homebox.Close()
+	os.Setenv("HOMEBOX_URL", homebox.URL)
+	os.Setenv("HOMEBOX_TOKEN", "test-token")
+	inventory.Invalidate()
+	defer inventory.Invalidate()
+	ctx := context.Background()
+
+	ids := func(out ListMaintenanceOutput) []string {
+		ids := []string{}
+		for _, e := range out.Entries {
+			ids = append(ids, e.ID)
+		}
+		return ids
+	}
+	list := func(input ListMaintenanceInput) ListMaintenanceOutput {
+		_, out, err := listMaintenance(ctx, nil, input)
+		assert.NoError(t, err)
+		return out
+	}
+
+	out := list(ListMaintenanceInput{})
+	assert.Equal(t, []string{"m5", "m1", "m2", "m3", "m4"}, ids(out))
+	assert.Equal(t, MaintenanceListEntry{
+		ID: "m2", Name: "Oil change", Status: "overdue", ScheduledDate: day(-3), Cost: 40,
+		ItemID: item1ID, ItemName: "Mower", Location: "Garage", Labels: []string{"Tools"},
+	}, out.Entries[2])
+	assert.Equal(t, "completed", out.Entries[1].Status)
+	assert.Equal(t, "scheduled", out.Entries[3].Status)
+	assert.Equal(t, 175.5, out.TotalCost)
+	assert.Equal(t, []CostTotal{{ID: item2ID, Name: "Heater", Entries: 2, Cost: 110}, {ID: item1ID, Name: "Mower", Entries: 3, Cost: 65.5}}, out.ByItem)
+	assert.Equal(t, []CostTotal{{ID: garageTreeID, Name: "Garage", Entries: 3, Cost: 65.5}}, out.ByLocation)
+	assert.Equal(t, []CostTotal{{ID: electronicsID, Name: "Electronics", Entries: 2, Cost: 110}, {ID: toolsID, Name: "Tools", Entries: 3, Cost: 65.5}}, out.ByLabel)
+
+	assert.Equal(t, []string{"m2"}, ids(list(ListMaintenanceInput{Status: "overdue"})))
+	assert.Equal(t, []string{"m2", "m3"}, ids(list(ListMaintenanceInput{Status: "due"})))
+	assert.Equal(t, []string{"m2", "m3", "m4"}, ids(list(ListMaintenanceInput{Status: "due", DueWithinDays: 90})))
+	assert.Equal(t, []string{"m2", "m3", "m4"}, ids(list(ListMaintenanceInput{Status: "scheduled"})))
+	assert.Equal(t, []string{"m5", "m1"}, ids(list(ListMaintenanceInput{Status: "completed"})))
+	assert.Equal(t, []string{"m4", "m3", "m2", "m1", "m5"}, ids(list(ListMaintenanceInput{Descending: true})))
+	assert.Equal(t, []string{"m1", "m2", "m3"}, ids(list(ListMaintenanceInput{Location: "Garage"})))
+	assert.Equal(t, []string{"m5", "m4"}, ids(list(ListMaintenanceInput{Label: "electronics"})))
+	assert.Equal(t, []string{"m4"}, ids(list(ListMaintenanceInput{Item: "Heater", Status: "scheduled"})))
+
+	_, _, err := listMaintenance(ctx, nil, ListMaintenanceInput{Status: "late"})
+	assert.Error(t, err)
+}
+
+func TestListMaintenanceLocation(t *testing.T) {
+	const (
+		garageID = "00000000-0000-4000-b000-000000000001"
+		shelf1ID = "00000000-0000-4000-b000-000000000002"
+		shelf2ID = "00000000-0000-4000-b000-000000000003"
+		binID    = "00000000-0000-4000-b000-000000000004"
+	)
+	// The shelves share a path, and a name contains "/".
+	locations := newFakeLocationStore(
+		&fakeLocation{ID: garageID, Name: "Garage"},
+		&fakeLocation{ID: shelf1ID, Name: "Shelf", ParentID: garageID},
+		&fakeLocation{ID: shelf2ID, Name: "Shelf", ParentID: garageID},
+		&fakeLocation{ID: binID, Name: "In/Out", ParentID: shelf1ID},
+	)
+	items := newFakeItemStore()
+	items.items[item1ID] = ItemOut{ID: item1ID, Name: "Drill", Location: &LocationSummary{ID: binID}}
+	items.items[item2ID] = ItemOut{ID: item2ID, Name: "Saw", Location: &LocationSummary{ID: shelf2ID}}
+	items.maintenance[item1ID] = []MaintenanceEntryWithDetails{{ID: "e1", ItemID: item1ID, Name: "Charge"}}
+	items.maintenance[item2ID] = []MaintenanceEntryWithDetails{{ID: "e2", ItemID: item2ID, Name: "Sharpen"}}
+	mux := http.NewServeMux()
+	mux.Handle("/api/v1/locations", locations)
+	mux.Handle("/api/v1/locations/", locations)
+	mux.Handle("/", items)
+	homebox := httptest.NewServer(mux)
+	defer homebox.Close()
+	os.Setenv("HOMEBOX_URL", homebox.URL)
+	os.Setenv("HOMEBOX_TOKEN", "test-token")
+	inventory.Invalidate()
+	defer inventory.Invalidate()
+
+	for id, want := range map[string]string{shelf1ID: "e1", shelf2ID: "e2", binID: "e1"} {
+		_, out, err := listMaintenance(context.Background(), nil, ListMaintenanceInput{Status: "all", Location: id})
+		assert.NoError(t, err)
+		if assert.Len(t, out.Entries, 1, id) {
+			assert.Equal(t, want, out.Entries[0].ID)
+		}
+	}
+}
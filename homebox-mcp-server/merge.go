@@ -0,0 +1,540 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// defaultMinSimilarity is how similar two normalized names must be for
+// suggest_merges to report them, unless the caller asks otherwise.
+const defaultMinSimilarity = 0.8
+
+// Input for the merge_labels tool.
+type MergeLabelsInput struct {
+	Target  string   `json:"target" jsonschema:"Label to keep, by ID or name"`
+	Sources []string `json:"sources" jsonschema:"Labels to merge into the target and then delete, by ID or name"`
+	DryRun  bool     `json:"dryRun,omitempty" jsonschema:"Only count what would be changed"`
+}
+
+// Input for the merge_locations tool.
+type MergeLocationsInput struct {
+	Target  string   `json:"target" jsonschema:"Location to keep, by ID, name or path"`
+	Sources []string `json:"sources" jsonschema:"Locations to merge into the target and then delete, by ID, name or path"`
+	DryRun  bool     `json:"dryRun,omitempty" jsonschema:"Only count what would be changed"`
+}
+
+// MergeSource is a label or location merged into the target.
+type MergeSource struct {
+	ID        string `json:"id" jsonschema:"ID of the source"`
+	Name      string `json:"name" jsonschema:"Name of the source"`
+	Path      string `json:"path,omitempty" jsonschema:"Path of a source location"`
+	Items     int    `json:"items" jsonschema:"Number of items moved, or to be moved, to the target"`
+	Locations int    `json:"locations,omitempty" jsonschema:"Number of child locations moved, or to be moved, to the target"`
+	Deleted   bool   `json:"deleted" jsonschema:"Whether the source was deleted"`
+	Error     string `json:"error,omitempty" jsonschema:"Why the source was kept"`
+}
+
+// Output for the merge_labels and merge_locations tools.
+type MergeOutput struct {
+	DryRun      bool             `json:"dryRun" jsonschema:"Whether nothing was changed"`
+	TargetID    string           `json:"targetId" jsonschema:"ID of the target"`
+	TargetName  string           `json:"targetName" jsonschema:"Name of the target"`
+	Description string           `json:"description" jsonschema:"Description of the target after the merge, combining the descriptions of the sources"`
+	Sources     []MergeSource    `json:"sources" jsonschema:"The merged sources"`
+	Failures    []BulkItemResult `json:"failures,omitempty" jsonschema:"Items that could not be moved to the target"`
+}
+
+// combineDescriptions appends the distinct, non-empty descriptions to
+// target's.
+func combineDescriptions(target string, others ...string) string {
+	combined := strings.TrimSpace(target)
+	for _, other := range others {
+		other = strings.TrimSpace(other)
+		if other == "" || strings.Contains(combined, other) {
+			continue
+		}
+		if combined != "" {
+			combined += "\n\n"
+		}
+		combined += other
+	}
+	return combined
+}
+
+// resolveMergeRefs resolves the target and sources of a merge, dropping
+// repeated sources.
+func resolveMergeRefs(target string, sources []string, resolve func(field, ref string) (string, error)) (string, []string, error) {
+	if len(sources) == 0 {
+		return "", nil, fmt.Errorf("sources must not be empty")
+	}
+	targetID, err := resolve("target", target)
+	if err != nil {
+		return "", nil, err
+	}
+	var sourceIDs []string
+	for _, ref := range sources {
+		id, err := resolve("sources", ref)
+		if err != nil {
+			return "", nil, err
+		}
+		if id == targetID {
+			return "", nil, fmt.Errorf("source %s is the target", ref)
+		}
+		if !slices.Contains(sourceIDs, id) {
+			sourceIDs = append(sourceIDs, id)
+		}
+	}
+	return targetID, sourceIDs, nil
+}
+
+// patchItems applies patch to every item through patchItem, and returns the
+// results that failed.
+func patchItems(ctx context.Context, items []ItemSummary, patch ItemPatch, names map[string]string) []BulkItemResult {
+	var mu sync.Mutex
+	var failures []BulkItemResult
+	forEachBounded(len(items), bulkConcurrency(0, len(items)), func(i int) {
+		result := patchItem(ctx, items[i].ID, patch, names, false)
+		if result.Status == "failed" {
+			result.Index = i
+			mu.Lock()
+			failures = append(failures, result)
+			mu.Unlock()
+		}
+	})
+	sort.Slice(failures, func(i, j int) bool { return failures[i].Index < failures[j].Index })
+	return failures
+}
+
+// mergeLabels is the implementation of the "merge_labels" tool. Every item
+// with a source label gets the target label instead, the descriptions are
+// combined, and the sources are deleted. A source is kept if any of its
+// items could not be relabelled.
+func mergeLabels(ctx context.Context, req *mcp.CallToolRequest, input MergeLabelsInput) (*mcp.CallToolResult, MergeOutput, error) {
+	targetID, sourceIDs, err := resolveMergeRefs(input.Target, input.Sources, func(field, ref string) (string, error) {
+		refs := []string{ref}
+		err := resolveLabelRefs(ctx, field, refs, false)
+		return refs[0], err
+	})
+	if err != nil {
+		return nil, MergeOutput{}, err
+	}
+
+	_, labels, err := getLabels(ctx, nil, GetLabelsInput{})
+	if err != nil {
+		return nil, MergeOutput{}, err
+	}
+	byID := make(map[string]LabelOut)
+	for _, label := range labels.Labels {
+		byID[label.ID] = label
+	}
+	target, ok := byID[targetID]
+	if !ok {
+		return nil, MergeOutput{}, fmt.Errorf("label %s not found", input.Target)
+	}
+
+	out := MergeOutput{DryRun: input.DryRun, TargetID: target.ID, TargetName: target.Name}
+	names := map[string]string{target.ID: target.Name}
+	sourceItems := make([][]ItemSummary, len(sourceIDs))
+	var descriptions []string
+	for i, id := range sourceIDs {
+		source, ok := byID[id]
+		if !ok {
+			return nil, MergeOutput{}, fmt.Errorf("label %s not found", id)
+		}
+		names[id] = source.Name
+		descriptions = append(descriptions, source.Description)
+		if sourceItems[i], err = queryAllItems(ctx, url.Values{"labels": {id}, "includeArchived": {"true"}}); err != nil {
+			return nil, MergeOutput{}, err
+		}
+		out.Sources = append(out.Sources, MergeSource{ID: id, Name: source.Name, Items: len(sourceItems[i])})
+	}
+	out.Description = combineDescriptions(target.Description, descriptions...)
+	if input.DryRun {
+		return nil, out, nil
+	}
+
+	defer inventory.Invalidate()
+	progress := newToolProgress(req)
+	for i, id := range sourceIDs {
+		progress.Report(ctx, float64(i), float64(len(sourceIDs)), fmt.Sprintf("Merging %s into %s", names[id], target.Name))
+		failures := patchItems(ctx, sourceItems[i], ItemPatch{AddLabels: []string{target.ID}, RemoveLabels: []string{id}}, names)
+		out.Failures = append(out.Failures, failures...)
+		if len(failures) > 0 {
+			out.Sources[i].Error = fmt.Sprintf("kept because %d items could not be relabelled", len(failures))
+		}
+	}
+	if out.Description != strings.TrimSpace(target.Description) {
+		if _, _, err := updateLabel(ctx, nil, UpdateLabelInput{ID: target.ID, Name: target.Name, Description: out.Description, Color: target.Color}); err != nil {
+			return nil, MergeOutput{}, err
+		}
+	}
+	for i := range out.Sources {
+		if out.Sources[i].Error != "" {
+			continue
+		}
+		if _, _, err := deleteLabel(ctx, nil, DeleteLabelInput{ID: out.Sources[i].ID}); err != nil {
+			out.Sources[i].Error = err.Error()
+			continue
+		}
+		out.Sources[i].Deleted = true
+	}
+	return nil, out, nil
+}
+
+// mergeLocations is the implementation of the "merge_locations" tool. The
+// items and child locations of every source are moved to the target, the
+// descriptions are combined, and the sources are deleted, deepest first. A
+// source is kept if anything in it could not be moved.
+func mergeLocations(ctx context.Context, req *mcp.CallToolRequest, input MergeLocationsInput) (*mcp.CallToolResult, MergeOutput, error) {
+	targetID, sourceIDs, err := resolveMergeRefs(input.Target, input.Sources, func(field, ref string) (string, error) {
+		return resolveLocationRef(ctx, field, ref, false)
+	})
+	if err != nil {
+		return nil, MergeOutput{}, err
+	}
+
+	forest, err := loadLocationForest(ctx)
+	if err != nil {
+		return nil, MergeOutput{}, err
+	}
+	target, ok := forest.Nodes[targetID]
+	if !ok {
+		return nil, MergeOutput{}, fmt.Errorf("location %s not found", input.Target)
+	}
+
+	out := MergeOutput{DryRun: input.DryRun, TargetID: target.ID, TargetName: target.Name}
+	names := map[string]string{target.ID: target.Path}
+	sourceItems := make([][]ItemSummary, len(sourceIDs))
+	sourceChildren := make([][]string, len(sourceIDs))
+	var descriptions []string
+	for i, id := range sourceIDs {
+		source, ok := forest.Nodes[id]
+		if !ok {
+			return nil, MergeOutput{}, fmt.Errorf("location %s not found", id)
+		}
+		if forest.isWithin(target.ID, id) {
+			return nil, MergeOutput{}, fmt.Errorf("cannot merge %s into %s: the target is inside the source", source.Path, target.Path)
+		}
+		descriptions = append(descriptions, source.Description)
+
+		// Homebox may include the items of child locations, which stay
+		// where they are.
+		items, err := queryAllItems(ctx, url.Values{"locations": {id}, "includeArchived": {"true"}})
+		if err != nil {
+			return nil, MergeOutput{}, err
+		}
+		for _, item := range items {
+			if item.Location != nil && item.Location.ID == id {
+				sourceItems[i] = append(sourceItems[i], item)
+			}
+		}
+		for _, child := range source.Children {
+			if !slices.Contains(sourceIDs, child) {
+				sourceChildren[i] = append(sourceChildren[i], child)
+			}
+		}
+		out.Sources = append(out.Sources, MergeSource{ID: id, Name: source.Name, Path: source.Path, Items: len(sourceItems[i]), Locations: len(sourceChildren[i])})
+	}
+	out.Description = combineDescriptions(target.Description, descriptions...)
+	if input.DryRun {
+		return nil, out, nil
+	}
+
+	defer inventory.Invalidate()
+	progress := newToolProgress(req)
+	for i, id := range sourceIDs {
+		progress.Report(ctx, float64(i), float64(len(sourceIDs)), fmt.Sprintf("Merging %s into %s", forest.Nodes[id].Path, target.Path))
+		failures := patchItems(ctx, sourceItems[i], ItemPatch{LocationID: target.ID}, names)
+		out.Failures = append(out.Failures, failures...)
+		failed := len(failures)
+		for _, childID := range sourceChildren[i] {
+			child := forest.Nodes[childID]
+			if _, _, err := updateLocation(ctx, nil, UpdateLocationInput{ID: child.ID, Name: child.Name, Description: child.Description, ParentID: target.ID}); err != nil {
+				out.Failures = append(out.Failures, BulkItemResult{ID: child.ID, Name: child.Path, Status: "failed", Error: err.Error()})
+				failed++
+			}
+		}
+		if failed > 0 {
+			out.Sources[i].Error = fmt.Sprintf("kept because %d items or locations could not be moved", failed)
+		}
+	}
+	if out.Description != strings.TrimSpace(target.Description) {
+		if _, _, err := updateLocation(ctx, nil, UpdateLocationInput{ID: target.ID, Name: target.Name, Description: out.Description, ParentID: target.ParentID}); err != nil {
+			return nil, MergeOutput{}, err
+		}
+	}
+
+	// Delete nested sources before the sources they are in.
+	order := make([]int, len(out.Sources))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return strings.Count(out.Sources[order[a]].Path, "/") > strings.Count(out.Sources[order[b]].Path, "/")
+	})
+	kept := make(map[string]bool)
+	for _, i := range order {
+		source := &out.Sources[i]
+		for _, child := range forest.Nodes[source.ID].Children {
+			if kept[child] {
+				source.Error = "kept because a source inside it was kept"
+			}
+		}
+		if source.Error != "" {
+			kept[source.ID] = true
+			continue
+		}
+		if _, _, err := deleteLocation(ctx, nil, DeleteLocationInput{ID: source.ID}); err != nil {
+			source.Error = err.Error()
+			kept[source.ID] = true
+			continue
+		}
+		source.Deleted = true
+	}
+	return nil, out, nil
+}
+
+// Input for the suggest_merges tool.
+type SuggestMergesInput struct {
+	Kind          string  `json:"kind,omitempty" jsonschema:"labels or locations; both when empty"`
+	MinSimilarity float64 `json:"minSimilarity,omitempty" jsonschema:"How similar normalized names must be, from 0 to 1; 0.8 by default"`
+}
+
+// MergeCandidate is a label or location that may be a duplicate.
+type MergeCandidate struct {
+	ID    string `json:"id" jsonschema:"ID of the label or location"`
+	Name  string `json:"name" jsonschema:"Name of the label or location"`
+	Path  string `json:"path,omitempty" jsonschema:"Path of a location"`
+	Items int    `json:"items" jsonschema:"Number of items with the label or in the location"`
+}
+
+// MergeSuggestion is a group of labels or locations that are likely
+// duplicates of each other.
+type MergeSuggestion struct {
+	Kind       string           `json:"kind" jsonschema:"labels or locations"`
+	Similarity float64          `json:"similarity" jsonschema:"Lowest similarity between the names in the group, from 0 to 1"`
+	Target     MergeCandidate   `json:"target" jsonschema:"Suggested target: the entry with the most items"`
+	Sources    []MergeCandidate `json:"sources" jsonschema:"Suggested sources to merge into the target"`
+}
+
+// Output for the suggest_merges tool.
+type SuggestMergesOutput struct {
+	Suggestions []MergeSuggestion `json:"suggestions" jsonschema:"Groups of likely duplicates, most similar first"`
+}
+
+// suggestMerges is the implementation of the "suggest_merges" tool. Labels
+// are compared with every other label, and locations with the other
+// locations in the same parent, since locations such as Shelf 1 often
+// repeat in different places.
+func suggestMerges(ctx context.Context, req *mcp.CallToolRequest, input SuggestMergesInput) (*mcp.CallToolResult, SuggestMergesOutput, error) {
+	minSimilarity := input.MinSimilarity
+	if minSimilarity <= 0 {
+		minSimilarity = defaultMinSimilarity
+	}
+	if input.Kind != "" && input.Kind != "labels" && input.Kind != "locations" {
+		return nil, SuggestMergesOutput{}, fmt.Errorf("kind must be labels or locations")
+	}
+
+	out := SuggestMergesOutput{Suggestions: []MergeSuggestion{}}
+	if input.Kind != "locations" {
+		_, labels, err := getLabels(ctx, nil, GetLabelsInput{})
+		if err != nil {
+			return nil, SuggestMergesOutput{}, err
+		}
+		candidates := make([]MergeCandidate, len(labels.Labels))
+		for i, label := range labels.Labels {
+			candidates[i] = MergeCandidate{ID: label.ID, Name: label.Name}
+		}
+		for _, group := range similarGroups(candidates, minSimilarity) {
+			for i := range group.members {
+				result, err := queryItems(ctx, url.Values{"labels": {group.members[i].ID}, "includeArchived": {"true"}, "pageSize": {"1"}})
+				if err != nil {
+					return nil, SuggestMergesOutput{}, err
+				}
+				group.members[i].Items = result.Total
+			}
+			out.Suggestions = append(out.Suggestions, group.suggestion("labels"))
+		}
+	}
+
+	if input.Kind != "labels" {
+		forest, err := loadLocationForest(ctx)
+		if err != nil {
+			return nil, SuggestMergesOutput{}, err
+		}
+		siblings := map[string][]MergeCandidate{}
+		for _, node := range forest.Nodes {
+			siblings[node.ParentID] = append(siblings[node.ParentID], MergeCandidate{ID: node.ID, Name: node.Name, Path: node.Path, Items: node.ItemCount})
+		}
+		for _, candidates := range siblings {
+			sort.Slice(candidates, func(i, j int) bool {
+				if candidates[i].Path != candidates[j].Path {
+					return candidates[i].Path < candidates[j].Path
+				}
+				return candidates[i].ID < candidates[j].ID
+			})
+			for _, group := range similarGroups(candidates, minSimilarity) {
+				out.Suggestions = append(out.Suggestions, group.suggestion("locations"))
+			}
+		}
+	}
+
+	sort.SliceStable(out.Suggestions, func(i, j int) bool {
+		a, b := out.Suggestions[i], out.Suggestions[j]
+		if a.Similarity != b.Similarity {
+			return a.Similarity > b.Similarity
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Target.Name+a.Target.Path < b.Target.Name+b.Target.Path
+	})
+	return nil, out, nil
+}
+
+// similarGroup is a group of candidates with similar names.
+type similarGroup struct {
+	members    []MergeCandidate
+	similarity float64
+}
+
+// suggestion suggests merging the group into the member with the most items.
+func (g *similarGroup) suggestion(kind string) MergeSuggestion {
+	target := 0
+	for i, member := range g.members {
+		if member.Items > g.members[target].Items {
+			target = i
+		}
+	}
+	s := MergeSuggestion{Kind: kind, Similarity: g.similarity, Target: g.members[target]}
+	for i, member := range g.members {
+		if i != target {
+			s.Sources = append(s.Sources, member)
+		}
+	}
+	return s
+}
+
+// similarGroups groups candidates whose normalized names are at least
+// minSimilarity similar, directly or through other members of the group.
+// Candidates without a similar name are left out.
+func similarGroups(candidates []MergeCandidate, minSimilarity float64) []*similarGroup {
+	normalized := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		normalized[i] = normalizeName(candidate.Name)
+	}
+
+	// Union-find over the pairs that are similar enough.
+	parent := make([]int, len(candidates))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	lowest := make(map[int]float64)
+	for i := range candidates {
+		for j := i + 1; j < len(candidates); j++ {
+			score := nameSimilarity(normalized[i], normalized[j])
+			if score < minSimilarity {
+				continue
+			}
+			a, b := find(i), find(j)
+			low := score
+			for _, root := range []int{a, b} {
+				if s, ok := lowest[root]; ok {
+					low = min(low, s)
+				}
+			}
+			delete(lowest, a)
+			delete(lowest, b)
+			parent[b] = a
+			lowest[a] = low
+		}
+	}
+
+	groups := make(map[int]*similarGroup)
+	var order []*similarGroup
+	for i, candidate := range candidates {
+		root := find(i)
+		similarity, ok := lowest[root]
+		if !ok {
+			continue
+		}
+		group := groups[root]
+		if group == nil {
+			group = &similarGroup{similarity: similarity}
+			groups[root] = group
+			order = append(order, group)
+		}
+		group.members = append(group.members, candidate)
+	}
+	return order
+}
+
+// normalizeName lower-cases name, drops punctuation and reduces each word to
+// a naive singular, so that "Electronics", "electronic" and "Electronic!"
+// compare equal.
+func normalizeName(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, word := range words {
+		switch {
+		case len(word) > 4 && strings.HasSuffix(word, "ies"):
+			words[i] = strings.TrimSuffix(word, "ies") + "y"
+		case len(word) > 4 && (strings.HasSuffix(word, "ches") || strings.HasSuffix(word, "shes") || strings.HasSuffix(word, "xes") || strings.HasSuffix(word, "sses")):
+			words[i] = strings.TrimSuffix(word, "es")
+		case len(word) > 3 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss"):
+			words[i] = strings.TrimSuffix(word, "s")
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// nameSimilarity returns 1 minus the edit distance of a and b relative to
+// the longer of the two.
+func nameSimilarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(editDistance(ra, rb))/float64(longest)
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	electronicsID = "00000000-0000-4000-a000-000000000001"
+	lowerID       = "00000000-0000-4000-a000-000000000002"
+	singularID    = "00000000-0000-4000-a000-000000000003"
+	toolsID       = "00000000-0000-4000-a000-000000000004"
+	garageID      = "00000000-0000-4000-b000-000000000001"
+	garage2ID     = "00000000-0000-4000-b000-000000000002"
+	garage3ID     = "00000000-0000-4000-b000-000000000003"
+	shelfID       = "00000000-0000-4000-b000-000000000004"
+	item1ID       = "00000000-0000-4000-8000-000000000001"
+	item2ID       = "00000000-0000-4000-8000-000000000002"
+	item3ID       = "00000000-0000-4000-8000-000000000003"
+)
+
+// newMergeFixture serves labels and items from an item store and locations
+// from a location store.
+func newMergeFixture(t *testing.T) (*fakeItemStore, *fakeLocationStore) {
+	items := newFakeItemStore()
+	items.labels = map[string]*LabelOut{
+		electronicsID: {ID: electronicsID, Name: "Electronics", Description: "Gadgets", Color: "#00f"},
+		lowerID:       {ID: lowerID, Name: "electronics"},
+		singularID:    {ID: singularID, Name: "Electronic", Description: "Cables too"},
+		toolsID:       {ID: toolsID, Name: "Tools"},
+	}
+	items.items[item1ID] = ItemOut{ID: item1ID, Name: "Radio", Labels: []LabelSummary{{ID: lowerID, Name: "electronics"}}, Location: &LocationSummary{ID: garage2ID}}
+	items.items[item2ID] = ItemOut{ID: item2ID, Name: "Cable", Labels: []LabelSummary{{ID: singularID, Name: "Electronic"}, {ID: electronicsID, Name: "Electronics"}}, Location: &LocationSummary{ID: garage3ID}}
+	items.items[item3ID] = ItemOut{ID: item3ID, Name: "Hammer", Labels: []LabelSummary{{ID: toolsID, Name: "Tools"}}, Location: &LocationSummary{ID: garageID}}
+	locations := newFakeLocationStore(
+		&fakeLocation{ID: garageID, Name: "Garage", Description: "Front", Items: 1},
+		&fakeLocation{ID: garage2ID, Name: "Garage", Description: "Back", Items: 1},
+		&fakeLocation{ID: garage3ID, Name: "garage", Items: 1},
+		&fakeLocation{ID: shelfID, Name: "Shelf", ParentID: garage2ID},
+	)
+
+	mux := http.NewServeMux()
+	mux.Handle("/api/v1/locations", locations)
+	mux.Handle("/api/v1/locations/", locations)
+	mux.Handle("/", items)
+	homebox := httptest.NewServer(mux)
+	t.Cleanup(homebox.Close)
+
+	os.Setenv("HOMEBOX_URL", homebox.URL)
+	os.Setenv("HOMEBOX_TOKEN", "test-token")
+	inventory.Invalidate()
+	t.Cleanup(inventory.Invalidate)
+	return items, locations
+}
+
+func TestNormalizeName(t *testing.T) {
+	assert.Equal(t, "electronic", normalizeName("Electronics"))
+	assert.Equal(t, "electronic", normalizeName(" electronic! "))
+	assert.Equal(t, "battery box", normalizeName("Batteries & Boxes"))
+	assert.Equal(t, "glass", normalizeName("Glass"))
+	assert.Equal(t, 1.0, nameSimilarity("garage", "garage"))
+	assert.InDelta(t, 0.83, nameSimilarity("garage", "garag"), 0.01)
+	assert.Equal(t, 3, editDistance([]rune("kitten"), []rune("sitting")))
+}
+
+func TestSuggestMerges(t *testing.T) {
+	newMergeFixture(t)
+
+	_, out, err := suggestMerges(context.Background(), nil, SuggestMergesInput{})
+	assert.NoError(t, err)
+	assert.Len(t, out.Suggestions, 2)
+
+	labels := out.Suggestions[0]
+	assert.Equal(t, "labels", labels.Kind)
+	assert.Equal(t, 1.0, labels.Similarity)
+	assert.Equal(t, MergeCandidate{ID: electronicsID, Name: "Electronics", Items: 1}, labels.Target)
+	assert.Len(t, labels.Sources, 2)
+
+	locations := out.Suggestions[1]
+	assert.Equal(t, "locations", locations.Kind)
+	assert.Equal(t, garageID, locations.Target.ID)
+	assert.Len(t, locations.Sources, 2)
+}
+
+func TestMergeLabels(t *testing.T) {
+	items, _ := newMergeFixture(t)
+	ctx := context.Background()
+	input := MergeLabelsInput{Target: "Electronics", Sources: []string{"electronics", "Electronic"}, DryRun: true}
+
+	_, out, err := mergeLabels(ctx, nil, input)
+	assert.NoError(t, err)
+	assert.Equal(t, electronicsID, out.TargetID)
+	assert.Equal(t, "Gadgets\n\nCables too", out.Description)
+	assert.Equal(t, []MergeSource{{ID: lowerID, Name: "electronics", Items: 1}, {ID: singularID, Name: "Electronic", Items: 1}}, out.Sources)
+	assert.Len(t, items.labels, 4)
+
+	input.DryRun = false
+	_, out, err = mergeLabels(ctx, nil, input)
+	assert.NoError(t, err)
+	assert.True(t, out.Sources[0].Deleted)
+	assert.True(t, out.Sources[1].Deleted)
+	assert.Empty(t, out.Failures)
+	assert.Equal(t, []LabelSummary{{ID: electronicsID, Name: "Electronics"}}, items.items[item1ID].Labels)
+	assert.Equal(t, []LabelSummary{{ID: electronicsID, Name: "Electronics"}}, items.items[item2ID].Labels)
+	assert.Equal(t, &LabelOut{ID: electronicsID, Name: "Electronics", Description: "Gadgets\n\nCables too", Color: "#00f"}, items.labels[electronicsID])
+	assert.Len(t, items.labels, 2)
+
+	_, _, err = mergeLabels(ctx, nil, MergeLabelsInput{Target: "Tools", Sources: []string{"Tools"}})
+	assert.ErrorContains(t, err, "is the target")
+}
+
+func TestMergeLocations(t *testing.T) {
+	items, locations := newMergeFixture(t)
+	ctx := context.Background()
+
+	_, _, err := mergeLocations(ctx, nil, MergeLocationsInput{Target: shelfID, Sources: []string{garage2ID}})
+	assert.ErrorContains(t, err, "the target is inside the source")
+
+	input := MergeLocationsInput{Target: garageID, Sources: []string{garage2ID, "garage"}, DryRun: true}
+	_, out, err := mergeLocations(ctx, nil, input)
+	assert.NoError(t, err)
+	assert.Equal(t, []MergeSource{
+		{ID: garage2ID, Name: "Garage", Path: "Garage", Items: 1, Locations: 1},
+		{ID: garage3ID, Name: "garage", Path: "garage", Items: 1},
+	}, out.Sources)
+	assert.Len(t, locations.locations, 4)
+
+	input.DryRun = false
+	_, out, err = mergeLocations(ctx, nil, input)
+	assert.NoError(t, err)
+	assert.True(t, out.Sources[0].Deleted)
+	assert.True(t, out.Sources[1].Deleted)
+	assert.Equal(t, []string{"Garage", "Garage/Shelf"}, locations.paths())
+	assert.Equal(t, "Front\n\nBack", locations.locations[garageID].Description)
+	for _, id := range []string{item1ID, item2ID, item3ID} {
+		assert.Equal(t, garageID, items.items[id].Location.ID)
+	}
+}
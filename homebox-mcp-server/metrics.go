@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	toolCallsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "homebox_mcp_tool_calls_total",
+		Help: "Number of MCP tool calls, by tool.",
+	}, []string{"tool"})
+	toolErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "homebox_mcp_tool_errors_total",
+		Help: "Number of failed MCP tool calls, by tool and error class (protocol, tool, network, upstream_4xx, upstream_5xx).",
+	}, []string{"tool", "class"})
+	toolDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "homebox_mcp_tool_duration_seconds",
+		Help:    "Latency of MCP tool calls, by tool.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"tool"})
+
+	upstreamRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "homebox_upstream_requests_total",
+		Help: "Number of requests to the Homebox API, by method, endpoint and status code (0 if no response was received).",
+	}, []string{"method", "endpoint", "code"})
+	upstreamDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "homebox_upstream_request_duration_seconds",
+		Help:    "Latency of requests to the Homebox API, by method and endpoint.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "endpoint"})
+	upstreamRetriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "homebox_upstream_retries_total",
+		Help: "Number of requests to the Homebox API that were retried, by endpoint.",
+	}, []string{"endpoint"})
+	upstreamCircuitState = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "homebox_upstream_circuit_state",
+		Help: "State of the circuit breaker in front of the Homebox API: 0 closed, 1 open, 2 half-open.",
+	})
+)
+
+// metricsMiddleware returns an MCP receiving middleware that records call
+// counts, error classes and latency for every tools/call request.
+func metricsMiddleware() mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			callReq, ok := req.(*mcp.CallToolRequest)
+			if method != "tools/call" || !ok {
+				return next(ctx, method, req)
+			}
+
+			tool := callReq.Params.Name
+			ctx, call := withToolCall(ctx)
+			start := time.Now()
+			result, err := next(ctx, method, req)
+			toolDuration.WithLabelValues(tool).Observe(time.Since(start).Seconds())
+			toolCallsTotal.WithLabelValues(tool).Inc()
+
+			if err != nil {
+				toolErrorsTotal.WithLabelValues(tool, "protocol").Inc()
+			} else if res, _ := result.(*mcp.CallToolResult); res != nil && res.IsError {
+				toolErrorsTotal.WithLabelValues(tool, call.errorClass()).Inc()
+			}
+			return result, err
+		}
+	}
+}
+
+// registerSessionGauge exposes the number of sessions connected to server.
+func registerSessionGauge(server *mcp.Server) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "homebox_mcp_active_sessions",
+		Help: "Number of connected MCP sessions.",
+	}, func() float64 {
+		n := 0
+		for range server.Sessions() {
+			n++
+		}
+		return float64(n)
+	})
+}
+
+// observeUpstream records the outcome of a single request to the Homebox API.
+func observeUpstream(method, endpoint string, resp *http.Response, err error, d time.Duration) {
+	code := 0
+	if err == nil {
+		code = resp.StatusCode
+	}
+	upstreamRequestsTotal.WithLabelValues(method, endpoint, strconv.Itoa(code)).Inc()
+	upstreamDuration.WithLabelValues(method, endpoint).Observe(d.Seconds())
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEndpointPattern(t *testing.T) {
+	assert.Equal(t, "/api/v1/items/{id}/attachments/{id}", endpointPattern("/api/v1/items/1c9b1a8e-0c1b-4c8e-9d55-3f1f0e0d2a11/attachments/0a9f4c9e-2b1d-4e7a-8c3b-5d6e7f8a9b0c"))
+	assert.Equal(t, "/api/v1/labelmaker/assets/{id}", endpointPattern("/api/v1/labelmaker/assets/000-123"))
+	assert.Equal(t, "/api/v1/items/export", endpointPattern("/api/v1/items/export"))
+}
+
+func TestToolCallErrorClass(t *testing.T) {
+	call := &toolCall{}
+	assert.Equal(t, "tool", call.errorClass())
+	call.addStatus(200)
+	call.addStatus(404)
+	assert.Equal(t, "upstream_4xx", call.errorClass())
+	call.addStatus(502)
+	assert.Equal(t, "upstream_5xx", call.errorClass())
+	call.addStatus(0)
+	assert.Equal(t, "network", call.errorClass())
+}
+
+func TestTransportRetries(t *testing.T) {
+	var calls atomic.Int32
+	homebox := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer homebox.Close()
+	client := &http.Client{Transport: &homeboxTransport{base: http.DefaultTransport, breaker: &circuitBreaker{}}}
+	retries := testutil.ToFloat64(upstreamRetriesTotal.WithLabelValues("/api/v1/items/{id}"))
+
+	req, _ := http.NewRequest("PUT", homebox.URL+"/api/v1/items/123", strings.NewReader("{}"))
+	resp, err := client.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(2), calls.Load())
+	assert.Equal(t, retries+1, testutil.ToFloat64(upstreamRetriesTotal.WithLabelValues("/api/v1/items/{id}")))
+
+	// POST is not idempotent, so it is not retried.
+	calls.Store(0)
+	resp, err = client.Post(homebox.URL+"/api/v1/items", "application/json", strings.NewReader("{}"))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	b := &circuitBreaker{}
+	for range breakerThreshold - 1 {
+		assert.True(t, b.allow())
+		b.record(true)
+	}
+	assert.Equal(t, circuitClosed, b.state)
+	b.record(true)
+	assert.Equal(t, circuitOpen, b.state)
+	assert.False(t, b.allow())
+	assert.Equal(t, 1.0, testutil.ToFloat64(upstreamCircuitState))
+
+	// After the cooldown one probe is let through; its failure opens the
+	// breaker again and its success closes it.
+	b.openedAt = time.Now().Add(-breakerCooldown)
+	assert.True(t, b.allow())
+	assert.Equal(t, circuitHalfOpen, b.state)
+	assert.False(t, b.allow())
+	b.record(true)
+	assert.Equal(t, circuitOpen, b.state)
+	b.openedAt = time.Now().Add(-breakerCooldown)
+	assert.True(t, b.allow())
+	b.record(false)
+	assert.Equal(t, circuitClosed, b.state)
+	assert.True(t, b.allow())
+	assert.Equal(t, 0.0, testutil.ToFloat64(upstreamCircuitState))
+}
+
+func TestCircuitBreakerCancelledProbe(t *testing.T) {
+	homebox := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer homebox.Close()
+	b := &circuitBreaker{state: circuitOpen, failures: breakerThreshold, openedAt: time.Now().Add(-breakerCooldown)}
+	client := &http.Client{Transport: &homeboxTransport{base: http.DefaultTransport, breaker: b}}
+
+	// A probe the caller gives up on neither closes the breaker nor blocks
+	// the next probe.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", homebox.URL+"/api/v1/items", nil)
+	_, err := client.Do(req)
+	assert.Error(t, err)
+	assert.Equal(t, circuitHalfOpen, b.state)
+	assert.Equal(t, breakerThreshold, b.failures)
+	assert.True(t, b.allow())
+
+	// Nor does a request timing out reset the failures of a closed breaker.
+	b.state, b.failures, b.probing = circuitClosed, 2, false
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ = http.NewRequestWithContext(ctx, "GET", homebox.URL+"/api/v1/items", nil)
+	_, err = client.Do(req)
+	assert.Error(t, err)
+	assert.Equal(t, circuitClosed, b.state)
+	assert.Equal(t, 2, b.failures)
+}
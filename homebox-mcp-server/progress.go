@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// progressChunkSize is how many bytes are read between progress
+// notifications when streaming a response body.
+const progressChunkSize = 64 << 10
+
+// toolProgress reports the progress of a long-running tool call to the
+// client, as notifications/progress tied to the request's progress token and
+// as notifications/message log messages. Log messages are only delivered at
+// or above the level the client selected with logging/setLevel.
+//
+// A nil *toolProgress, or one for a request without a progress token, is
+// valid and only sends what the client asked for.
+type toolProgress struct {
+	session *mcp.ServerSession
+	token   any
+	tool    string
+}
+
+// newToolProgress returns a reporter for req, which may be nil.
+func newToolProgress(req *mcp.CallToolRequest) *toolProgress {
+	if req == nil || req.Session == nil {
+		return nil
+	}
+	p := &toolProgress{session: req.Session}
+	if req.Params != nil {
+		p.token = req.Params.GetProgressToken()
+		p.tool = req.Params.Name
+	}
+	return p
+}
+
+// Report sends a progress notification. total may be zero if it is unknown.
+func (p *toolProgress) Report(ctx context.Context, progress, total float64, message string) {
+	if p == nil || p.token == nil {
+		return
+	}
+	p.session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+		ProgressToken: p.token,
+		Progress:      progress,
+		Total:         total,
+		Message:       message,
+	})
+}
+
+// Logf sends a log message at the given level.
+func (p *toolProgress) Logf(ctx context.Context, level mcp.LoggingLevel, format string, args ...any) {
+	if p == nil {
+		return
+	}
+	p.session.Log(ctx, &mcp.LoggingMessageParams{
+		Level:  level,
+		Logger: p.tool,
+		Data:   fmt.Sprintf(format, args...),
+	})
+}
+
+// readAllWithProgress reads r to the end, reporting the number of bytes read
+// so far. size is the expected length, or -1 if it is unknown.
+func readAllWithProgress(ctx context.Context, r io.Reader, size int64, progress *toolProgress) ([]byte, error) {
+	if progress == nil || progress.token == nil {
+		return io.ReadAll(r)
+	}
+
+	var total float64
+	if size > 0 {
+		total = float64(size)
+	}
+	var data []byte
+	buf := make([]byte, progressChunkSize)
+	for {
+		n, err := io.ReadFull(r, buf)
+		data = append(data, buf[:n]...)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return data, nil
+		}
+		if err != nil {
+			return data, err
+		}
+		progress.Report(ctx, float64(len(data)), total, fmt.Sprintf("Downloaded %d bytes", len(data)))
+	}
+}
+
+// countCSVRows returns the number of data records in a CSV or TSV export,
+// excluding the header. Records are parsed rather than counted by line, so
+// quoted fields spanning several lines count once. A file that fails to
+// parse counts the records read before the error.
+func countCSVRows(data []byte) int {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	if header, _, _ := bytes.Cut(data, []byte("\n")); bytes.Contains(header, []byte("\t")) {
+		reader.Comma = '\t'
+	}
+	records := 0
+	for {
+		if _, err := reader.Read(); err != nil {
+			break
+		}
+		records++
+	}
+	if records > 0 {
+		records--
+	}
+	return records
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountCSVRows(t *testing.T) {
+	assert.Equal(t, 0, countCSVRows(nil))
+	assert.Equal(t, 0, countCSVRows([]byte("HB.name\n")))
+	assert.Equal(t, 2, countCSVRows([]byte("HB.name\nDrill\nSaw\n")))
+	assert.Equal(t, 2, countCSVRows([]byte("HB.name\nDrill\nSaw")))
+	assert.Equal(t, 1, countCSVRows([]byte("HB.name,HB.description\nDrill,\"line one\nline two\"\n")))
+	assert.Equal(t, 2, countCSVRows([]byte("HB.name\tHB.description\nDrill\t\"18V\nbrushless\"\nSaw\t\n")))
+}
+
+func TestCreateMissingThumbnailsProgress(t *testing.T) {
+	homebox := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"completed":7}`))
+	}))
+	defer homebox.Close()
+
+	os.Setenv("HOMEBOX_URL", homebox.URL)
+	os.Setenv("HOMEBOX_TOKEN", "test-token")
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "create_missing_thumbnails"}, createMissingThumbnails)
+
+	var (
+		mu       sync.Mutex
+		progress []string
+		logs     []string
+	)
+	client := mcp.NewClient(&mcp.Implementation{Name: "client"}, &mcp.ClientOptions{
+		ProgressNotificationHandler: func(_ context.Context, req *mcp.ProgressNotificationClientRequest) {
+			mu.Lock()
+			defer mu.Unlock()
+			progress = append(progress, req.Params.Message)
+		},
+		LoggingMessageHandler: func(_ context.Context, req *mcp.LoggingMessageRequest) {
+			mu.Lock()
+			defer mu.Unlock()
+			logs = append(logs, fmt.Sprint(req.Params.Data))
+		},
+	})
+
+	ctx := context.Background()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	_, err := server.Connect(ctx, serverTransport, nil)
+	assert.NoError(t, err)
+	session, err := client.Connect(ctx, clientTransport, nil)
+	assert.NoError(t, err)
+	defer session.Close()
+
+	assert.NoError(t, session.SetLoggingLevel(ctx, &mcp.SetLoggingLevelParams{Level: "info"}))
+	_, err = session.CallTool(ctx, &mcp.CallToolParams{
+		Meta: mcp.Meta{"progressToken": "tok"},
+		Name: "create_missing_thumbnails",
+	})
+	assert.NoError(t, err)
+
+	// Notifications are delivered asynchronously, so wait for the last one.
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(progress) == 2 && len(logs) == 2
+	}, time.Second, 10*time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"Creating missing thumbnails", "Created 7 thumbnails"}, progress)
+	assert.Equal(t, []string{"Creating missing thumbnails", "Created 7 thumbnails"}, logs)
+}
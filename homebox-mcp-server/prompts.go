@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// findItemMatches is how many matching items the find_item prompt embeds.
+const findItemMatches = 5
+
+// registerPrompts registers the prompts for common inventory workflows. Each
+// prompt embeds the resources it is about and tells the model which tools to
+// use for the rest.
+func registerPrompts(server *mcp.Server) {
+	server.AddPrompt(&mcp.Prompt{
+		Name:        "audit_location",
+		Title:       "Audit a location",
+		Description: "Walks a location and everything below it, flagging items without a serial number or photo.",
+		Arguments: []*mcp.PromptArgument{
+			{Name: "location_id", Description: "ID of the location to audit.", Required: true},
+		},
+	}, auditLocationPrompt)
+	server.AddPrompt(&mcp.Prompt{
+		Name:        "add_item_from_description",
+		Title:       "Add an item from a description",
+		Description: "Creates an inventory item from a free-text description, reusing existing locations and labels.",
+		Arguments: []*mcp.PromptArgument{
+			{Name: "description", Description: "What the item is, where it is kept and anything else known about it.", Required: true},
+			{Name: "location_id", Description: "ID of the location to put the item in, if known."},
+		},
+	}, addItemPrompt)
+	server.AddPrompt(&mcp.Prompt{
+		Name:        "plan_maintenance",
+		Title:       "Plan upcoming maintenance",
+		Description: "Lists maintenance that is due soon or overdue and proposes a schedule.",
+		Arguments: []*mcp.PromptArgument{
+			{Name: "days", Description: "How many days ahead to plan for. Defaults to 30."},
+			{Name: "location_id", Description: "Only plan for items in this location."},
+		},
+	}, planMaintenancePrompt)
+	server.AddPrompt(&mcp.Prompt{
+		Name:        "insurance_summary",
+		Title:       "Prepare an insurance summary",
+		Description: "Summarises insured and high-value items, with the documentation an insurer will ask for.",
+		Arguments: []*mcp.PromptArgument{
+			{Name: "location_id", Description: "Only summarise items in this location."},
+		},
+	}, insuranceSummaryPrompt)
+	server.AddPrompt(&mcp.Prompt{
+		Name:        "find_item",
+		Title:       "Find where something is",
+		Description: "Searches the inventory for an item and reports the full path to where it is kept.",
+		Arguments: []*mcp.PromptArgument{
+			{Name: "query", Description: "Name or description of the item to find.", Required: true},
+		},
+	}, findItemPrompt)
+}
+
+func auditLocationPrompt(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	id := req.Params.Arguments["location_id"]
+	if id == "" {
+		return nil, fmt.Errorf("location_id is required")
+	}
+	location, err := embedResource(ctx, locationResourceURI(id))
+	if err != nil {
+		return nil, err
+	}
+	messages := []*mcp.PromptMessage{location}
+
+	items, err := queryItems(ctx, url.Values{"locations": {id}, "pageSize": {strconv.Itoa(resourcePageSize)}})
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items.Items {
+		messages = append(messages, &mcp.PromptMessage{Role: "user", Content: &mcp.ResourceLink{
+			URI:      itemResourceURI(item.ID),
+			Name:     item.Name,
+			MIMEType: "application/json",
+		}})
+	}
+
+	messages = append(messages, textMessage(fmt.Sprintf(`Audit the location %s shown above and every location below it.
+
+1. The location's children are listed above. Call get_location on each child, and on their children in turn, until you have the whole tree.
+2. For every location in the tree, find its items with get_items and read each one with get_item. The items directly in %s are linked above.
+3. Flag every item that has no serial number (an empty serialNumber) or no photo (no attachment of type "photo" and no imageId).
+4. Report the findings grouped by location, as the path from the audited location, for example "Garage / Shelf 2". For each flagged item give its name, asset ID and what is missing. Finish with totals of items checked and items flagged.
+
+Do not change anything in Homebox during the audit.`, id, id)))
+	return &mcp.GetPromptResult{Description: "Audit of location " + id, Messages: messages}, nil
+}
+
+func addItemPrompt(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	description := req.Params.Arguments["description"]
+	if description == "" {
+		return nil, fmt.Errorf("description is required")
+	}
+	var messages []*mcp.PromptMessage
+	where := "Call get_locations and pick the location that best matches the description. If none fits, ask before creating one with create_location."
+	if id := req.Params.Arguments["location_id"]; id != "" {
+		location, err := embedResource(ctx, locationResourceURI(id))
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, location)
+		where = "Put the item in the location shown above."
+	}
+
+	messages = append(messages, textMessage(fmt.Sprintf(`Add this item to the Homebox inventory:
+
+%s
+
+1. %s
+2. Call get_labels and choose the existing labels that apply. Only create a new label with create_label if nothing similar exists.
+3. Check with get_items that the item is not already in the inventory. If it is, show the existing entry and stop.
+4. Create the item with create_item, using a short name and putting the remaining details in the description.
+5. If the description mentions a manufacturer, model number, serial number, purchase date, price or warranty, set them with update_item.
+6. Reply with the new item's name, asset ID and location.`, description, where)))
+	return &mcp.GetPromptResult{Description: "Add an item to the inventory", Messages: messages}, nil
+}
+
+func planMaintenancePrompt(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	days := 30
+	if value := req.Params.Arguments["days"]; value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("days must be a positive number")
+		}
+		days = n
+	}
+	var messages []*mcp.PromptMessage
+	scope := "every item"
+	if id := req.Params.Arguments["location_id"]; id != "" {
+		location, err := embedResource(ctx, locationResourceURI(id))
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, location)
+		scope = "the items in the location shown above"
+	}
+
+	messages = append(messages, textMessage(fmt.Sprintf(`Plan the maintenance due in the next %d days for %s.
+
+1. List the items with get_items.
+2. Call get_maintenance_log for each item. An entry with a scheduledDate and no completedDate is pending.
+3. Collect the pending entries that are overdue or scheduled within the next %d days.
+4. Also look for items that are regularly maintained (several completed entries at a steady interval) but have nothing scheduled, and work out when the next one is due.
+5. Present a schedule ordered by date, with overdue work first. Give the item, the task, the date and the expected cost from earlier entries.
+6. Offer to record the proposed entries with create_maintenance_entry. Only create them once the plan is confirmed.`, days, scope, days)))
+	return &mcp.GetPromptResult{Description: fmt.Sprintf("Maintenance plan for the next %d days", days), Messages: messages}, nil
+}
+
+func insuranceSummaryPrompt(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	export, err := embedResource(ctx, exportResourceURI)
+	if err != nil {
+		return nil, err
+	}
+	messages := []*mcp.PromptMessage{export}
+	scope := "the whole inventory"
+	if id := req.Params.Arguments["location_id"]; id != "" {
+		location, err := embedResource(ctx, locationResourceURI(id))
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, location)
+		scope = "the location shown above and the locations below it"
+	}
+
+	messages = append(messages, textMessage(fmt.Sprintf(`Prepare an insurance summary of %s, using the CSV export above.
+
+1. Call get_currency so amounts are shown in the inventory's currency.
+2. List every insured item and every item with a purchase price, grouped by location, with the manufacturer, model number, serial number, purchase date and purchase price.
+3. For the most valuable items, call get_item to check for receipts, manuals and photos among the attachments.
+4. Flag items that are insured but missing a serial number, a purchase price or a receipt, and high-value items that are not marked as insured.
+5. Finish with the total purchase price per location and overall, and the number of items with complete documentation.`, scope)))
+	return &mcp.GetPromptResult{Description: "Insurance summary of " + scope, Messages: messages}, nil
+}
+
+func findItemPrompt(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	query := req.Params.Arguments["query"]
+	if query == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	matches, err := queryItems(ctx, url.Values{"q": {query}, "pageSize": {strconv.Itoa(findItemMatches)}})
+	if err != nil {
+		return nil, err
+	}
+	var messages []*mcp.PromptMessage
+	for _, item := range matches.Items {
+		message, err := embedResource(ctx, itemResourceURI(item.ID))
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+
+	found := "The items matching the search are shown above. Pick the one that best fits, asking if it is unclear."
+	if len(matches.Items) == 0 {
+		found = "No item name matched the search directly. Call get_items and look for items whose description, notes or labels fit instead."
+	}
+	messages = append(messages, textMessage(fmt.Sprintf(`Find where "%s" is kept.
+
+1. %s
+2. Call get_item_path with the item's ID to get the full path of locations and parent items it is stored in.
+3. Answer with the path, for example "Garage / Shelf 2 / Bin A", and mention the quantity if it is more than one.`, query, found)))
+	return &mcp.GetPromptResult{Description: fmt.Sprintf("Find %q", query), Messages: messages}, nil
+}
+
+// embedResource reads a homebox:// resource into a user message.
+func embedResource(ctx context.Context, uri string) (*mcp.PromptMessage, error) {
+	result, err := readResource(ctx, &mcp.ReadResourceRequest{Params: &mcp.ReadResourceParams{URI: uri}})
+	if err != nil {
+		return nil, err
+	}
+	return &mcp.PromptMessage{Role: "user", Content: &mcp.EmbeddedResource{Resource: result.Contents[0]}}, nil
+}
+
+// textMessage is a user message with the given instructions.
+func textMessage(text string) *mcp.PromptMessage {
+	return &mcp.PromptMessage{Role: "user", Content: &mcp.TextContent{Text: strings.TrimSpace(text)}}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrompts(t *testing.T) {
+	homebox := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/items":
+			if r.URL.Query().Get("q") == "drill" {
+				w.Write([]byte(`{"items":[{"id":"item1","name":"Drill"}],"page":1,"pageSize":5,"total":1}`))
+				return
+			}
+			assert.Equal(t, "loc1", r.URL.Query().Get("locations"))
+			w.Write([]byte(`{"items":[{"id":"item2","name":"Saw"}],"page":1,"pageSize":100,"total":1}`))
+		case "/api/v1/items/item1":
+			w.Write([]byte(`{"id":"item1","name":"Drill"}`))
+		case "/api/v1/locations/loc1":
+			w.Write([]byte(`{"id":"loc1","name":"Garage"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer homebox.Close()
+
+	os.Setenv("HOMEBOX_URL", homebox.URL)
+	os.Setenv("HOMEBOX_TOKEN", "test-token")
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	registerPrompts(server)
+
+	ctx := context.Background()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	_, err := server.Connect(ctx, serverTransport, nil)
+	assert.NoError(t, err)
+	session, err := mcp.NewClient(&mcp.Implementation{Name: "client"}, nil).Connect(ctx, clientTransport, nil)
+	assert.NoError(t, err)
+	defer session.Close()
+
+	prompts, err := session.ListPrompts(ctx, nil)
+	assert.NoError(t, err)
+	assert.Len(t, prompts.Prompts, 5)
+
+	find, err := session.GetPrompt(ctx, &mcp.GetPromptParams{Name: "find_item", Arguments: map[string]string{"query": "drill"}})
+	assert.NoError(t, err)
+	if assert.Len(t, find.Messages, 2) {
+		assert.Equal(t, "homebox://items/item1", find.Messages[0].Content.(*mcp.EmbeddedResource).Resource.URI)
+		assert.Contains(t, find.Messages[1].Content.(*mcp.TextContent).Text, "get_item_path")
+	}
+
+	audit, err := session.GetPrompt(ctx, &mcp.GetPromptParams{Name: "audit_location", Arguments: map[string]string{"location_id": "loc1"}})
+	assert.NoError(t, err)
+	if assert.Len(t, audit.Messages, 3) {
+		assert.Equal(t, "homebox://locations/loc1", audit.Messages[0].Content.(*mcp.EmbeddedResource).Resource.URI)
+		assert.Equal(t, "homebox://items/item2", audit.Messages[1].Content.(*mcp.ResourceLink).URI)
+		assert.Contains(t, audit.Messages[2].Content.(*mcp.TextContent).Text, "get_location")
+	}
+
+	_, err = session.GetPrompt(ctx, &mcp.GetPromptParams{Name: "plan_maintenance", Arguments: map[string]string{"days": "soon"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// uuidPattern matches a Homebox ID. IDs are used as they are, without
+// consulting the inventory index.
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// referenceCandidate is an entry a reference could refer to.
+type referenceCandidate struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Path string `json:"path,omitempty"`
+}
+
+// referenceError reports a reference that matched no entry, or more than
+// one. Its message is JSON, so the client can offer the candidates.
+type referenceError struct {
+	Kind       string               `json:"error"`
+	Field      string               `json:"field"`
+	Reference  string               `json:"reference"`
+	Candidates []referenceCandidate `json:"candidates,omitempty"`
+	Hint       string               `json:"hint,omitempty"`
+}
+
+func (e *referenceError) Error() string {
+	data, _ := json.Marshal(e)
+	return string(data)
+}
+
+// matchReference returns the error for a reference with the given matches,
+// or nil if it matched exactly one entry.
+func matchReference(field, ref string, matches []indexEntry) error {
+	switch len(matches) {
+	case 1:
+		return nil
+	case 0:
+		return &referenceError{Kind: "unknown_reference", Field: field, Reference: ref}
+	}
+	err := &referenceError{Kind: "ambiguous_reference", Field: field, Reference: ref}
+	for _, match := range matches {
+		err.Candidates = append(err.Candidates, referenceCandidate{ID: match.ID, Name: match.Name, Path: match.Path})
+	}
+	return err
+}
+
+// preferExact narrows matches that differ only in case to the one whose
+// key is exactly ref, if there is one.
+func preferExact(matches []indexEntry, ref string, key func(indexEntry) string) []indexEntry {
+	if len(matches) < 2 {
+		return matches
+	}
+	var exact []indexEntry
+	for _, match := range matches {
+		if key(match) == ref {
+			exact = append(exact, match)
+		}
+	}
+	if len(exact) == 1 {
+		return exact
+	}
+	return matches
+}
+
+// lookupReference returns the index entries find matches. If there are none,
+// the index is rebuilt and searched again, since the entry may have been
+// created after the index was built.
+func lookupReference(ctx context.Context, find func(*indexSnapshot) []indexEntry) ([]indexEntry, *indexSnapshot, error) {
+	snapshot, err := inventory.Snapshot(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if matches := find(snapshot); len(matches) > 0 {
+		return matches, snapshot, nil
+	}
+	inventory.Invalidate()
+	if snapshot, err = inventory.Snapshot(ctx); err != nil {
+		return nil, nil, err
+	}
+	return find(snapshot), snapshot, nil
+}
+
+// splitLocationPath splits a location path such as "Garage/Shelf 2/Bin A".
+func splitLocationPath(path string) []string {
+	var segments []string
+	for _, segment := range strings.Split(path, "/") {
+		if segment = strings.TrimSpace(segment); segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+// resolveLocationRef returns the ID of the location ref refers to: an ID, a
+// name, or a path. A path need not start at a top-level location; it matches
+// every location whose path ends with it, unless it starts with "/". With
+// createMissing, the missing locations along a path are created.
+func resolveLocationRef(ctx context.Context, field, ref string, createMissing bool) (string, error) {
+	if ref == "" || uuidPattern.MatchString(ref) {
+		return ref, nil
+	}
+	segments := splitLocationPath(ref)
+	absolute := strings.HasPrefix(strings.TrimSpace(ref), "/")
+	matches, snapshot, err := lookupReference(ctx, func(snapshot *indexSnapshot) []indexEntry {
+		for _, loc := range snapshot.Locations {
+			if loc.ID == ref {
+				return []indexEntry{loc}
+			}
+		}
+		return matchLocationPath(snapshot.Locations, segments, absolute)
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(matches) == 0 && createMissing && len(segments) > 0 {
+		return createLocationPath(ctx, snapshot, field, ref, segments, absolute)
+	}
+	matches = preferExactPath(matches, segments)
+	if err := matchReference(field, ref, matches); err != nil {
+		return "", err
+	}
+	return matches[0].ID, nil
+}
+
+// matchLocationPath returns the locations whose path is segments. If there
+// are none and the path is not absolute, it returns the locations whose path
+// ends with segments, so "Shelf 2" matches "Garage/Shelf 2".
+func matchLocationPath(locations []indexEntry, segments []string, absolute bool) []indexEntry {
+	path := strings.ToLower(strings.Join(segments, "/"))
+	var full, suffix []indexEntry
+	for _, loc := range locations {
+		switch locPath := strings.ToLower(loc.Path); {
+		case locPath == path:
+			full = append(full, loc)
+		case !absolute && strings.HasSuffix(locPath, "/"+path):
+			suffix = append(suffix, loc)
+		}
+	}
+	if len(full) > 0 {
+		return full
+	}
+	return suffix
+}
+
+// preferExactPath narrows the matches of a path that differ only in case.
+func preferExactPath(matches []indexEntry, segments []string) []indexEntry {
+	path := strings.Join(segments, "/")
+	return preferExact(matches, path, func(loc indexEntry) string {
+		if len(segments) == 1 {
+			return loc.Name
+		}
+		return loc.Path
+	})
+}
+
+// createLocationPath creates the locations of a path that do not exist yet,
+// below the longest part of it that does. That part is matched like a
+// reference, so it may be ambiguous. A path none of which exists is only
+// created as a new top-level tree if it is a single name or starts with "/";
+// otherwise it is more likely a partial path with a typo than a new tree.
+func createLocationPath(ctx context.Context, snapshot *indexSnapshot, field, ref string, segments []string, absolute bool) (string, error) {
+	var parentID string
+	missing := segments
+	for i := len(segments) - 1; i > 0; i-- {
+		matches := matchLocationPath(snapshot.Locations, segments[:i], absolute)
+		if len(matches) == 0 {
+			continue
+		}
+		matches = preferExactPath(matches, segments[:i])
+		if err := matchReference(field, ref, matches); err != nil {
+			return "", err
+		}
+		parentID, missing = matches[0].ID, segments[i:]
+		break
+	}
+	if parentID == "" && len(segments) > 1 && !absolute {
+		return "", &referenceError{
+			Kind: "unknown_reference", Field: field, Reference: ref,
+			Hint: "start the path at an existing location, or with / to create a new top-level location",
+		}
+	}
+
+	defer inventory.Invalidate()
+	for _, name := range missing {
+		_, created, err := createLocation(ctx, nil, CreateLocationInput{Name: name, ParentID: parentID})
+		if err != nil {
+			return "", err
+		}
+		parentID = created.ID
+	}
+	return parentID, nil
+}
+
+// resolveLabelRefs replaces label names in refs with their IDs. With
+// createMissing, labels that do not exist are created.
+func resolveLabelRefs(ctx context.Context, field string, refs []string, createMissing bool) error {
+	for i, ref := range refs {
+		if ref == "" || uuidPattern.MatchString(ref) {
+			continue
+		}
+		name := strings.TrimSpace(ref)
+		matches, _, err := lookupReference(ctx, func(snapshot *indexSnapshot) []indexEntry {
+			var matches []indexEntry
+			for _, label := range snapshot.Labels {
+				if label.ID == ref {
+					return []indexEntry{label}
+				}
+				if strings.EqualFold(label.Name, name) {
+					matches = append(matches, label)
+				}
+			}
+			return matches
+		})
+		if err != nil {
+			return err
+		}
+		if len(matches) == 0 && createMissing {
+			_, created, err := createLabel(ctx, nil, CreateLabelInput{Name: name})
+			if err != nil {
+				return err
+			}
+			inventory.Invalidate()
+			refs[i] = created.ID
+			continue
+		}
+		matches = preferExact(matches, name, func(label indexEntry) string { return label.Name })
+		if err := matchReference(field, ref, matches); err != nil {
+			return err
+		}
+		refs[i] = matches[0].ID
+	}
+	return nil
+}
+
+// resolveItemRef returns the ID of the item ref refers to: an ID, an asset ID
+// such as 000-042, or a name.
+func resolveItemRef(ctx context.Context, field, ref string) (string, error) {
+	if ref == "" || uuidPattern.MatchString(ref) {
+		return ref, nil
+	}
+	name := strings.TrimSpace(ref)
+	matches, _, err := lookupReference(ctx, func(snapshot *indexSnapshot) []indexEntry {
+		var matches []indexEntry
+		for _, item := range snapshot.Items {
+			if item.ID == ref || item.AssetID == ref {
+				return []indexEntry{item}
+			}
+			if strings.EqualFold(item.Name, name) {
+				matches = append(matches, item)
+			}
+		}
+		return matches
+	})
+	if err != nil {
+		return "", err
+	}
+	matches = preferExact(matches, name, func(item indexEntry) string { return item.Name })
+	if err := matchReference(field, ref, matches); err != nil {
+		return "", err
+	}
+	return matches[0].ID, nil
+}
+
+// resolveItemReferences resolves the location, labels and parent item of an
+// item being created or updated, in place.
+func resolveItemReferences(ctx context.Context, locationID *string, labelIDs []string, parentID *string, createMissing bool) error {
+	var err error
+	if *locationID, err = resolveLocationRef(ctx, "locationId", *locationID, createMissing); err != nil {
+		return err
+	}
+	if err := resolveLabelRefs(ctx, "labelIds", labelIDs, createMissing); err != nil {
+		return err
+	}
+	*parentID, err = resolveItemRef(ctx, "parentId", *parentID)
+	return err
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveReferences(t *testing.T) {
+	var created []string
+	newIDs := map[string]string{
+		"Shelf 3": "7a1e0c55-2b8e-4a43-9d1c-0f3b6d1e2a01",
+		"Bin A":   "7a1e0c55-2b8e-4a43-9d1c-0f3b6d1e2a02",
+		"Attic":   "7a1e0c55-2b8e-4a43-9d1c-0f3b6d1e2a03",
+		"Box":     "7a1e0c55-2b8e-4a43-9d1c-0f3b6d1e2a04",
+	}
+	homebox := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/v1/locations/tree":
+			w.Write([]byte(`[
+				{"id":"loc1","name":"Garage","type":"location","children":[{"id":"loc2","name":"Shelf 2","type":"location","children":[{"id":"loc5","name":"Bin C","type":"location","children":[]}]}]},
+				{"id":"loc3","name":"Basement","type":"location","children":[{"id":"loc4","name":"Shelf 2","type":"location","children":[]}]}
+			]`))
+		case r.URL.Path == "/api/v1/labels" && r.Method == http.MethodGet:
+			w.Write([]byte(`[{"id":"lab1","name":"Tools"}]`))
+		case r.URL.Path == "/api/v1/labels":
+			w.Write([]byte(`{"id":"lab2","name":"Power"}`))
+		case r.URL.Path == "/api/v1/items":
+			w.Write([]byte(`{"items":[{"id":"item1","name":"Toolbox","assetId":"000-042"}],"page":1,"pageSize":100,"total":1}`))
+		case r.URL.Path == "/api/v1/locations" && r.Method == http.MethodPost:
+			body, _ := io.ReadAll(r.Body)
+			var input CreateLocationInput
+			json.Unmarshal(body, &input)
+			created = append(created, input.Name+" in "+input.ParentID)
+			json.NewEncoder(w).Encode(LocationSummary{ID: newIDs[input.Name], Name: input.Name})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer homebox.Close()
+
+	os.Setenv("HOMEBOX_URL", homebox.URL)
+	os.Setenv("HOMEBOX_TOKEN", "test-token")
+	inventory.Invalidate()
+	defer inventory.Invalidate()
+	ctx := context.Background()
+
+	id, err := resolveLocationRef(ctx, "locationId", "garage / shelf 2", false)
+	assert.NoError(t, err)
+	assert.Equal(t, "loc2", id)
+
+	id, err = resolveLocationRef(ctx, "locationId", "0e4f4c1e-5d0a-4b0c-9a57-1c1c2f3e4d5a", false)
+	assert.NoError(t, err)
+	assert.Equal(t, "0e4f4c1e-5d0a-4b0c-9a57-1c1c2f3e4d5a", id)
+
+	_, err = resolveLocationRef(ctx, "locationId", "Shelf 2", false)
+	assert.JSONEq(t, `{"error":"ambiguous_reference","field":"locationId","reference":"Shelf 2","candidates":[
+		{"id":"loc2","name":"Shelf 2","path":"Garage/Shelf 2"},
+		{"id":"loc4","name":"Shelf 2","path":"Basement/Shelf 2"}
+	]}`, err.Error())
+
+	_, err = resolveLocationRef(ctx, "locationId", "Garage/Shelf 3/Bin A", false)
+	assert.JSONEq(t, `{"error":"unknown_reference","field":"locationId","reference":"Garage/Shelf 3/Bin A"}`, err.Error())
+
+	id, err = resolveLocationRef(ctx, "locationId", "Garage/Shelf 3/Bin A", true)
+	assert.NoError(t, err)
+	assert.Equal(t, newIDs["Bin A"], id)
+	assert.Equal(t, []string{"Shelf 3 in loc1", "Bin A in " + newIDs["Shelf 3"]}, created)
+
+	// A path may leave out the locations above it, but then nothing is
+	// created unless the part that exists is unambiguous.
+	id, err = resolveLocationRef(ctx, "locationId", "shelf 2/bin c", false)
+	assert.NoError(t, err)
+	assert.Equal(t, "loc5", id)
+	_, err = resolveLocationRef(ctx, "locationId", "/Shelf 2/Bin C", false)
+	assert.JSONEq(t, `{"error":"unknown_reference","field":"locationId","reference":"/Shelf 2/Bin C"}`, err.Error())
+	created = nil
+	_, err = resolveLocationRef(ctx, "locationId", "Shelf 2/Bin D", true)
+	assert.JSONEq(t, `{"error":"ambiguous_reference","field":"locationId","reference":"Shelf 2/Bin D","candidates":[
+		{"id":"loc2","name":"Shelf 2","path":"Garage/Shelf 2"},
+		{"id":"loc4","name":"Shelf 2","path":"Basement/Shelf 2"}
+	]}`, err.Error())
+	_, err = resolveLocationRef(ctx, "locationId", "Attic/Box", true)
+	assert.ErrorContains(t, err, `"hint":"start the path at an existing location`)
+	assert.Empty(t, created)
+	id, err = resolveLocationRef(ctx, "locationId", "/Attic/Box", true)
+	assert.NoError(t, err)
+	assert.Equal(t, newIDs["Box"], id)
+	assert.Equal(t, []string{"Attic in ", "Box in " + newIDs["Attic"]}, created)
+
+	labels := []string{"tools", "Power"}
+	assert.Error(t, resolveLabelRefs(ctx, "labelIds", labels, false))
+	assert.NoError(t, resolveLabelRefs(ctx, "labelIds", labels, true))
+	assert.Equal(t, []string{"lab1", "lab2"}, labels)
+
+	id, err = resolveItemRef(ctx, "parentId", "000-042")
+	assert.NoError(t, err)
+	assert.Equal(t, "item1", id)
+}
@@ -0,0 +1,688 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html/template"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// insuranceReportTemplate is the URI template of the insurance report
+	// resources. The query parameters are the tool's input.
+	insuranceReportTemplate = resourceScheme + "reports/insurance.{format}{?insuredOnly,location,includeArchived,noPhotos}"
+
+	// reportPhotoSize is the largest width and height of a report photo, in
+	// pixels.
+	reportPhotoSize = 240
+)
+
+// errReportLocationNotFound is returned for a report limited to a location
+// ID that is not in the location tree.
+var errReportLocationNotFound = errors.New("location not found")
+
+// Input for the generate_insurance_report tool.
+type GenerateInsuranceReportInput struct {
+	InsuredOnly     bool   `json:"insuredOnly,omitempty" jsonschema:"Only list items marked as insured"`
+	Location        string `json:"location,omitempty" jsonschema:"Only list the items in this location and below it, by ID, name or path"`
+	IncludeArchived bool   `json:"includeArchived,omitempty" jsonschema:"Also list archived items"`
+	NoPhotos        bool   `json:"noPhotos,omitempty" jsonschema:"Leave out the photos, for a smaller report"`
+}
+
+// Output for the generate_insurance_report tool.
+type GenerateInsuranceReportOutput struct {
+	Date               string   `json:"date" jsonschema:"Date of the report"`
+	Items              int      `json:"items" jsonschema:"Number of items in the report"`
+	Locations          int      `json:"locations" jsonschema:"Number of locations with items in the report"`
+	TotalPurchasePrice float64  `json:"totalPurchasePrice" jsonschema:"Total purchase price of the items"`
+	TotalValue         float64  `json:"totalValue" jsonschema:"Total estimated value of the items"`
+	WithoutReceipt     int      `json:"withoutReceipt" jsonschema:"Number of items without a receipt attachment"`
+	HTMLURI            string   `json:"htmlUri" jsonschema:"URI of the HTML report resource"`
+	PDFURI             string   `json:"pdfUri" jsonschema:"URI of the PDF report resource"`
+	Files              []string `json:"files,omitempty" jsonschema:"Paths the report was written to, if HOMEBOX_REPORT_DIR is set"`
+}
+
+// reportItem is an item as listed in the insurance report.
+type reportItem struct {
+	ItemOut
+	Parent   string
+	Value    float64
+	Receipts []string
+	Photo    []byte
+}
+
+// PhotoURI is the photo as a data URI, so the HTML report is self-contained.
+func (i reportItem) PhotoURI() template.URL {
+	return template.URL("data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(i.Photo))
+}
+
+// reportSection is a location and its items.
+type reportSection struct {
+	Path  string
+	Depth int
+	Items []reportItem
+	// Total is the value of the items of the location and the ones below it.
+	Total float64
+}
+
+// insuranceReport is the content of an insurance report.
+type insuranceReport struct {
+	Title              string
+	Date               string
+	Currency           string
+	Filter             string
+	LocationID         string
+	LocationPath       string
+	Sections           []reportSection
+	Items              int
+	TotalPurchasePrice float64
+	TotalValue         float64
+	WithoutReceipt     int
+}
+
+// insuranceReportURI returns the URI of the report in format, html or pdf,
+// for input.
+func insuranceReportURI(format string, input GenerateInsuranceReportInput) string {
+	query := url.Values{}
+	if input.InsuredOnly {
+		query.Set("insuredOnly", "true")
+	}
+	if input.Location != "" {
+		query.Set("location", input.Location)
+	}
+	if input.IncludeArchived {
+		query.Set("includeArchived", "true")
+	}
+	if input.NoPhotos {
+		query.Set("noPhotos", "true")
+	}
+	uri := resourceScheme + "reports/insurance." + format
+	if len(query) > 0 {
+		uri += "?" + query.Encode()
+	}
+	return uri
+}
+
+// generateInsuranceReport is the implementation of the
+// "generate_insurance_report" tool. It returns the HTML and PDF reports as
+// embedded resources.
+func generateInsuranceReport(ctx context.Context, req *mcp.CallToolRequest, input GenerateInsuranceReportInput) (*mcp.CallToolResult, GenerateInsuranceReportOutput, error) {
+	report, err := buildInsuranceReport(ctx, req, input)
+	if err != nil {
+		return nil, GenerateInsuranceReportOutput{}, err
+	}
+	html, err := renderReportHTML(report)
+	if err != nil {
+		return nil, GenerateInsuranceReportOutput{}, err
+	}
+	pdf, err := renderReportPDF(report)
+	if err != nil {
+		return nil, GenerateInsuranceReportOutput{}, err
+	}
+
+	locations := 0
+	for _, section := range report.Sections {
+		if len(section.Items) > 0 {
+			locations++
+		}
+	}
+	out := GenerateInsuranceReportOutput{
+		Date:               report.Date,
+		Items:              report.Items,
+		Locations:          locations,
+		TotalPurchasePrice: roundCents(report.TotalPurchasePrice),
+		TotalValue:         roundCents(report.TotalValue),
+		WithoutReceipt:     report.WithoutReceipt,
+		HTMLURI:            insuranceReportURI("html", input),
+		PDFURI:             insuranceReportURI("pdf", input),
+	}
+	result := &mcp.CallToolResult{Content: []mcp.Content{
+		&mcp.TextContent{Text: fmt.Sprintf("Insurance report of %d items in %d locations, estimated value %s %s.", out.Items, out.Locations, report.Currency, formatMoney(report.TotalValue))},
+		&mcp.EmbeddedResource{Resource: &mcp.ResourceContents{URI: out.HTMLURI, MIMEType: "text/html", Text: string(html)}},
+		&mcp.EmbeddedResource{Resource: &mcp.ResourceContents{URI: out.PDFURI, MIMEType: "application/pdf", Blob: pdf}},
+	}}
+
+	dir := os.Getenv("HOMEBOX_REPORT_DIR")
+	if dir == "" {
+		return result, out, nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, GenerateInsuranceReportOutput{}, fmt.Errorf("failed to create report directory: %w", err)
+	}
+	for _, file := range []struct {
+		ext      string
+		mimeType string
+		data     []byte
+	}{{".html", "text/html", html}, {".pdf", "application/pdf", pdf}} {
+		path, err := filepath.Abs(filepath.Join(dir, insuranceReportFileName(report, input)+file.ext))
+		if err != nil {
+			return nil, GenerateInsuranceReportOutput{}, err
+		}
+		if err := os.WriteFile(path, file.data, 0o644); err != nil {
+			return nil, GenerateInsuranceReportOutput{}, fmt.Errorf("failed to write report: %w", err)
+		}
+		size := int64(len(file.data))
+		result.Content = append(result.Content, &mcp.ResourceLink{
+			URI:      (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String(),
+			Name:     filepath.Base(path),
+			MIMEType: file.mimeType,
+			Size:     &size,
+		})
+		out.Files = append(out.Files, path)
+	}
+	return result, out, nil
+}
+
+// insuranceReportFileName returns the name of the files a report is written
+// to, without the extension. It names the filters, so reports of the same
+// day with different filters do not overwrite each other; the location is
+// named by its path and the start of its ID, as paths need not be unique.
+func insuranceReportFileName(report insuranceReport, input GenerateInsuranceReportInput) string {
+	parts := []string{"insurance-report", report.Date}
+	if report.LocationID != "" {
+		parts = append(parts, labelFileName(report.LocationPath), report.LocationID[:min(8, len(report.LocationID))])
+	}
+	if input.InsuredOnly {
+		parts = append(parts, "insured")
+	}
+	if input.IncludeArchived {
+		parts = append(parts, "archived")
+	}
+	if input.NoPhotos {
+		parts = append(parts, "no-photos")
+	}
+	return strings.Join(parts, "-")
+}
+
+// readInsuranceReport reads an insurance report resource, generating the
+// report for the input in its query.
+func readInsuranceReport(ctx context.Context, uri string) (*mcp.ReadResourceResult, error) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Host != "reports" {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	format := strings.TrimPrefix(u.Path, "/insurance.")
+	if format != "html" && format != "pdf" {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	query := u.Query()
+	input := GenerateInsuranceReportInput{Location: query.Get("location")}
+	input.InsuredOnly, _ = strconv.ParseBool(query.Get("insuredOnly"))
+	input.IncludeArchived, _ = strconv.ParseBool(query.Get("includeArchived"))
+	input.NoPhotos, _ = strconv.ParseBool(query.Get("noPhotos"))
+	report, err := buildInsuranceReport(ctx, nil, input)
+	if errors.Is(err, errReportLocationNotFound) {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if format == "html" {
+		html, err := renderReportHTML(report)
+		if err != nil {
+			return nil, err
+		}
+		return &mcp.ReadResourceResult{Contents: []*mcp.ResourceContents{{URI: uri, MIMEType: "text/html", Text: string(html)}}}, nil
+	}
+	pdf, err := renderReportPDF(report)
+	if err != nil {
+		return nil, err
+	}
+	return &mcp.ReadResourceResult{Contents: []*mcp.ResourceContents{{URI: uri, MIMEType: "application/pdf", Blob: pdf}}}, nil
+}
+
+// buildInsuranceReport gathers the items of the report, values them and
+// groups them by location.
+func buildInsuranceReport(ctx context.Context, req *mcp.CallToolRequest, input GenerateInsuranceReportInput) (insuranceReport, error) {
+	locationID, err := resolveLocationRef(ctx, "location", input.Location, false)
+	if err != nil {
+		return insuranceReport{}, err
+	}
+	query := url.Values{}
+	if input.IncludeArchived {
+		query.Set("includeArchived", "true")
+	}
+	summaries, err := queryAllItems(ctx, query)
+	if err != nil {
+		return insuranceReport{}, err
+	}
+	items, err := readItems(ctx, req, summaries)
+	if err != nil {
+		return insuranceReport{}, err
+	}
+	forest, err := loadLocationForest(ctx)
+	if err != nil {
+		return insuranceReport{}, err
+	}
+	if locationID != "" && forest.Nodes[locationID] == nil {
+		return insuranceReport{}, fmt.Errorf("%w: %s", errReportLocationNotFound, locationID)
+	}
+	valuation, err := appraise(items, forest, ValuationInput{Months: 1})
+	if err != nil {
+		return insuranceReport{}, err
+	}
+	values := make(map[string]float64)
+	for _, v := range valuation.Items {
+		values[v.ID] = v.Value
+	}
+	names := make(map[string]string)
+	for _, item := range items {
+		names[item.ID] = item.Name
+	}
+
+	report := insuranceReport{
+		Title: "Home inventory",
+		Date:  valuation.Date,
+	}
+	if _, currency, err := getCurrency(ctx, nil, GetCurrencyInput{}); err == nil {
+		report.Currency = currency.Code
+	}
+	var filters []string
+	if input.InsuredOnly {
+		filters = append(filters, "insured items only")
+	}
+	if locationID != "" {
+		report.LocationID, report.LocationPath = locationID, forest.Nodes[locationID].Path
+		filters = append(filters, "items in "+report.LocationPath)
+	}
+	report.Filter = strings.Join(filters, ", ")
+
+	// Sections are keyed by location ID, as names need not be unique and
+	// may contain "/". Items without a known location are keyed by "".
+	sections := make(map[string]*reportSection)
+	for _, item := range items {
+		if input.InsuredOnly && !item.Insured {
+			continue
+		}
+		id := ""
+		if item.Location != nil && forest.Nodes[item.Location.ID] != nil {
+			id = item.Location.ID
+		}
+		if locationID != "" && !forest.isWithin(id, locationID) {
+			continue
+		}
+		entry := reportItem{ItemOut: item, Value: values[item.ID]}
+		if item.Parent != nil {
+			entry.Parent = cmp.Or(names[item.Parent.ID], item.Parent.Name)
+		}
+		for _, a := range item.Attachments {
+			if a.Type == "receipt" {
+				entry.Receipts = append(entry.Receipts, cmp.Or(a.Title, "receipt"))
+			}
+		}
+		if len(entry.Receipts) == 0 {
+			report.WithoutReceipt++
+		}
+		if sections[id] == nil {
+			sections[id] = &reportSection{}
+		}
+		sections[id].Items = append(sections[id].Items, entry)
+		report.Items++
+		report.TotalPurchasePrice += item.PurchasePrice
+		report.TotalValue += entry.Value
+	}
+
+	if !input.NoPhotos {
+		var all []*reportItem
+		for _, section := range sections {
+			for i := range section.Items {
+				all = append(all, &section.Items[i])
+			}
+		}
+		var mu sync.Mutex
+		done := 0
+		progress := newToolProgress(req)
+		forEachBounded(len(all), bulkConcurrency(0, len(all)), func(i int) {
+			// Items whose photo cannot be read are listed without one.
+			photo, _ := reportPhoto(ctx, all[i].ItemOut)
+			mu.Lock()
+			defer mu.Unlock()
+			all[i].Photo = photo
+			done++
+			progress.Report(ctx, float64(done), float64(len(all)), fmt.Sprintf("Read %d of %d photos", done, len(all)))
+		})
+	}
+
+	// Sections follow the location tree, with the items without a location
+	// last. Locations without items of their own get a section too when
+	// there are items below them, so every section sits under its parent.
+	// Each total includes the locations below.
+	for _, section := range sections {
+		slices.SortFunc(section.Items, func(a, b reportItem) int {
+			return cmp.Or(strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)), strings.Compare(a.ID, b.ID))
+		})
+		for _, item := range section.Items {
+			section.Total += item.Value
+		}
+	}
+	var walk func(ids []string, depth int) float64
+	walk = func(ids []string, depth int) float64 {
+		total := 0.0
+		for _, id := range ids {
+			node := forest.Nodes[id]
+			at := len(report.Sections)
+			report.Sections = append(report.Sections, reportSection{Path: node.Path, Depth: depth})
+			subtotal := walk(node.Children, depth+1)
+			if section := sections[id]; section != nil {
+				subtotal += section.Total
+				report.Sections[at].Items = section.Items
+			}
+			if sections[id] == nil && len(report.Sections) == at+1 {
+				// Nothing in or below this location is in the report.
+				report.Sections = report.Sections[:at]
+				continue
+			}
+			report.Sections[at].Total = subtotal
+			total += subtotal
+		}
+		return total
+	}
+	walk(forest.Roots, 0)
+	if section := sections[""]; section != nil {
+		report.Sections = append(report.Sections, *section)
+	}
+	return report, nil
+}
+
+// reportPhoto returns the primary photo of item, or its first photo, as a
+// JPEG no larger than reportPhotoSize. The thumbnail is used if Homebox has
+// made one, and the photo itself if the thumbnail cannot be read.
+func reportPhoto(ctx context.Context, item ItemOut) ([]byte, error) {
+	var photo *ItemAttachment
+	for i, a := range item.Attachments {
+		if a.Type == "photo" && (photo == nil || a.Primary && !photo.Primary) {
+			photo = &item.Attachments[i]
+		}
+	}
+	if photo == nil {
+		return nil, nil
+	}
+	decode := func(id string) (image.Image, error) {
+		data, _, err := getItemAttachmentFile(ctx, item.ID, id)
+		if err != nil {
+			return nil, err
+		}
+		img, _, err := image.Decode(bytes.NewReader(data))
+		return img, err
+	}
+	var img image.Image
+	if photo.Thumbnail != nil && photo.Thumbnail.ID != "" {
+		img, _ = decode(photo.Thumbnail.ID)
+	}
+	if img == nil {
+		var err error
+		if img, err = decode(photo.ID); err != nil {
+			return nil, err
+		}
+	}
+	bounds := img.Bounds()
+	if scale := float64(reportPhotoSize) / float64(max(bounds.Dx(), bounds.Dy())); scale < 1 {
+		scaled := image.NewRGBA(image.Rect(0, 0, max(int(float64(bounds.Dx())*scale), 1), max(int(float64(bounds.Dy())*scale), 1)))
+		draw.CatmullRom.Scale(scaled, scaled.Bounds(), img, bounds, draw.Over, nil)
+		img = scaled
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// formatMoney formats an amount with two decimals and thousands separators.
+func formatMoney(amount float64) string {
+	s := strconv.FormatFloat(roundCents(amount), 'f', 2, 64)
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	whole, cents, _ := strings.Cut(s, ".")
+	for i := len(whole) - 3; i > 0; i -= 3 {
+		whole = whole[:i] + "," + whole[i:]
+	}
+	return sign + whole + "." + cents
+}
+
+// reportHTML is the template of the HTML report. It has no external
+// references, so the file can be sent as it is.
+var reportHTML = template.Must(template.New("report").Funcs(template.FuncMap{
+	"money": formatMoney,
+	"date":  dateOnly,
+	"indent": func(depth int) string {
+		return fmt.Sprintf("%dem", depth*2)
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}} {{.Date}}</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; font-size: 13px; color: #222; margin: 2em; }
+h1 { margin-bottom: 0; }
+.meta { color: #666; margin-bottom: 1.5em; }
+h2 { border-bottom: 1px solid #ccc; padding-bottom: 0.2em; margin-top: 1.5em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; vertical-align: top; padding: 0.4em; border-bottom: 1px solid #eee; }
+th { background: #f4f4f4; }
+td.num, th.num { text-align: right; white-space: nowrap; }
+img { max-width: 96px; max-height: 96px; }
+.missing { color: #b00; }
+.summary td { font-weight: bold; }
+@media print { h2 { page-break-after: avoid; } tr { page-break-inside: avoid; } }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<div class="meta">{{.Date}}{{if .Filter}} &middot; {{.Filter}}{{end}}{{if .Currency}} &middot; Amounts in {{.Currency}}{{end}}</div>
+<table class="summary">
+<tr><td>Items</td><td class="num">{{.Items}}</td></tr>
+<tr><td>Purchase price</td><td class="num">{{money .TotalPurchasePrice}}</td></tr>
+<tr><td>Estimated value</td><td class="num">{{money .TotalValue}}</td></tr>
+<tr><td>Items without a receipt</td><td class="num">{{.WithoutReceipt}}</td></tr>
+</table>
+{{range .Sections}}
+<h2 style="margin-left: {{indent .Depth}}">{{if .Path}}{{.Path}}{{else}}No location{{end}} <small>({{money .Total}})</small></h2>
+{{if .Items}}<table>
+<tr><th>Photo</th><th>Item</th><th>Identification</th><th>Purchase</th><th>Receipt</th><th class="num">Price</th><th class="num">Value</th></tr>
+{{range .Items}}<tr>
+<td>{{if .Photo}}<img src="{{.PhotoURI}}" alt="{{.Name}}">{{end}}</td>
+<td><strong>{{.Name}}</strong>{{if .AssetID}}<br>Asset {{.AssetID}}{{end}}{{if gt .Quantity 1}}<br>Quantity {{.Quantity}}{{end}}{{if .Parent}}<br>Part of {{.Parent}}{{end}}{{if .Description}}<br>{{.Description}}{{end}}</td>
+<td>{{if .Manufacturer}}{{.Manufacturer}}<br>{{end}}{{if .ModelNumber}}Model {{.ModelNumber}}<br>{{end}}{{if .SerialNumber}}Serial {{.SerialNumber}}{{else}}<span class="missing">No serial number</span>{{end}}</td>
+<td>{{with date .PurchaseTime}}{{.}}{{end}}{{if .PurchaseFrom}}<br>{{.PurchaseFrom}}{{end}}</td>
+<td>{{if .Receipts}}{{range $i, $r := .Receipts}}{{if $i}}<br>{{end}}{{$r}}{{end}}{{else}}<span class="missing">None</span>{{end}}</td>
+<td class="num">{{money .PurchasePrice}}</td>
+<td class="num">{{money .Value}}</td>
+</tr>
+{{end}}</table>{{end}}
+{{end}}
+</body>
+</html>
+`))
+
+// renderReportHTML renders the report as a self-contained HTML document.
+func renderReportHTML(report insuranceReport) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := reportHTML.Execute(&buf, report); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// renderReportPDF renders the report as an A4 PDF with the same content as
+// the HTML report.
+func renderReportPDF(report insuranceReport) ([]byte, error) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	// The core fonts are Windows-1252, so other characters are replaced.
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetTitle(report.Title+" "+report.Date, true)
+	pdf.SetCreator("homebox-mcp-server", true)
+	pdf.SetMargins(15, 15, 15)
+	pdf.SetAutoPageBreak(true, 15)
+	pdf.AliasNbPages("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.SetTextColor(120, 120, 120)
+		pdf.CellFormat(0, 5, tr(fmt.Sprintf("%s %s - page %d of {nb}", report.Title, report.Date, pdf.PageNo())), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+	width, height := pdf.GetPageSize()
+	left, _, right, bottom := pdf.GetMargins()
+	contentWidth := width - left - right
+
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.CellFormat(0, 9, tr(report.Title), "", 1, "L", false, 0, "")
+	meta := report.Date
+	if report.Filter != "" {
+		meta += " - " + report.Filter
+	}
+	if report.Currency != "" {
+		meta += " - Amounts in " + report.Currency
+	}
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.SetTextColor(100, 100, 100)
+	pdf.CellFormat(0, 6, tr(meta), "", 1, "L", false, 0, "")
+	pdf.SetTextColor(0, 0, 0)
+	pdf.Ln(2)
+	for _, row := range [][2]string{
+		{"Items", strconv.Itoa(report.Items)},
+		{"Purchase price", formatMoney(report.TotalPurchasePrice)},
+		{"Estimated value", formatMoney(report.TotalValue)},
+		{"Items without a receipt", strconv.Itoa(report.WithoutReceipt)},
+	} {
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.CellFormat(60, 6, tr(row[0]), "", 0, "L", false, 0, "")
+		pdf.CellFormat(40, 6, tr(row[1]), "", 1, "R", false, 0, "")
+	}
+
+	const photoWidth, amountWidth, gap = 24.0, 28.0, 3.0
+	textWidth := contentWidth - photoWidth - amountWidth - 2*gap
+	for s, section := range report.Sections {
+		if pdf.GetY()+20 > height-bottom {
+			pdf.AddPage()
+		}
+		pdf.Ln(4)
+		title := cmp.Or(section.Path, "No location")
+		indent := float64(section.Depth) * 4
+		pdf.SetX(left + indent)
+		pdf.SetFont("Helvetica", "B", 13)
+		pdf.CellFormat(contentWidth-indent-amountWidth, 8, tr(title), "B", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.CellFormat(amountWidth, 8, tr(formatMoney(section.Total)), "B", 1, "R", false, 0, "")
+		pdf.Ln(1)
+
+		for i, item := range section.Items {
+			lines := reportItemLines(item)
+			var wrapped []string
+			pdf.SetFont("Helvetica", "", 9)
+			for _, line := range lines[1:] {
+				for _, part := range pdf.SplitLines([]byte(tr(line)), textWidth) {
+					wrapped = append(wrapped, string(part))
+				}
+			}
+			rowHeight := max(5+4*float64(len(wrapped)), photoWidth) + 2
+			if pdf.GetY()+rowHeight > height-bottom {
+				pdf.AddPage()
+			}
+			top := pdf.GetY()
+			if len(item.Photo) > 0 {
+				name := fmt.Sprintf("photo-%d-%d", s, i)
+				options := gofpdf.ImageOptions{ImageType: "JPG"}
+				pdf.RegisterImageOptionsReader(name, options, bytes.NewReader(item.Photo))
+				// Portrait photos are fitted to the height of the box instead.
+				w, h := photoWidth, 0.0
+				if config, _, err := image.DecodeConfig(bytes.NewReader(item.Photo)); err == nil && config.Height > config.Width {
+					w, h = 0, photoWidth
+				}
+				pdf.ImageOptions(name, left, top, w, h, false, options, 0, "")
+			}
+			x := left + photoWidth + gap
+			pdf.SetXY(x, top)
+			pdf.SetFont("Helvetica", "B", 10)
+			// Long names are cut so they do not run into the value, ending in a
+			// Windows-1252 ellipsis.
+			title := tr(lines[0])
+			for pdf.GetStringWidth(title) > textWidth && len(title) > 1 {
+				title = title[:len(title)-2] + "\x85"
+			}
+			pdf.CellFormat(textWidth, 5, title, "", 0, "L", false, 0, "")
+			pdf.CellFormat(amountWidth+gap, 5, tr(formatMoney(item.Value)), "", 1, "R", false, 0, "")
+			pdf.SetFont("Helvetica", "", 9)
+			for _, line := range wrapped {
+				pdf.SetX(x)
+				pdf.CellFormat(textWidth, 4, line, "", 1, "L", false, 0, "")
+			}
+			pdf.SetY(top + rowHeight)
+			pdf.SetDrawColor(220, 220, 220)
+			pdf.Line(left, pdf.GetY()-1, width-right, pdf.GetY()-1)
+			pdf.SetDrawColor(0, 0, 0)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to render the PDF report: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// reportItemLines returns the name of an item and the lines describing it
+// in the PDF report.
+func reportItemLines(item reportItem) []string {
+	name := item.Name
+	if item.AssetID != "" {
+		name += " (asset " + item.AssetID + ")"
+	}
+	lines := []string{name}
+	var identity []string
+	if item.Manufacturer != "" {
+		identity = append(identity, item.Manufacturer)
+	}
+	if item.ModelNumber != "" {
+		identity = append(identity, "model "+item.ModelNumber)
+	}
+	if item.SerialNumber != "" {
+		identity = append(identity, "serial "+item.SerialNumber)
+	} else {
+		identity = append(identity, "no serial number")
+	}
+	lines = append(lines, strings.Join(identity, ", "))
+	purchase := "Bought"
+	if date := dateOnly(item.PurchaseTime); date != "" {
+		purchase += " " + date
+	}
+	if item.PurchaseFrom != "" {
+		purchase += " from " + item.PurchaseFrom
+	}
+	lines = append(lines, purchase+" for "+formatMoney(item.PurchasePrice))
+	if len(item.Receipts) > 0 {
+		lines = append(lines, "Receipt: "+strings.Join(item.Receipts, ", "))
+	} else {
+		lines = append(lines, "No receipt")
+	}
+	if item.Quantity > 1 {
+		lines = append(lines, fmt.Sprintf("Quantity %d", item.Quantity))
+	}
+	if item.Parent != "" {
+		lines = append(lines, "Part of "+item.Parent)
+	}
+	return lines
+}
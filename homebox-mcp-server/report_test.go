@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatMoney(t *testing.T) {
+	assert.Equal(t, "0.00", formatMoney(0))
+	assert.Equal(t, "999.50", formatMoney(999.5))
+	assert.Equal(t, "1,234,567.89", formatMoney(1234567.891))
+	assert.Equal(t, "-1,000.00", formatMoney(-1000))
+}
+
+func TestGenerateInsuranceReport(t *testing.T) {
+	const garageTreeID = "00000000-0000-4000-9000-000000000001"
+	var photo bytes.Buffer
+	png.Encode(&photo, image.NewRGBA(image.Rect(0, 0, 800, 400)))
+
+	store := newFakeItemStore()
+	store.items[item1ID] = ItemOut{
+		ID: item1ID, Name: "Lawn mower", AssetID: "000-001", Location: &LocationSummary{ID: garageTreeID}, Insured: true,
+		Manufacturer: "Husqvarna", ModelNumber: "LC 140", SerialNumber: "SN-42", PurchaseFrom: "Hardware & Co",
+		PurchasePrice: 400, PurchaseTime: "2025-06-01", Quantity: 1,
+		Attachments: []ItemAttachment{
+			{ID: "att-photo", Type: "photo", Primary: true},
+			{ID: "att-receipt", Type: "receipt", Title: "invoice.pdf"},
+		},
+	}
+	store.items[item2ID] = ItemOut{ID: item2ID, Name: "Grass catcher", Location: &LocationSummary{ID: garageTreeID}, Parent: &ItemSummary{ID: item1ID}, PurchasePrice: 50}
+	store.items[item3ID] = ItemOut{ID: item3ID, Name: "Bike", PurchasePrice: 900, Quantity: 2}
+	store.files["att-photo"] = photo.String()
+	homebox := httptest.NewServer(store)
+	defer homebox.Close()
+	os.Setenv("HOMEBOX_URL", homebox.URL)
+	os.Setenv("HOMEBOX_TOKEN", "test-token")
+	inventory.Invalidate()
+	defer inventory.Invalidate()
+	ctx := context.Background()
+
+	result, out, err := generateInsuranceReport(ctx, nil, GenerateInsuranceReportInput{})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, out.Items)
+	assert.Equal(t, 2, out.Locations)
+	assert.Equal(t, 1350.0, out.TotalPurchasePrice)
+	assert.Equal(t, 2, out.WithoutReceipt)
+	assert.Equal(t, "homebox://reports/insurance.html", out.HTMLURI)
+	assert.Equal(t, "homebox://reports/insurance.pdf", out.PDFURI)
+	assert.Empty(t, out.Files)
+
+	if assert.Len(t, result.Content, 3) {
+		html := result.Content[1].(*mcp.EmbeddedResource).Resource
+		assert.Equal(t, "text/html", html.MIMEType)
+		assert.Contains(t, html.Text, `<img src="data:image/jpeg;base64,`)
+		assert.Contains(t, html.Text, "Serial SN-42")
+		assert.Contains(t, html.Text, "Hardware &amp; Co")
+		assert.Contains(t, html.Text, "invoice.pdf")
+		assert.Contains(t, html.Text, "Part of Lawn mower")
+		assert.Contains(t, html.Text, "Quantity 2")
+		assert.Less(t, strings.Index(html.Text, "Garage"), strings.Index(html.Text, "No location"))
+		assert.NotContains(t, html.Text, "http")
+
+		pdf := result.Content[2].(*mcp.EmbeddedResource).Resource
+		assert.Equal(t, "application/pdf", pdf.MIMEType)
+		assert.True(t, bytes.HasPrefix(pdf.Blob, []byte("%PDF-")))
+	}
+
+	// The photo is scaled down to fit the report.
+	_, report, err := valuation(ctx, nil, ValuationInput{})
+	assert.NoError(t, err)
+	data, err := reportPhoto(ctx, store.items[item1ID])
+	assert.NoError(t, err)
+	scaled, format, err := image.Decode(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.Equal(t, "jpeg", format)
+	assert.Equal(t, image.Pt(reportPhotoSize, reportPhotoSize/2), scaled.Bounds().Size())
+	assert.Equal(t, report.TotalValue, out.TotalValue)
+
+	// A thumbnail that cannot be read or decoded falls back to the photo.
+	store.files["att-broken"] = "not an image"
+	for _, thumbnail := range []string{"att-missing", "att-broken"} {
+		item := store.items[item1ID]
+		item.Attachments = []ItemAttachment{{ID: "att-photo", Type: "photo", Thumbnail: &AttachmentThumbnail{ID: thumbnail}}}
+		data, err := reportPhoto(ctx, item)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, data)
+	}
+
+	t.Setenv("HOMEBOX_REPORT_DIR", t.TempDir())
+	result, out, err = generateInsuranceReport(ctx, nil, GenerateInsuranceReportInput{InsuredOnly: true, NoPhotos: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, out.Items)
+	assert.Equal(t, "homebox://reports/insurance.pdf?insuredOnly=true&noPhotos=true", out.PDFURI)
+	if assert.Len(t, out.Files, 2) {
+		assert.Equal(t, ".html", filepath.Ext(out.Files[0]))
+		written, err := os.ReadFile(out.Files[1])
+		assert.NoError(t, err)
+		assert.True(t, bytes.HasPrefix(written, []byte("%PDF-")))
+	}
+	assert.Len(t, result.Content, 5)
+	assert.NotContains(t, result.Content[1].(*mcp.EmbeddedResource).Resource.Text, "<img")
+
+	// Reports with other filters are written to other files.
+	_, located, err := generateInsuranceReport(ctx, nil, GenerateInsuranceReportInput{Location: "Garage", NoPhotos: true})
+	assert.NoError(t, err)
+	if assert.Len(t, located.Files, 2) && assert.Len(t, out.Files, 2) {
+		assert.Equal(t, "insurance-report-"+out.Date+"-insured-no-photos.pdf", filepath.Base(out.Files[1]))
+		assert.Equal(t, "insurance-report-"+out.Date+"-Garage-00000000-no-photos.pdf", filepath.Base(located.Files[1]))
+		assert.FileExists(t, out.Files[1])
+	}
+
+	// The resources generate the report of the input in their query.
+	res, err := readResource(ctx, &mcp.ReadResourceRequest{Params: &mcp.ReadResourceParams{URI: "homebox://reports/insurance.html?location=Garage"}})
+	assert.NoError(t, err)
+	assert.Contains(t, res.Contents[0].Text, "items in Garage")
+	assert.NotContains(t, res.Contents[0].Text, "Bike")
+	res, err = readResource(ctx, &mcp.ReadResourceRequest{Params: &mcp.ReadResourceParams{URI: out.PDFURI}})
+	assert.NoError(t, err)
+	assert.Equal(t, "application/pdf", res.Contents[0].MIMEType)
+	_, err = readResource(ctx, &mcp.ReadResourceRequest{Params: &mcp.ReadResourceParams{URI: "homebox://reports/insurance.docx"}})
+	assert.Error(t, err)
+}
+
+func TestInsuranceReportSections(t *testing.T) {
+	const (
+		garageID = "00000000-0000-4000-b000-000000000001"
+		shelf1ID = "00000000-0000-4000-b000-000000000002"
+		binID    = "00000000-0000-4000-b000-000000000003"
+		shelf2ID = "00000000-0000-4000-b000-000000000004"
+		inOutID  = "00000000-0000-4000-b000-000000000005"
+		atticID  = "00000000-0000-4000-b000-000000000006"
+	)
+	locations := newFakeLocationStore(
+		&fakeLocation{ID: garageID, Name: "Garage"},
+		&fakeLocation{ID: shelf1ID, Name: "Shelf", ParentID: garageID},
+		&fakeLocation{ID: binID, Name: "Bin", ParentID: shelf1ID},
+		&fakeLocation{ID: shelf2ID, Name: "Shelf", ParentID: garageID},
+		&fakeLocation{ID: inOutID, Name: "In/Out"},
+		&fakeLocation{ID: atticID, Name: "Attic"},
+	)
+	items := newFakeItemStore()
+	items.items[item1ID] = ItemOut{ID: item1ID, Name: "Drill", Location: &LocationSummary{ID: binID}, PurchasePrice: 100}
+	items.items[item2ID] = ItemOut{ID: item2ID, Name: "Saw", Location: &LocationSummary{ID: shelf2ID}, PurchasePrice: 50}
+	items.items[item3ID] = ItemOut{ID: item3ID, Name: "Tray", Location: &LocationSummary{ID: inOutID}, PurchasePrice: 10}
+	items.items["00000000-0000-4000-8000-000000000004"] = ItemOut{ID: "00000000-0000-4000-8000-000000000004", Name: "Sock", PurchasePrice: 5}
+	mux := http.NewServeMux()
+	mux.Handle("/api/v1/locations", locations)
+	mux.Handle("/api/v1/locations/", locations)
+	mux.Handle("/", items)
+	homebox := httptest.NewServer(mux)
+	defer homebox.Close()
+	os.Setenv("HOMEBOX_URL", homebox.URL)
+	os.Setenv("HOMEBOX_TOKEN", "test-token")
+	t.Setenv("HOMEBOX_DEPRECIATION_RULES", "")
+	inventory.Invalidate()
+	defer inventory.Invalidate()
+
+	// The shelves share a path and a name contains "/", but the sections
+	// follow the location IDs. The first shelf has no items of its own and
+	// still heads the bin below it; the empty attic is left out.
+	report, err := buildInsuranceReport(context.Background(), nil, GenerateInsuranceReportInput{NoPhotos: true})
+	assert.NoError(t, err)
+	type section struct {
+		path         string
+		depth, items int
+		total        float64
+	}
+	var got []section
+	for _, s := range report.Sections {
+		got = append(got, section{s.Path, s.Depth, len(s.Items), s.Total})
+	}
+	if assert.Len(t, got, 6) {
+		assert.Equal(t, section{"Garage", 0, 0, 150}, got[0])
+		assert.ElementsMatch(t, []section{{"Garage/Shelf", 1, 0, 100}, {"Garage/Shelf/Bin", 2, 1, 100}, {"Garage/Shelf", 1, 1, 50}}, got[1:4])
+		bin := slices.Index(got, section{"Garage/Shelf/Bin", 2, 1, 100})
+		assert.Equal(t, section{"Garage/Shelf", 1, 0, 100}, got[bin-1])
+		assert.Equal(t, []section{{"In/Out", 0, 1, 10}, {"", 0, 1, 5}}, got[4:])
+	}
+
+	_, out, err := generateInsuranceReport(context.Background(), nil, GenerateInsuranceReportInput{NoPhotos: true, Location: shelf1ID})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, out.Items)
+	assert.Equal(t, 1, out.Locations)
+}
+
+func TestInsuranceReportUnknownLocation(t *testing.T) {
+	const unknownID = "00000000-0000-4000-b000-0000000000ff"
+	homebox := httptest.NewServer(newFakeItemStore())
+	defer homebox.Close()
+	os.Setenv("HOMEBOX_URL", homebox.URL)
+	os.Setenv("HOMEBOX_TOKEN", "test-token")
+	inventory.Invalidate()
+	defer inventory.Invalidate()
+	ctx := context.Background()
+
+	_, _, err := generateInsuranceReport(ctx, nil, GenerateInsuranceReportInput{Location: unknownID, NoPhotos: true})
+	assert.ErrorContains(t, err, "location not found: "+unknownID)
+	_, err = readResource(ctx, &mcp.ReadResourceRequest{Params: &mcp.ReadResourceParams{URI: "homebox://reports/insurance.pdf?location=" + unknownID}})
+	assert.ErrorContains(t, err, "Resource not found")
+}
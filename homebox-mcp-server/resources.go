@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	resourceScheme = "homebox://"
+
+	exportResourceURI = resourceScheme + "export/items.csv"
+
+	// resourcePageSize is the number of items listed per resources/list page.
+	resourcePageSize = 100
+)
+
+// exportResource is the full CSV export of the inventory.
+var exportResource = &mcp.Resource{
+	URI:         exportResourceURI,
+	Name:        "items.csv",
+	Description: "CSV export of every item in the Homebox inventory.",
+	MIMEType:    "text/csv",
+}
+
+// registerResources registers the inventory resource templates and the
+// export resource, and pages resources/list through the whole inventory.
+func registerResources(server *mcp.Server) {
+	server.AddResource(exportResource, readResource)
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: resourceScheme + "items/{id}",
+		Name:        "item",
+		Description: "A Homebox item, as returned by get_item.",
+		MIMEType:    "application/json",
+	}, readResource)
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: resourceScheme + "items/{id}/attachments/{attachmentId}",
+		Name:        "item attachment",
+		Description: "The file content of an item attachment.",
+	}, readResource)
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: resourceScheme + "locations/{id}",
+		Name:        "location",
+		Description: "A Homebox location, as returned by get_location.",
+		MIMEType:    "application/json",
+	}, readResource)
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: resourceScheme + "labels/{id}",
+		Name:        "label",
+		Description: "A Homebox label, as returned by get_label.",
+		MIMEType:    "application/json",
+	}, readResource)
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: insuranceReportTemplate,
+		Name:        "insurance report",
+		Description: "The insurance report of generate_insurance_report, as html or pdf.",
+	}, readResource)
+
+	server.AddReceivingMiddleware(listResourcesMiddleware())
+}
+
+// itemResourceURI and friends build the URI of a single inventory resource.
+func itemResourceURI(id string) string     { return resourceScheme + "items/" + id }
+func locationResourceURI(id string) string { return resourceScheme + "locations/" + id }
+func labelResourceURI(id string) string    { return resourceScheme + "labels/" + id }
+
+// readResource is the handler for every homebox:// resource.
+func readResource(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	uri := req.Params.URI
+	if uri == exportResourceURI {
+		_, out, err := exportItems(ctx, nil, ExportItemsInput{})
+		if err != nil {
+			return nil, err
+		}
+		return &mcp.ReadResourceResult{Contents: []*mcp.ResourceContents{{URI: uri, MIMEType: "text/csv", Text: out.CSVData}}}, nil
+	}
+	if strings.HasPrefix(uri, resourceScheme+"reports/") {
+		return readInsuranceReport(ctx, uri)
+	}
+
+	var (
+		out any
+		err error
+	)
+	segments := strings.Split(strings.TrimPrefix(uri, resourceScheme), "/")
+	if len(segments) >= 2 && segments[0] == "items" && assetIDPattern.MatchString(segments[1]) {
+		if segments[1], err = resolveAssetID(ctx, segments[1]); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case len(segments) == 2 && segments[0] == "items":
+		_, out, err = getItem(ctx, nil, GetItemInput{ID: segments[1]})
+	case len(segments) == 4 && segments[0] == "items" && segments[2] == "attachments":
+		data, mimeType, err := getItemAttachmentFile(ctx, segments[1], segments[3])
+		if err != nil {
+			return nil, err
+		}
+		return &mcp.ReadResourceResult{Contents: []*mcp.ResourceContents{{URI: uri, MIMEType: mimeType, Blob: data}}}, nil
+	case len(segments) == 2 && segments[0] == "locations":
+		_, out, err = getLocation(ctx, nil, GetLocationInput{ID: segments[1]})
+	case len(segments) == 2 && segments[0] == "labels":
+		_, out, err = getLabel(ctx, nil, GetLabelInput{ID: segments[1]})
+	default:
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(out)
+	if err != nil {
+		return nil, err
+	}
+	return &mcp.ReadResourceResult{Contents: []*mcp.ResourceContents{{URI: uri, MIMEType: "application/json", Text: string(data)}}}, nil
+}
+
+// listResourcesMiddleware answers resources/list with the inventory itself:
+// the export and every location on the first page, then every label, then
+// the items one Homebox page at a time.
+func listResourcesMiddleware() mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			listReq, ok := req.(*mcp.ListResourcesRequest)
+			if method != "resources/list" || !ok {
+				return next(ctx, method, req)
+			}
+			cursor := ""
+			if listReq.Params != nil {
+				cursor = listReq.Params.Cursor
+			}
+			return listResourcesPage(ctx, cursor)
+		}
+	}
+}
+
+// listResourcesPage returns the page of resources starting at cursor.
+func listResourcesPage(ctx context.Context, cursor string) (*mcp.ListResourcesResult, error) {
+	section, page, err := decodeResourceCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &mcp.ListResourcesResult{Resources: []*mcp.Resource{}}
+	switch section {
+	case "locations":
+		result.Resources = append(result.Resources, exportResource)
+		_, out, err := getLocations(ctx, nil, GetLocationsInput{})
+		if err != nil {
+			return nil, err
+		}
+		for _, loc := range out.Locations {
+			result.Resources = append(result.Resources, &mcp.Resource{
+				URI:         locationResourceURI(loc.ID),
+				Name:        loc.Name,
+				Description: fmt.Sprintf("Location with %d items.", loc.ItemCount),
+				MIMEType:    "application/json",
+			})
+		}
+		result.NextCursor = encodeResourceCursor("labels", 0)
+	case "labels":
+		_, out, err := getLabels(ctx, nil, GetLabelsInput{})
+		if err != nil {
+			return nil, err
+		}
+		for _, label := range out.Labels {
+			result.Resources = append(result.Resources, &mcp.Resource{
+				URI:         labelResourceURI(label.ID),
+				Name:        label.Name,
+				Description: label.Description,
+				MIMEType:    "application/json",
+			})
+		}
+		result.NextCursor = encodeResourceCursor("items", 1)
+	case "items":
+		out, err := queryItems(ctx, url.Values{
+			"page":     {strconv.Itoa(page)},
+			"pageSize": {strconv.Itoa(resourcePageSize)},
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range out.Items {
+			res := &mcp.Resource{
+				URI:      itemResourceURI(item.ID),
+				Name:     item.Name,
+				MIMEType: "application/json",
+			}
+			if item.Location != nil {
+				res.Description = "Item in " + item.Location.Name + "."
+			}
+			result.Resources = append(result.Resources, res)
+		}
+		if page*resourcePageSize < out.Total && len(out.Items) > 0 {
+			result.NextCursor = encodeResourceCursor("items", page+1)
+		}
+	}
+	return result, nil
+}
+
+// encodeResourceCursor builds an opaque resources/list cursor.
+func encodeResourceCursor(section string, page int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(section + ":" + strconv.Itoa(page)))
+}
+
+// decodeResourceCursor parses a cursor from encodeResourceCursor. The empty
+// cursor is the first page.
+func decodeResourceCursor(cursor string) (string, int, error) {
+	if cursor == "" {
+		return "locations", 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	section, pageStr, ok := strings.Cut(string(raw), ":")
+	page, err := strconv.Atoi(pageStr)
+	if !ok || err != nil {
+		return "", 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	switch section {
+	case "locations", "labels", "items":
+		return section, page, nil
+	}
+	return "", 0, fmt.Errorf("invalid cursor %q", cursor)
+}
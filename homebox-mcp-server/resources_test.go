@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResources(t *testing.T) {
+	homebox := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/locations":
+			w.Write([]byte(`[{"id":"loc1","name":"Garage","itemCount":1}]`))
+		case "/api/v1/labels":
+			w.Write([]byte(`[{"id":"lab1","name":"Tools"}]`))
+		case "/api/v1/items":
+			assert.Equal(t, "1", r.URL.Query().Get("page"))
+			w.Write([]byte(`{"items":[{"id":"item1","name":"Drill","location":{"id":"loc1","name":"Garage"}}],"page":1,"pageSize":100,"total":1}`))
+		case "/api/v1/labels/lab1":
+			w.Write([]byte(`{"id":"lab1","name":"Tools"}`))
+		case "/api/v1/items/item1/attachments/att1":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("png data"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer homebox.Close()
+
+	os.Setenv("HOMEBOX_URL", homebox.URL)
+	os.Setenv("HOMEBOX_TOKEN", "test-token")
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	registerResources(server)
+
+	ctx := context.Background()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	_, err := server.Connect(ctx, serverTransport, nil)
+	assert.NoError(t, err)
+	session, err := mcp.NewClient(&mcp.Implementation{Name: "client"}, nil).Connect(ctx, clientTransport, nil)
+	assert.NoError(t, err)
+	defer session.Close()
+
+	var uris []string
+	for res, err := range session.Resources(ctx, nil) {
+		assert.NoError(t, err)
+		uris = append(uris, res.URI)
+	}
+	assert.Equal(t, []string{exportResourceURI, "homebox://locations/loc1", "homebox://labels/lab1", "homebox://items/item1"}, uris)
+
+	label, err := session.ReadResource(ctx, &mcp.ReadResourceParams{URI: "homebox://labels/lab1"})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"id":"lab1","name":"Tools","description":"","color":"","createdAt":"","updatedAt":""}`, label.Contents[0].Text)
+
+	attachment, err := session.ReadResource(ctx, &mcp.ReadResourceParams{URI: "homebox://items/item1/attachments/att1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", attachment.Contents[0].MIMEType)
+	assert.Equal(t, []byte("png data"), attachment.Contents[0].Blob)
+}
@@ -0,0 +1,585 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// defaultScheduleInterval is how often maintenance schedules are checked for
+// completed entries when HOMEBOX_SCHEDULE_INTERVAL is not set.
+const defaultScheduleInterval = 15 * time.Minute
+
+// Statuses of a maintenance schedule.
+const (
+	scheduleActive   = "active"
+	schedulePaused   = "paused"
+	scheduleFinished = "finished"
+)
+
+// recurrence is an RRULE limited to FREQ (DAILY, WEEKLY, MONTHLY or
+// YEARLY), INTERVAL, COUNT and UNTIL.
+type recurrence struct {
+	freq     string
+	interval int
+	count    int
+	until    string
+}
+
+// parseRecurrence parses an RRULE such as "FREQ=DAILY;INTERVAL=90". The
+// "RRULE:" prefix is optional.
+func parseRecurrence(rule string) (recurrence, error) {
+	r := recurrence{interval: 1}
+	rule = strings.TrimSpace(rule)
+	if len(rule) >= 6 && strings.EqualFold(rule[:6], "RRULE:") {
+		rule = rule[6:]
+	}
+	for _, part := range strings.Split(rule, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return recurrence{}, fmt.Errorf("invalid recurrence part %q, expected KEY=VALUE", part)
+		}
+		value = strings.ToUpper(strings.TrimSpace(value))
+		switch strings.ToUpper(strings.TrimSpace(key)) {
+		case "FREQ":
+			if !slices.Contains([]string{"DAILY", "WEEKLY", "MONTHLY", "YEARLY"}, value) {
+				return recurrence{}, fmt.Errorf("unsupported recurrence FREQ %q, expected DAILY, WEEKLY, MONTHLY or YEARLY", value)
+			}
+			r.freq = value
+		case "INTERVAL", "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return recurrence{}, fmt.Errorf("recurrence %s must be a positive number", strings.ToUpper(key))
+			}
+			if strings.EqualFold(key, "INTERVAL") {
+				r.interval = n
+			} else {
+				r.count = n
+			}
+		case "UNTIL":
+			until, err := parseUntil(value)
+			if err != nil {
+				return recurrence{}, err
+			}
+			r.until = until
+		default:
+			return recurrence{}, fmt.Errorf("unsupported recurrence part %q", key)
+		}
+	}
+	if r.freq == "" {
+		return recurrence{}, fmt.Errorf("recurrence needs a FREQ")
+	}
+	return r, nil
+}
+
+// parseUntil parses the UNTIL of an RRULE, a date such as 20261231 or
+// 20261231T000000Z, or YYYY-MM-DD.
+func parseUntil(value string) (string, error) {
+	for _, layout := range []string{"20060102", "20060102T150405Z", time.DateOnly} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.Format(time.DateOnly), nil
+		}
+	}
+	return "", fmt.Errorf("invalid recurrence UNTIL %q", value)
+}
+
+// next returns the date one interval after date, both YYYY-MM-DD.
+func (r recurrence) next(date string) string {
+	t, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		return ""
+	}
+	switch r.freq {
+	case "DAILY":
+		t = t.AddDate(0, 0, r.interval)
+	case "WEEKLY":
+		t = t.AddDate(0, 0, 7*r.interval)
+	case "MONTHLY":
+		t = t.AddDate(0, r.interval, 0)
+	case "YEARLY":
+		t = t.AddDate(r.interval, 0, 0)
+	}
+	return t.Format(time.DateOnly)
+}
+
+// MaintenanceSchedule is a recurring maintenance task. The server keeps one
+// scheduled Homebox maintenance entry for it, and schedules the next one
+// once that entry is completed.
+type MaintenanceSchedule struct {
+	ID            string  `json:"id" jsonschema:"ID of the schedule"`
+	ItemID        string  `json:"itemId" jsonschema:"ID of the item"`
+	ItemName      string  `json:"itemName" jsonschema:"Name of the item"`
+	Task          string  `json:"task" jsonschema:"Name of the maintenance entries"`
+	Description   string  `json:"description,omitempty" jsonschema:"Description of the maintenance entries"`
+	Cost          string  `json:"cost,omitempty" jsonschema:"Estimated cost of the maintenance entries"`
+	Recurrence    string  `json:"recurrence,omitempty" jsonschema:"RRULE of the schedule"`
+	UsageField    string  `json:"usageField,omitempty" jsonschema:"Custom item field that counts usage, such as running hours"`
+	UsageInterval float64 `json:"usageInterval,omitempty" jsonschema:"Usage between services"`
+	LastUsage     float64 `json:"lastUsage,omitempty" jsonschema:"Usage when the schedule was created or last completed"`
+	Status        string  `json:"status" jsonschema:"active, paused or finished"`
+	NextDate      string  `json:"nextDate,omitempty" jsonschema:"Date the next entry is due"`
+	EntryID       string  `json:"entryId,omitempty" jsonschema:"ID of the scheduled Homebox maintenance entry"`
+	Occurrences   int     `json:"occurrences" jsonschema:"Number of entries scheduled so far"`
+	LastCompleted string  `json:"lastCompleted,omitempty" jsonschema:"Date the last entry was completed"`
+	LastError     string  `json:"lastError,omitempty" jsonschema:"Why the last check of the schedule failed"`
+	CreatedAt     string  `json:"createdAt" jsonschema:"When the schedule was created"`
+}
+
+// scheduleStore keeps the maintenance schedules in a JSON file, since
+// Homebox has no recurring maintenance.
+type scheduleStore struct {
+	mu sync.Mutex
+}
+
+// schedules is the store shared by every session.
+var schedules = &scheduleStore{}
+
+// scheduleFile returns the path of the schedule file: HOMEBOX_SCHEDULE_FILE,
+// or homebox-mcp-server/schedules.json in the user's config directory.
+func scheduleFile() (string, error) {
+	if path := os.Getenv("HOMEBOX_SCHEDULE_FILE"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot locate the schedule file, set HOMEBOX_SCHEDULE_FILE: %w", err)
+	}
+	return filepath.Join(dir, "homebox-mcp-server", "schedules.json"), nil
+}
+
+// load reads the schedules. A missing file has none.
+func (s *scheduleStore) load() ([]MaintenanceSchedule, error) {
+	path, err := scheduleFile()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return []MaintenanceSchedule{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []MaintenanceSchedule
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return list, nil
+}
+
+// save replaces the schedule file, through a temporary file so that a crash
+// cannot leave it half written.
+func (s *scheduleStore) save(list []MaintenanceSchedule) error {
+	path, err := scheduleFile()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// list returns the schedules.
+func (s *scheduleStore) list() ([]MaintenanceSchedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load()
+}
+
+// update loads the schedules, lets fn change them and saves the result.
+func (s *scheduleStore) update(fn func([]MaintenanceSchedule) ([]MaintenanceSchedule, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list, err := s.load()
+	if err != nil {
+		return err
+	}
+	if list, err = fn(list); err != nil {
+		return err
+	}
+	return s.save(list)
+}
+
+// sync checks every schedule against Homebox, scheduling the next entry of
+// those whose entry was completed.
+func (s *scheduleStore) sync(ctx context.Context) error {
+	if list, err := s.list(); err != nil || len(list) == 0 {
+		return err
+	}
+	today := time.Now().Format(time.DateOnly)
+	return s.update(func(list []MaintenanceSchedule) ([]MaintenanceSchedule, error) {
+		for i := range list {
+			list[i].LastError = ""
+			if err := syncSchedule(ctx, &list[i], today); err != nil {
+				list[i].LastError = err.Error()
+			}
+		}
+		return list, nil
+	})
+}
+
+// run syncs the schedules every interval until ctx is done.
+func (s *scheduleStore) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.sync(ctx); err != nil {
+				log.Printf("Maintenance schedule error: %v", err)
+			}
+		}
+	}
+}
+
+// scheduleIntervalFromEnv returns how often schedules are checked, from
+// HOMEBOX_SCHEDULE_INTERVAL, a Go duration such as "15m".
+func scheduleIntervalFromEnv() (time.Duration, error) {
+	value := os.Getenv("HOMEBOX_SCHEDULE_INTERVAL")
+	if value == "" {
+		return defaultScheduleInterval, nil
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		return 0, fmt.Errorf("invalid HOMEBOX_SCHEDULE_INTERVAL %q", value)
+	}
+	return interval, nil
+}
+
+// usageValue returns the value of the custom field of item that counts
+// usage.
+func usageValue(item ItemOut, field string) (float64, bool) {
+	for _, f := range item.Fields {
+		if !strings.EqualFold(f.Name, field) {
+			continue
+		}
+		if f.Type == "number" {
+			return float64(f.NumberValue), true
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(f.TextValue), 64)
+		return value, err == nil
+	}
+	return 0, false
+}
+
+// syncSchedule brings one schedule up to date. A completed entry moves the
+// next date one interval past its completion date. Once enough usage has
+// accumulated, the entry is due today, whatever the recurrence says. An
+// entry deleted in Homebox is scheduled again.
+func syncSchedule(ctx context.Context, s *MaintenanceSchedule, today string) error {
+	if s.Status == scheduleFinished {
+		return nil
+	}
+	var rule recurrence
+	if s.Recurrence != "" {
+		var err error
+		if rule, err = parseRecurrence(s.Recurrence); err != nil {
+			return err
+		}
+	}
+	var usage float64
+	var usageDue, hasUsage bool
+	if s.UsageField != "" {
+		_, item, err := getItem(ctx, nil, GetItemInput{ID: s.ItemID})
+		if err != nil {
+			return err
+		}
+		usage, hasUsage = usageValue(item, s.UsageField)
+		usageDue = hasUsage && usage-s.LastUsage >= s.UsageInterval
+	}
+
+	if s.EntryID != "" {
+		_, maintenanceLog, err := getMaintenanceLog(ctx, nil, GetMaintenanceLogInput{ItemID: s.ItemID})
+		if err != nil {
+			return err
+		}
+		i := slices.IndexFunc(maintenanceLog.Entries, func(e MaintenanceEntryWithDetails) bool { return e.ID == s.EntryID })
+		switch {
+		case i < 0:
+			s.EntryID = ""
+			s.Occurrences--
+		case dateOnly(maintenanceLog.Entries[i].CompletedDate) != "":
+			s.EntryID = ""
+			s.LastCompleted = dateOnly(maintenanceLog.Entries[i].CompletedDate)
+			s.NextDate = ""
+			if rule.freq != "" {
+				s.NextDate = rule.next(s.LastCompleted)
+			}
+			if hasUsage {
+				s.LastUsage = usage
+			}
+			usageDue = false
+		case usageDue && dateOnly(maintenanceLog.Entries[i].ScheduledDate) > today:
+			entry := maintenanceLog.Entries[i]
+			_, _, err := updateMaintenanceEntry(ctx, nil, UpdateMaintenanceEntryInput{
+				ID: entry.ID, Name: entry.Name, Description: entry.Description, Cost: entry.Cost, ScheduledDate: today,
+			})
+			if err != nil {
+				return err
+			}
+			s.NextDate = today
+			return nil
+		default:
+			return nil
+		}
+	}
+
+	if s.Status == schedulePaused {
+		return nil
+	}
+	due := s.NextDate
+	if usageDue && (due == "" || due > today) {
+		due = today
+	}
+	if due == "" {
+		return nil
+	}
+	if (rule.count > 0 && s.Occurrences >= rule.count) || (rule.until != "" && due > rule.until) {
+		s.Status, s.NextDate = scheduleFinished, ""
+		return nil
+	}
+	_, entry, err := createMaintenanceEntry(ctx, nil, CreateMaintenanceEntryInput{
+		ItemID: s.ItemID, Name: s.Task, Description: s.Description, Cost: s.Cost, ScheduledDate: due,
+	})
+	if err != nil {
+		return err
+	}
+	s.EntryID, s.NextDate = entry.ID, due
+	s.Occurrences++
+	return nil
+}
+
+// Input for the create_maintenance_schedule tool.
+type CreateMaintenanceScheduleInput struct {
+	Item          string  `json:"item" jsonschema:"Item to maintain, by ID, asset ID or name"`
+	Task          string  `json:"task" jsonschema:"Short name of the maintenance task"`
+	Description   string  `json:"description,omitempty" jsonschema:"Details of the task"`
+	Cost          string  `json:"cost,omitempty" jsonschema:"Estimated cost of the task"`
+	Recurrence    string  `json:"recurrence,omitempty" jsonschema:"How often the task is due after it was last done, as an RRULE with FREQ (DAILY, WEEKLY, MONTHLY or YEARLY), INTERVAL, COUNT and UNTIL"`
+	Start         string  `json:"start,omitempty" jsonschema:"Date the first entry is due, as YYYY-MM-DD. By default one interval from today"`
+	UsageField    string  `json:"usageField,omitempty" jsonschema:"Custom item field that counts usage, such as Hours. The task is also due once it grows by usageInterval"`
+	UsageInterval float64 `json:"usageInterval,omitempty" jsonschema:"Usage between services, such as 500 for every 500 hours"`
+}
+
+// createMaintenanceSchedule is the implementation of the
+// "create_maintenance_schedule" tool. The first entry is scheduled right
+// away.
+func createMaintenanceSchedule(ctx context.Context, req *mcp.CallToolRequest, input CreateMaintenanceScheduleInput) (*mcp.CallToolResult, MaintenanceSchedule, error) {
+	if input.Task == "" {
+		return nil, MaintenanceSchedule{}, fmt.Errorf("task is required")
+	}
+	if input.Recurrence == "" && input.UsageField == "" {
+		return nil, MaintenanceSchedule{}, fmt.Errorf("set a recurrence, a usageField, or both")
+	}
+	if (input.UsageField == "") != (input.UsageInterval <= 0) {
+		return nil, MaintenanceSchedule{}, fmt.Errorf("usageField and a positive usageInterval go together")
+	}
+	var rule recurrence
+	if input.Recurrence != "" {
+		var err error
+		if rule, err = parseRecurrence(input.Recurrence); err != nil {
+			return nil, MaintenanceSchedule{}, err
+		}
+	}
+	if input.Start != "" {
+		if _, err := time.Parse(time.DateOnly, input.Start); err != nil {
+			return nil, MaintenanceSchedule{}, fmt.Errorf("start must be a date as YYYY-MM-DD")
+		}
+	}
+
+	itemID, err := resolveItemRef(ctx, "item", input.Item)
+	if err != nil {
+		return nil, MaintenanceSchedule{}, err
+	}
+	_, item, err := getItem(ctx, nil, GetItemInput{ID: itemID})
+	if err != nil {
+		return nil, MaintenanceSchedule{}, err
+	}
+
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	now := time.Now()
+	schedule := MaintenanceSchedule{
+		ID:            hex.EncodeToString(buf),
+		ItemID:        item.ID,
+		ItemName:      item.Name,
+		Task:          input.Task,
+		Description:   input.Description,
+		Cost:          input.Cost,
+		Recurrence:    input.Recurrence,
+		UsageField:    input.UsageField,
+		UsageInterval: input.UsageInterval,
+		Status:        scheduleActive,
+		NextDate:      input.Start,
+		CreatedAt:     now.Format(time.RFC3339),
+	}
+	if schedule.NextDate == "" && rule.freq != "" {
+		schedule.NextDate = rule.next(now.Format(time.DateOnly))
+	}
+	if input.UsageField != "" {
+		usage, ok := usageValue(item, input.UsageField)
+		if !ok {
+			return nil, MaintenanceSchedule{}, fmt.Errorf("%s has no numeric field %s", item.Name, input.UsageField)
+		}
+		schedule.LastUsage = usage
+	}
+
+	err = schedules.update(func(list []MaintenanceSchedule) ([]MaintenanceSchedule, error) {
+		if err := syncSchedule(ctx, &schedule, now.Format(time.DateOnly)); err != nil {
+			return nil, err
+		}
+		return append(list, schedule), nil
+	})
+	if err != nil {
+		return nil, MaintenanceSchedule{}, err
+	}
+	return nil, schedule, nil
+}
+
+// Input for the list_maintenance_schedules tool.
+type ListMaintenanceSchedulesInput struct {
+	Item   string `json:"item,omitempty" jsonschema:"Only list the schedules of this item, by ID, asset ID or name"`
+	Status string `json:"status,omitempty" jsonschema:"Only list schedules with this status: active, paused or finished"`
+}
+
+// Output for the list_maintenance_schedules tool.
+type ListMaintenanceSchedulesOutput struct {
+	Schedules []MaintenanceSchedule `json:"schedules" jsonschema:"The schedules, soonest due first"`
+}
+
+// listMaintenanceSchedules is the implementation of the
+// "list_maintenance_schedules" tool. It shows the schedules as of the last
+// check, which runs every HOMEBOX_SCHEDULE_INTERVAL.
+func listMaintenanceSchedules(ctx context.Context, req *mcp.CallToolRequest, input ListMaintenanceSchedulesInput) (*mcp.CallToolResult, ListMaintenanceSchedulesOutput, error) {
+	itemID, err := resolveItemRef(ctx, "item", input.Item)
+	if err != nil {
+		return nil, ListMaintenanceSchedulesOutput{}, err
+	}
+	list, err := schedules.list()
+	if err != nil {
+		return nil, ListMaintenanceSchedulesOutput{}, err
+	}
+	out := ListMaintenanceSchedulesOutput{Schedules: []MaintenanceSchedule{}}
+	for _, s := range list {
+		if (itemID == "" || s.ItemID == itemID) && (input.Status == "" || s.Status == input.Status) {
+			out.Schedules = append(out.Schedules, s)
+		}
+	}
+	// Schedules without a date, waiting for usage or finished, go last.
+	slices.SortStableFunc(out.Schedules, func(a, b MaintenanceSchedule) int {
+		switch {
+		case a.NextDate == b.NextDate:
+			return strings.Compare(a.ItemName+a.Task, b.ItemName+b.Task)
+		case a.NextDate == "":
+			return 1
+		case b.NextDate == "":
+			return -1
+		}
+		return strings.Compare(a.NextDate, b.NextDate)
+	})
+	return nil, out, nil
+}
+
+// Input for the pause_maintenance_schedule tool.
+type PauseMaintenanceScheduleInput struct {
+	ID     string `json:"id" jsonschema:"ID of the schedule"`
+	Resume bool   `json:"resume,omitempty" jsonschema:"Resume the schedule instead of pausing it"`
+}
+
+// pauseMaintenanceSchedule is the implementation of the
+// "pause_maintenance_schedule" tool. A paused schedule keeps its scheduled
+// entry but does not schedule the next one. A schedule resumed after its due
+// date is due today.
+func pauseMaintenanceSchedule(ctx context.Context, req *mcp.CallToolRequest, input PauseMaintenanceScheduleInput) (*mcp.CallToolResult, MaintenanceSchedule, error) {
+	var schedule MaintenanceSchedule
+	err := schedules.update(func(list []MaintenanceSchedule) ([]MaintenanceSchedule, error) {
+		i := slices.IndexFunc(list, func(s MaintenanceSchedule) bool { return s.ID == input.ID })
+		if i < 0 {
+			return nil, fmt.Errorf("maintenance schedule %s not found", input.ID)
+		}
+		s := &list[i]
+		switch {
+		case s.Status == scheduleFinished:
+			return nil, fmt.Errorf("maintenance schedule %s is finished", input.ID)
+		case input.Resume && s.Status == schedulePaused:
+			today := time.Now().Format(time.DateOnly)
+			s.Status = scheduleActive
+			if s.NextDate != "" && s.NextDate < today && s.EntryID == "" {
+				s.NextDate = today
+			}
+			s.LastError = ""
+			if err := syncSchedule(ctx, s, today); err != nil {
+				s.LastError = err.Error()
+			}
+		case !input.Resume:
+			s.Status = schedulePaused
+		}
+		schedule = *s
+		return list, nil
+	})
+	if err != nil {
+		return nil, MaintenanceSchedule{}, err
+	}
+	return nil, schedule, nil
+}
+
+// Input for the delete_maintenance_schedule tool.
+type DeleteMaintenanceScheduleInput struct {
+	ID          string `json:"id" jsonschema:"ID of the schedule"`
+	DeleteEntry bool   `json:"deleteEntry,omitempty" jsonschema:"Also delete the scheduled Homebox maintenance entry. Completed entries are always kept"`
+}
+
+// Output for the delete_maintenance_schedule tool.
+type DeleteMaintenanceScheduleOutput struct{}
+
+// deleteMaintenanceSchedule is the implementation of the
+// "delete_maintenance_schedule" tool.
+func deleteMaintenanceSchedule(ctx context.Context, req *mcp.CallToolRequest, input DeleteMaintenanceScheduleInput) (*mcp.CallToolResult, DeleteMaintenanceScheduleOutput, error) {
+	err := schedules.update(func(list []MaintenanceSchedule) ([]MaintenanceSchedule, error) {
+		i := slices.IndexFunc(list, func(s MaintenanceSchedule) bool { return s.ID == input.ID })
+		if i < 0 {
+			return nil, fmt.Errorf("maintenance schedule %s not found", input.ID)
+		}
+		if input.DeleteEntry && list[i].EntryID != "" {
+			// The entry may have been completed since the last check.
+			_, maintenanceLog, err := getMaintenanceLog(ctx, nil, GetMaintenanceLogInput{ItemID: list[i].ItemID})
+			if err != nil {
+				return nil, err
+			}
+			open := slices.ContainsFunc(maintenanceLog.Entries, func(e MaintenanceEntryWithDetails) bool {
+				return e.ID == list[i].EntryID && dateOnly(e.CompletedDate) == ""
+			})
+			if open {
+				if _, _, err := deleteMaintenanceEntry(ctx, nil, DeleteMaintenanceEntryInput{ID: list[i].EntryID}); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return slices.Delete(list, i, i+1), nil
+	})
+	return nil, DeleteMaintenanceScheduleOutput{}, err
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	r, err := parseRecurrence("RRULE:FREQ=DAILY;INTERVAL=90")
+	assert.NoError(t, err)
+	assert.Equal(t, "2026-04-01", r.next("2026-01-01"))
+
+	r, err = parseRecurrence("freq=monthly;count=3;until=20271231T000000Z")
+	assert.NoError(t, err)
+	assert.Equal(t, recurrence{freq: "MONTHLY", interval: 1, count: 3, until: "2027-12-31"}, r)
+	assert.Equal(t, "2026-02-15", r.next("2026-01-15"))
+
+	r, err = parseRecurrence("FREQ=WEEKLY;INTERVAL=2")
+	assert.NoError(t, err)
+	assert.Equal(t, "2026-01-15", r.next("2026-01-01"))
+
+	for rule, message := range map[string]string{
+		"INTERVAL=2":              "needs a FREQ",
+		"FREQ=HOURLY":             "unsupported recurrence FREQ",
+		"FREQ=DAILY;INTERVAL=0":   "INTERVAL must be a positive number",
+		"FREQ=DAILY;BYDAY=MO":     `unsupported recurrence part "BYDAY"`,
+		"FREQ=DAILY;UNTIL=soon":   "invalid recurrence UNTIL",
+		"FREQ=DAILY;every 90days": "expected KEY=VALUE",
+	} {
+		_, err := parseRecurrence(rule)
+		assert.ErrorContains(t, err, message, rule)
+	}
+}
+
+func TestMaintenanceSchedules(t *testing.T) {
+	store := newFakeItemStore()
+	hours := func(n int) []ItemField { return []ItemField{{Name: "Hours", Type: "number", NumberValue: n}} }
+	store.items[item1ID] = ItemOut{ID: item1ID, Name: "Generator", Fields: hours(100)}
+	homebox := httptest.NewServer(store)
+	defer homebox.Close()
+	os.Setenv("HOMEBOX_URL", homebox.URL)
+	os.Setenv("HOMEBOX_TOKEN", "test-token")
+	t.Setenv("HOMEBOX_SCHEDULE_FILE", filepath.Join(t.TempDir(), "schedules.json"))
+	ctx := context.Background()
+
+	day := func(days int) string { return time.Now().AddDate(0, 0, days).Format(time.DateOnly) }
+	entries := func() []MaintenanceEntryWithDetails { return store.maintenance[item1ID] }
+	complete := func(date string) {
+		entry := &store.maintenance[item1ID][len(entries())-1]
+		entry.CompletedDate = date + "T00:00:00Z"
+	}
+
+	_, schedule, err := createMaintenanceSchedule(ctx, nil, CreateMaintenanceScheduleInput{
+		Item: item1ID, Task: "Oil change", Cost: "40", Recurrence: "FREQ=DAILY;INTERVAL=90", UsageField: "hours", UsageInterval: 500,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Generator", schedule.ItemName)
+	assert.Equal(t, 100.0, schedule.LastUsage)
+	assert.Equal(t, day(90), schedule.NextDate)
+	assert.Equal(t, 1, schedule.Occurrences)
+	if assert.Len(t, entries(), 1) {
+		assert.Equal(t, MaintenanceEntryWithDetails{ID: schedule.EntryID, ItemID: item1ID, Name: "Oil change", Cost: "40", ScheduledDate: day(90)}, entries()[0])
+	}
+
+	// Completing the entry schedules the next one, one interval later.
+	complete(day(-1))
+	assert.NoError(t, schedules.sync(ctx))
+	_, list, err := listMaintenanceSchedules(ctx, nil, ListMaintenanceSchedulesInput{})
+	assert.NoError(t, err)
+	schedule = list.Schedules[0]
+	assert.Equal(t, day(-1), schedule.LastCompleted)
+	assert.Equal(t, day(89), schedule.NextDate)
+	assert.Equal(t, 2, schedule.Occurrences)
+	assert.Len(t, entries(), 2)
+
+	// Enough running hours make the entry due today.
+	store.items[item1ID] = ItemOut{ID: item1ID, Name: "Generator", Fields: hours(600)}
+	assert.NoError(t, schedules.sync(ctx))
+	assert.Equal(t, day(0), entries()[1].ScheduledDate)
+
+	// A paused schedule does not schedule the next entry until resumed.
+	_, schedule, err = pauseMaintenanceSchedule(ctx, nil, PauseMaintenanceScheduleInput{ID: schedule.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, schedulePaused, schedule.Status)
+	complete(day(0))
+	assert.NoError(t, schedules.sync(ctx))
+	assert.Len(t, entries(), 2)
+	_, list, _ = listMaintenanceSchedules(ctx, nil, ListMaintenanceSchedulesInput{Status: schedulePaused})
+	assert.Equal(t, 600.0, list.Schedules[0].LastUsage)
+	assert.Empty(t, list.Schedules[0].EntryID)
+
+	_, schedule, err = pauseMaintenanceSchedule(ctx, nil, PauseMaintenanceScheduleInput{ID: schedule.ID, Resume: true})
+	assert.NoError(t, err)
+	assert.Equal(t, scheduleActive, schedule.Status)
+	assert.Equal(t, day(90), schedule.NextDate)
+	assert.Len(t, entries(), 3)
+
+	// A schedule with a COUNT finishes.
+	_, counted, err := createMaintenanceSchedule(ctx, nil, CreateMaintenanceScheduleInput{
+		Item: item1ID, Task: "Inspection", Recurrence: "FREQ=YEARLY;COUNT=1", Start: day(7),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, day(7), counted.NextDate)
+	complete(day(0))
+	assert.NoError(t, schedules.sync(ctx))
+	_, list, _ = listMaintenanceSchedules(ctx, nil, ListMaintenanceSchedulesInput{Item: item1ID})
+	assert.Equal(t, []string{schedule.ID, counted.ID}, []string{list.Schedules[0].ID, list.Schedules[1].ID})
+	assert.Equal(t, scheduleFinished, list.Schedules[1].Status)
+
+	_, _, err = deleteMaintenanceSchedule(ctx, nil, DeleteMaintenanceScheduleInput{ID: schedule.ID, DeleteEntry: true})
+	assert.NoError(t, err)
+	assert.Len(t, entries(), 3)
+	_, list, _ = listMaintenanceSchedules(ctx, nil, ListMaintenanceSchedulesInput{})
+	assert.Len(t, list.Schedules, 1)
+
+	_, _, err = createMaintenanceSchedule(ctx, nil, CreateMaintenanceScheduleInput{Item: item1ID, Task: "Check", UsageField: "Voltage", UsageInterval: 1})
+	assert.ErrorContains(t, err, "no numeric field Voltage")
+}
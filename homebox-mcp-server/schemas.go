@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Tool annotations, so clients can tell which tools are safe to call without
+// asking. Every tool only talks to the configured Homebox instance, so none
+// of them is open-world.
+var (
+	// readOnlyTool only reads from Homebox.
+	readOnlyTool = &mcp.ToolAnnotations{ReadOnlyHint: true, IdempotentHint: true, OpenWorldHint: boolPtr(false)}
+	// createTool adds something new each time it is called.
+	createTool = &mcp.ToolAnnotations{DestructiveHint: boolPtr(false), OpenWorldHint: boolPtr(false)}
+	// updateTool overwrites existing fields with the given values.
+	updateTool = &mcp.ToolAnnotations{DestructiveHint: boolPtr(true), IdempotentHint: true, OpenWorldHint: boolPtr(false)}
+	// deleteTool removes something from Homebox.
+	deleteTool = &mcp.ToolAnnotations{DestructiveHint: boolPtr(true), IdempotentHint: true, OpenWorldHint: boolPtr(false)}
+	// bulkActionTool changes many items at once.
+	bulkActionTool = &mcp.ToolAnnotations{DestructiveHint: boolPtr(true), IdempotentHint: true, OpenWorldHint: boolPtr(false)}
+	// cumulativeBulkTool changes many items at once, and calling it again
+	// with the same arguments adds to what the first call did.
+	cumulativeBulkTool = &mcp.ToolAnnotations{DestructiveHint: boolPtr(true), OpenWorldHint: boolPtr(false)}
+	// reportTool reads the inventory and can add entries that are missing
+	// or write the report to a file, but never changes or removes anything
+	// in Homebox.
+	reportTool = &mcp.ToolAnnotations{DestructiveHint: boolPtr(false), IdempotentHint: true, OpenWorldHint: boolPtr(false)}
+)
+
+func boolPtr(b bool) *bool { return &b }
+
+// attachmentTypes are the attachment types Homebox accepts.
+var attachmentTypes = []any{"photo", "manual", "warranty", "attachment", "receipt"}
+
+// itemOrderBy are the fields Homebox can sort items by.
+var itemOrderBy = []any{"name", "createdAt", "updatedAt", "assetId"}
+
+// propertySchemas refines the input properties that struct tags cannot
+// describe, by JSON property name. The refinements apply to the top-level
+// properties of every tool input.
+var propertySchemas = map[string]func(*jsonschema.Schema){
+	"name":            lengthRange(1, 255),
+	"color":           colorProperty,
+	"purchaseTime":    dateProperty,
+	"soldTime":        dateProperty,
+	"warrantyExpires": dateProperty,
+	"completedDate":   dateProperty,
+	"scheduledDate":   dateProperty,
+	"start":           dateProperty,
+	"end":             dateProperty,
+	"assetId":         examples("000-042"),
+	"locationId":      examples("Garage/Shelf 2"),
+	"email":           func(s *jsonschema.Schema) { s.Format = "email" },
+	"type":            func(s *jsonschema.Schema) { s.Enum = attachmentTypes },
+	"orderBy":         func(s *jsonschema.Schema) { s.Enum = itemOrderBy },
+}
+
+func lengthRange(min, max int) func(*jsonschema.Schema) {
+	return func(s *jsonschema.Schema) {
+		s.MinLength, s.MaxLength = &min, &max
+	}
+}
+
+func examples(values ...any) func(*jsonschema.Schema) {
+	return func(s *jsonschema.Schema) { s.Examples = values }
+}
+
+func dateProperty(s *jsonschema.Schema) {
+	s.Format = "date"
+	s.Examples = []any{"2024-03-15"}
+}
+
+func colorProperty(s *jsonschema.Schema) {
+	s.Pattern = "^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$"
+	s.Examples = []any{"#3b82f6"}
+}
+
+// inputSchema derives the input schema of a tool from In, as mcp.AddTool
+// would, and applies propertySchemas to it.
+func inputSchema[In any]() (*jsonschema.Schema, error) {
+	schema, err := jsonschema.For[In](nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, property := range schema.Properties {
+		if refine, ok := propertySchemas[name]; ok {
+			refine(property)
+		}
+	}
+	return schema, nil
+}
+
+// addTool registers a tool like mcp.AddTool, with a refined input schema and,
+// for tools that take OutputOptions, an output schema that allows projection.
+func addTool[In, Out any](server *mcp.Server, tool *mcp.Tool, handler mcp.ToolHandlerFor[In, Out]) {
+	schema, err := inputSchema[In]()
+	if err != nil {
+		panic(fmt.Sprintf("tool %q: input schema: %v", tool.Name, err))
+	}
+	tool.InputSchema = schema
+	if tool.OutputSchema, err = outputSchemaFor[In, Out](); err != nil {
+		panic(fmt.Sprintf("tool %q: output schema: %v", tool.Name, err))
+	}
+	mcp.AddTool(server, tool, handler)
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInputSchema(t *testing.T) {
+	schema, err := inputSchema[UpdateItemInput]()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"id", "name"}, schema.Required)
+	assert.Equal(t, "date", schema.Properties["purchaseTime"].Format)
+	assert.Equal(t, "Date the item was bought, as YYYY-MM-DD", schema.Properties["purchaseTime"].Description)
+	assert.Equal(t, 255, *schema.Properties["name"].MaxLength)
+
+	schema, err = inputSchema[CreateItemAttachmentInput]()
+	assert.NoError(t, err)
+	assert.Equal(t, attachmentTypes, schema.Properties["type"].Enum)
+
+	// Nested properties keep the schema derived from their type.
+	schema, err = inputSchema[UpdateItemInput]()
+	assert.NoError(t, err)
+	assert.Nil(t, schema.Properties["fields"].Items.Properties["type"].Enum)
+}
@@ -0,0 +1,519 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	// defaultMaxResponseBytes caps tool results when HOMEBOX_MAX_RESPONSE_BYTES
+	// is not set. It is roughly 25k tokens of JSON.
+	defaultMaxResponseBytes = 100000
+
+	// moreResultsTTL is how long the rest of a truncated result is kept.
+	moreResultsTTL = 15 * time.Minute
+
+	// maxPendingResults is how many truncated results are kept at once. The
+	// oldest is dropped to make room.
+	maxPendingResults = 100
+)
+
+// OutputOptions are the output shaping arguments of the read tools. They are
+// applied to the tool's structured result by outputShaper.
+type OutputOptions struct {
+	Fields  []string `json:"fields,omitempty" jsonschema:"Only return these fields, such as name or location.name. For lists the fields apply to each entry"`
+	Summary bool     `json:"summary,omitempty" jsonschema:"Return a compact text summary instead of JSON"`
+}
+
+// Input for the get_more_results tool.
+type GetMoreResultsInput struct {
+	Cursor string `json:"cursor" jsonschema:"Cursor from a truncated result"`
+}
+
+// maxResponseBytesFromEnv returns the response size cap from
+// HOMEBOX_MAX_RESPONSE_BYTES. Zero disables the cap.
+func maxResponseBytesFromEnv() (int, error) {
+	value := os.Getenv("HOMEBOX_MAX_RESPONSE_BYTES")
+	if value == "" {
+		return defaultMaxResponseBytes, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid HOMEBOX_MAX_RESPONSE_BYTES %q", value)
+	}
+	return n, nil
+}
+
+// pendingResult is the rest of a truncated result.
+type pendingResult struct {
+	value   any
+	summary bool
+	expires time.Time
+}
+
+// outputShaper applies field projection and summaries to tool results and
+// caps their size. A result over the cap has its largest list or string cut
+// short, and the rest is kept for get_more_results.
+type outputShaper struct {
+	maxBytes int
+
+	mu      sync.Mutex
+	pending map[string]*pendingResult
+}
+
+func newOutputShaper(maxBytes int) *outputShaper {
+	return &outputShaper{maxBytes: maxBytes, pending: make(map[string]*pendingResult)}
+}
+
+// middleware shapes the structured results of tools/call. Results with
+// content of their own, such as images, are left alone.
+func (s *outputShaper) middleware() mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			result, err := next(ctx, method, req)
+			callReq, ok := req.(*mcp.CallToolRequest)
+			if err != nil || method != "tools/call" || !ok || callReq.Params.Name == "get_more_results" {
+				return result, err
+			}
+			res, ok := result.(*mcp.CallToolResult)
+			if !ok || res.IsError || res.StructuredContent == nil || len(res.Content) != 1 {
+				return result, err
+			}
+			if _, ok := res.Content[0].(*mcp.TextContent); !ok {
+				return result, err
+			}
+
+			raw, ok := res.StructuredContent.(json.RawMessage)
+			if !ok {
+				if raw, err = json.Marshal(res.StructuredContent); err != nil {
+					return nil, err
+				}
+			}
+			var opts OutputOptions
+			json.Unmarshal(callReq.Params.Arguments, &opts)
+			if len(opts.Fields) == 0 && !opts.Summary && (s.maxBytes == 0 || len(raw) <= s.maxBytes) {
+				return result, nil
+			}
+			var value any
+			if err := json.Unmarshal(raw, &value); err != nil {
+				return nil, err
+			}
+			if len(opts.Fields) > 0 {
+				value = projectFields(value, opts.Fields)
+			}
+
+			shaped := s.shape(value, opts.Summary)
+			res.StructuredContent, res.Content, res.Meta = shaped.StructuredContent, shaped.Content, shaped.Meta
+			if shaped.Meta != nil {
+				if link := fullResultLink(callReq.Params.Name, callReq.Params.Arguments); link != nil {
+					res.Content = append(res.Content, link)
+				}
+			}
+			return res, nil
+		}
+	}
+}
+
+// getMoreResults is the implementation of the "get_more_results" tool.
+func (s *outputShaper) getMoreResults(ctx context.Context, req *mcp.CallToolRequest, input GetMoreResultsInput) (*mcp.CallToolResult, any, error) {
+	s.mu.Lock()
+	pending, ok := s.pending[input.Cursor]
+	delete(s.pending, input.Cursor)
+	s.mu.Unlock()
+	if !ok || time.Now().After(pending.expires) {
+		return nil, nil, fmt.Errorf("unknown or expired cursor %q", input.Cursor)
+	}
+	return s.shape(pending.value, pending.summary), nil, nil
+}
+
+// shape renders value as a result, truncating it to the size cap.
+func (s *outputShaper) shape(value any, summary bool) *mcp.CallToolResult {
+	data, _ := json.Marshal(value)
+	var cursor string
+	if s.maxBytes > 0 && len(data) > s.maxBytes {
+		if kept, rest, ok := truncateValue(value, s.maxBytes); ok {
+			value, cursor = kept, s.keep(rest, summary)
+			data, _ = json.Marshal(value)
+		}
+	}
+
+	text := string(data)
+	if summary {
+		text = summarize(value)
+	}
+	res := &mcp.CallToolResult{StructuredContent: json.RawMessage(data), Content: []mcp.Content{&mcp.TextContent{Text: text}}}
+	if cursor != "" {
+		res.Meta = mcp.Meta{"nextCursor": cursor}
+		res.Content = append(res.Content, &mcp.TextContent{
+			Text: fmt.Sprintf("The result was truncated to %d bytes. Call get_more_results with cursor %q for the rest.", s.maxBytes, cursor),
+		})
+	}
+	return res
+}
+
+// keep stores the rest of a truncated result and returns its cursor.
+func (s *outputShaper) keep(rest any, summary bool) string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	cursor := hex.EncodeToString(buf)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	var oldest string
+	for c, p := range s.pending {
+		if now.After(p.expires) {
+			delete(s.pending, c)
+		} else if oldest == "" || p.expires.Before(s.pending[oldest].expires) {
+			oldest = c
+		}
+	}
+	if len(s.pending) >= maxPendingResults {
+		delete(s.pending, oldest)
+	}
+	s.pending[cursor] = &pendingResult{value: rest, summary: summary, expires: now.Add(moreResultsTTL)}
+	return cursor
+}
+
+// fullResultLink links to the resource holding the full result of a tool
+// call, for the tools that have one.
+func fullResultLink(tool string, arguments json.RawMessage) *mcp.ResourceLink {
+	var args struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal(arguments, &args)
+	switch {
+	case tool == "export_items":
+		return &mcp.ResourceLink{URI: exportResourceURI, Name: exportResource.Name, MIMEType: exportResource.MIMEType}
+	case tool == "get_item" && args.ID != "":
+		return &mcp.ResourceLink{URI: itemResourceURI(args.ID), Name: "item " + args.ID, MIMEType: "application/json"}
+	case tool == "get_location" && args.ID != "":
+		return &mcp.ResourceLink{URI: locationResourceURI(args.ID), Name: "location " + args.ID, MIMEType: "application/json"}
+	}
+	return nil
+}
+
+// recordList returns the key of the only list of objects in an object, such
+// as the items of get_items, or "" if there is not exactly one.
+func recordList(obj map[string]any) string {
+	key := ""
+	for k, v := range obj {
+		if list, ok := v.([]any); ok && (len(list) == 0 || isObject(list[0])) {
+			if key != "" {
+				return ""
+			}
+			key = k
+		}
+	}
+	return key
+}
+
+func isObject(v any) bool {
+	_, ok := v.(map[string]any)
+	return ok
+}
+
+// projectFields keeps only the given fields of value. Nested fields are
+// written with dots, as in location.name. If value is a list, or holds a list
+// of records none of whose fields were named, the projection applies to
+// each entry of the list.
+func projectFields(value any, fields []string) any {
+	tree := make(map[string]any)
+	for _, field := range fields {
+		node := tree
+		for _, part := range strings.Split(field, ".") {
+			child, ok := node[part].(map[string]any)
+			if !ok {
+				child = make(map[string]any)
+				node[part] = child
+			}
+			node = child
+		}
+	}
+
+	if obj, ok := value.(map[string]any); ok {
+		if key := recordList(obj); key != "" {
+			named := false
+			for field := range tree {
+				_, named = obj[field]
+				if named {
+					break
+				}
+			}
+			if !named {
+				projected := make(map[string]any, len(obj))
+				for k, v := range obj {
+					projected[k] = v
+				}
+				projected[key] = pickFields(obj[key], tree)
+				return projected
+			}
+		}
+	}
+	return pickFields(value, tree)
+}
+
+// pickFields keeps the fields in tree, a map of field names to the tree of
+// their nested fields, or to an empty map to keep the whole field.
+func pickFields(value any, tree map[string]any) any {
+	switch v := value.(type) {
+	case []any:
+		out := make([]any, len(v))
+		for i, elem := range v {
+			out[i] = pickFields(elem, tree)
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any)
+		for field, sub := range tree {
+			fv, ok := v[field]
+			if !ok {
+				continue
+			}
+			if subtree := sub.(map[string]any); len(subtree) > 0 {
+				fv = pickFields(fv, subtree)
+			}
+			out[field] = fv
+		}
+		return out
+	}
+	return value
+}
+
+// truncateValue cuts the largest list or string in value so that it fits
+// in maxBytes of JSON. It returns the value that fits and the same value with
+// the rest of the list or string in its place, or false if nothing could be
+// cut.
+func truncateValue(value any, maxBytes int) (kept, rest any, ok bool) {
+	switch v := value.(type) {
+	case []any:
+		return truncateList(v, maxBytes, jsonSize([]any{}))
+	case string:
+		return truncateString(v, maxBytes-2)
+	case map[string]any:
+		key, largest := "", 0
+		for k, fv := range v {
+			switch fv.(type) {
+			case []any, string:
+				if size := jsonSize(fv); size > largest {
+					key, largest = k, size
+				}
+			}
+		}
+		if key == "" {
+			return nil, nil, false
+		}
+		overhead := jsonSize(v) - largest
+		var keptField, restField any
+		switch fv := v[key].(type) {
+		case []any:
+			keptField, restField, ok = truncateList(fv, maxBytes-overhead, 2)
+		case string:
+			keptField, restField, ok = truncateString(fv, maxBytes-overhead-2)
+		}
+		if !ok {
+			return nil, nil, false
+		}
+		keptObj, restObj := make(map[string]any, len(v)), make(map[string]any, len(v))
+		for k, fv := range v {
+			keptObj[k], restObj[k] = fv, fv
+		}
+		keptObj[key], restObj[key] = keptField, restField
+		return keptObj, restObj, true
+	}
+	return nil, nil, false
+}
+
+// truncateList keeps as many entries of list as fit in budget bytes, given
+// the overhead of the list's surroundings. At least one entry is kept.
+func truncateList(list []any, budget, overhead int) (kept, rest any, ok bool) {
+	size, n := overhead, 0
+	for n < len(list) {
+		size += jsonSize(list[n]) + 1
+		if size > budget && n > 0 {
+			break
+		}
+		n++
+	}
+	if n >= len(list) {
+		return nil, nil, false
+	}
+	return list[:n], list[n:], true
+}
+
+// truncateString keeps as much of str as fits in budget bytes of JSON,
+// ending at a line break if there is one in the second half.
+func truncateString(str string, budget int) (kept, rest any, ok bool) {
+	if budget <= 0 || jsonSize(str) <= budget+2 {
+		return nil, nil, false
+	}
+	cut := budget
+	if cut > len(str) {
+		cut = len(str)
+	}
+	for cut > 0 {
+		size := jsonSize(str[:cut]) - 2
+		if size <= budget {
+			break
+		}
+		next := cut * budget / size
+		if next >= cut {
+			next = cut - 1
+		}
+		cut = next
+	}
+	for cut > 0 && !utf8.RuneStart(str[cut]) {
+		cut--
+	}
+	if i := strings.LastIndexByte(str[:cut], '\n'); i >= cut/2 {
+		cut = i + 1
+	}
+	if cut == 0 {
+		return nil, nil, false
+	}
+	return str[:cut], str[cut:], true
+}
+
+func jsonSize(v any) int {
+	data, _ := json.Marshal(v)
+	return len(data)
+}
+
+// summarize renders value as compact text: one line per entry for lists,
+// and one line per field otherwise.
+func summarize(value any) string {
+	var b strings.Builder
+	switch v := value.(type) {
+	case []any:
+		summarizeList(&b, v)
+	case map[string]any:
+		if key := recordList(v); key != "" {
+			list := v[key].([]any)
+			fmt.Fprintf(&b, "%d %s", len(list), key)
+			if total, ok := v["total"].(float64); ok && int(total) != len(list) {
+				fmt.Fprintf(&b, " of %d", int(total))
+			}
+			b.WriteString(":\n")
+			summarizeList(&b, list)
+			break
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if text := scalarText(v[k]); text != "" {
+				fmt.Fprintf(&b, "%s: %s\n", k, text)
+			}
+		}
+	default:
+		b.WriteString(scalarText(v))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func summarizeList(b *strings.Builder, list []any) {
+	for _, elem := range list {
+		obj, ok := elem.(map[string]any)
+		if !ok {
+			fmt.Fprintf(b, "- %s\n", scalarText(elem))
+			continue
+		}
+		b.WriteString("- " + summaryLine(obj) + "\n")
+	}
+}
+
+// summaryLine describes a record in one line, such as
+// "Drill [id 1f2e…, asset 000-042] in Garage, quantity 2".
+func summaryLine(obj map[string]any) string {
+	name := ""
+	for _, k := range []string{"name", "title", "id"} {
+		if name = scalarText(obj[k]); name != "" {
+			break
+		}
+	}
+	var ids []string
+	if id := scalarText(obj["id"]); id != "" && id != name {
+		ids = append(ids, "id "+id)
+	}
+	if asset := scalarText(obj["assetId"]); asset != "" && asset != "000-000" {
+		ids = append(ids, "asset "+asset)
+	}
+	line := name
+	if len(ids) > 0 {
+		line += " [" + strings.Join(ids, ", ") + "]"
+	}
+	var details []string
+	if loc, ok := obj["location"].(map[string]any); ok {
+		details = append(details, "in "+scalarText(loc["name"]))
+	}
+	if qty, ok := obj["quantity"].(float64); ok && qty > 1 {
+		details = append(details, "quantity "+strconv.FormatFloat(qty, 'f', -1, 64))
+	}
+	for _, k := range []string{"itemCount", "scheduledDate", "completedDate"} {
+		if text := scalarText(obj[k]); text != "" && text != "0" {
+			details = append(details, k+" "+text)
+		}
+	}
+	if len(details) > 0 {
+		line += " " + strings.Join(details, ", ")
+	}
+	return line
+}
+
+// scalarText renders a scalar, or the name of an object, as text. Lists and
+// unnamed objects render as "".
+func scalarText(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case map[string]any:
+		return scalarText(v["name"])
+	}
+	return ""
+}
+
+// outputSchemaFor derives the output schema of a tool whose input embeds
+// OutputOptions. Field projection can leave out any field, so no property is
+// required.
+func outputSchemaFor[In, Out any]() (*jsonschema.Schema, error) {
+	if _, ok := reflect.TypeFor[In]().FieldByName("OutputOptions"); !ok {
+		return nil, nil
+	}
+	schema, err := jsonschema.For[Out](nil)
+	if err != nil {
+		return nil, err
+	}
+	var optional func(*jsonschema.Schema)
+	optional = func(s *jsonschema.Schema) {
+		if s == nil {
+			return
+		}
+		s.Required = nil
+		for _, property := range s.Properties {
+			optional(property)
+		}
+		optional(s.Items)
+	}
+	optional(schema)
+	return schema, nil
+}
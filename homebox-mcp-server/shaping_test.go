@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProjectFields(t *testing.T) {
+	var item any
+	json.Unmarshal([]byte(`{"id":"1","name":"Drill","notes":"cordless","location":{"id":"loc1","name":"Garage"},"labels":[{"id":"lab1","name":"Tools"}]}`), &item)
+	data, _ := json.Marshal(projectFields(item, []string{"name", "location.name", "labels.name"}))
+	assert.JSONEq(t, `{"name":"Drill","location":{"name":"Garage"},"labels":[{"name":"Tools"}]}`, string(data))
+
+	var list any
+	json.Unmarshal([]byte(`{"items":[{"id":"1","name":"Drill"},{"id":"2","name":"Saw"}],"total":2}`), &list)
+	data, _ = json.Marshal(projectFields(list, []string{"name"}))
+	assert.JSONEq(t, `{"items":[{"name":"Drill"},{"name":"Saw"}],"total":2}`, string(data))
+}
+
+func TestTruncateValue(t *testing.T) {
+	csv := "a,b\n1,2\n3,4\n5,6\n"
+	kept, rest, ok := truncateValue(map[string]any{"csv_data": csv}, 30)
+	assert.True(t, ok)
+	assert.LessOrEqual(t, jsonSize(kept), 30)
+	head, tail := kept.(map[string]any)["csv_data"].(string), rest.(map[string]any)["csv_data"].(string)
+	assert.Equal(t, csv, head+tail)
+	assert.True(t, strings.HasSuffix(head, "\n"), "cut at a line break")
+	assert.NotEmpty(t, tail)
+
+	list := map[string]any{"items": []any{"aaaa", "bbbb", "cccc", "dddd"}, "total": 4}
+	kept, rest, ok = truncateValue(list, 35)
+	assert.True(t, ok)
+	assert.LessOrEqual(t, jsonSize(kept), 35)
+	assert.Equal(t, 4, len(kept.(map[string]any)["items"].([]any))+len(rest.(map[string]any)["items"].([]any)))
+
+	_, _, ok = truncateValue(map[string]any{"id": "1"}, 5)
+	assert.False(t, ok)
+}
+
+func TestSummarize(t *testing.T) {
+	var list any
+	json.Unmarshal([]byte(`{"items":[{"id":"1","name":"Drill","assetId":"000-042","location":{"name":"Garage"},"quantity":2}],"page":1,"total":3}`), &list)
+	assert.Equal(t, "1 items of 3:\n- Drill [id 1, asset 000-042] in Garage, quantity 2", summarize(list))
+}
+
+func TestOutputShaper(t *testing.T) {
+	homebox := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var labels []string
+		for i := 0; i < 20; i++ {
+			labels = append(labels, fmt.Sprintf(`{"id":"lab%02d","name":"Label %02d"}`, i, i))
+		}
+		w.Write([]byte("[" + strings.Join(labels, ",") + "]"))
+	}))
+	defer homebox.Close()
+
+	os.Setenv("HOMEBOX_URL", homebox.URL)
+	os.Setenv("HOMEBOX_TOKEN", "test-token")
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	shaper := newOutputShaper(600)
+	server.AddReceivingMiddleware(shaper.middleware())
+	addTool(server, &mcp.Tool{Name: "get_labels"}, getLabels)
+	addTool(server, &mcp.Tool{Name: "get_more_results"}, shaper.getMoreResults)
+
+	ctx := context.Background()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	_, err := server.Connect(ctx, serverTransport, nil)
+	assert.NoError(t, err)
+	session, err := mcp.NewClient(&mcp.Implementation{Name: "client"}, nil).Connect(ctx, clientTransport, nil)
+	assert.NoError(t, err)
+	defer session.Close()
+
+	// The full list is over the cap, so it comes in parts.
+	var names []string
+	result, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "get_labels"})
+	for err == nil {
+		var out GetLabelsOutput
+		data, _ := json.Marshal(result.StructuredContent)
+		assert.NoError(t, json.Unmarshal(data, &out))
+		assert.LessOrEqual(t, len(data), 600)
+		for _, label := range out.Labels {
+			names = append(names, label.Name)
+		}
+		cursor, ok := result.Meta["nextCursor"].(string)
+		if !ok {
+			break
+		}
+		result, err = session.CallTool(ctx, &mcp.CallToolParams{Name: "get_more_results", Arguments: map[string]any{"cursor": cursor}})
+	}
+	assert.NoError(t, err)
+	assert.Len(t, names, 20)
+	assert.Equal(t, "Label 19", names[19])
+
+	// Projection and summaries make it fit.
+	result, err = session.CallTool(ctx, &mcp.CallToolParams{Name: "get_labels", Arguments: map[string]any{"fields": []string{"name"}, "summary": true}})
+	assert.NoError(t, err)
+	assert.Nil(t, result.Meta["nextCursor"])
+	text := result.Content[0].(*mcp.TextContent).Text
+	assert.True(t, strings.HasPrefix(text, "20 labels:\n- Label 00\n- Label 01\n"), text)
+}
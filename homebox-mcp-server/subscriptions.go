@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// deletedVersion is the version of a subscribed resource that no longer
+// exists.
+const deletedVersion = "deleted"
+
+// defaultPollInterval is how often subscribed resources are checked for
+// changes when HOMEBOX_POLL_INTERVAL is not set.
+const defaultPollInterval = 30 * time.Second
+
+// pollIntervalFromEnv returns the poll interval from HOMEBOX_POLL_INTERVAL,
+// a Go duration such as "30s" or "5m".
+func pollIntervalFromEnv() (time.Duration, error) {
+	value := os.Getenv("HOMEBOX_POLL_INTERVAL")
+	if value == "" {
+		return defaultPollInterval, nil
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		return 0, fmt.Errorf("invalid HOMEBOX_POLL_INTERVAL %q", value)
+	}
+	return interval, nil
+}
+
+// resourcePoller implements resources/subscribe for homebox:// resources.
+// Homebox has no change feed, so the poller periodically compares the
+// UpdatedAt of every subscribed item, attachment, location and label and
+// sends notifications/resources/updated when it changes.
+//
+// Each poll fetches every subscribed URI once no matter how many sessions
+// subscribed to it, all locations with one request and all labels with one
+// request.
+type resourcePoller struct {
+	server   *mcp.Server
+	interval time.Duration
+
+	mu       sync.Mutex
+	sessions map[string]map[*mcp.ServerSession]bool
+	versions map[string]string
+}
+
+func newResourcePoller(interval time.Duration) *resourcePoller {
+	return &resourcePoller{
+		interval: interval,
+		sessions: make(map[string]map[*mcp.ServerSession]bool),
+		versions: make(map[string]string),
+	}
+}
+
+// subscribe is the server's SubscribeHandler. It records the current version
+// of the resource, so a missing resource is reported to the client.
+func (p *resourcePoller) subscribe(ctx context.Context, req *mcp.SubscribeRequest) error {
+	uri := req.Params.URI
+	if !isPollableResource(uri) {
+		return fmt.Errorf("subscriptions are not supported for %s", uri)
+	}
+	versions, err := fetchResourceVersions(ctx, []string{uri})
+	if err != nil {
+		return err
+	}
+	version := versions[uri]
+	if version == deletedVersion {
+		return mcp.ResourceNotFoundError(uri)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.sessions[uri] == nil {
+		p.sessions[uri] = make(map[*mcp.ServerSession]bool)
+		p.versions[uri] = version
+	}
+	p.sessions[uri][req.Session] = true
+	return nil
+}
+
+// unsubscribe is the server's UnsubscribeHandler.
+func (p *resourcePoller) unsubscribe(_ context.Context, req *mcp.UnsubscribeRequest) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.removeSession(req.Params.URI, req.Session)
+	return nil
+}
+
+// removeSession drops one subscriber, and the resource once it has none.
+// p.mu must be held.
+func (p *resourcePoller) removeSession(uri string, session *mcp.ServerSession) {
+	delete(p.sessions[uri], session)
+	if len(p.sessions[uri]) == 0 {
+		delete(p.sessions, uri)
+		delete(p.versions, uri)
+	}
+}
+
+// run polls until ctx is cancelled. Polls never overlap; a slow poll delays
+// the next one instead.
+func (p *resourcePoller) run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.poll(ctx); err != nil {
+				log.Printf("Resource poll error: %v", err)
+			}
+		}
+	}
+}
+
+// poll checks every subscribed resource once and notifies subscribers of the
+// ones that changed.
+func (p *resourcePoller) poll(ctx context.Context) error {
+	p.mu.Lock()
+	// The server forgets the subscriptions of closed sessions, so do the same.
+	live := make(map[*mcp.ServerSession]bool)
+	for session := range p.server.Sessions() {
+		live[session] = true
+	}
+	var uris []string
+	for uri, sessions := range p.sessions {
+		for session := range sessions {
+			if !live[session] {
+				p.removeSession(uri, session)
+			}
+		}
+		if len(p.sessions[uri]) > 0 {
+			uris = append(uris, uri)
+		}
+	}
+	p.mu.Unlock()
+
+	if len(uris) == 0 {
+		return nil
+	}
+	versions, err := fetchResourceVersions(ctx, uris)
+
+	// A deleted resource is reported once, when it is first found missing.
+	var changed []string
+	p.mu.Lock()
+	for _, uri := range uris {
+		version, ok := versions[uri]
+		old, subscribed := p.versions[uri]
+		if ok && subscribed && version != old {
+			p.versions[uri] = version
+			changed = append(changed, uri)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, uri := range changed {
+		p.server.ResourceUpdated(ctx, &mcp.ResourceUpdatedNotificationParams{URI: uri})
+	}
+	return err
+}
+
+// isPollableResource reports whether uri is a resource with an UpdatedAt.
+func isPollableResource(uri string) bool {
+	switch segments := strings.Split(strings.TrimPrefix(uri, resourceScheme), "/"); {
+	case !strings.HasPrefix(uri, resourceScheme):
+		return false
+	case len(segments) == 2:
+		return segments[0] == "items" || segments[0] == "locations" || segments[0] == "labels"
+	case len(segments) == 4:
+		return segments[0] == "items" && segments[2] == "attachments"
+	}
+	return false
+}
+
+// fetchResourceVersions returns the UpdatedAt of each of the given
+// resources, or deletedVersion for those that no longer exist. Items are
+// fetched once each, even when several of their attachments are requested,
+// and may be named by asset ID; locations and labels are listed once. Failed
+// lookups are left out of the result and reported in the returned error.
+func fetchResourceVersions(ctx context.Context, uris []string) (map[string]string, error) {
+	var (
+		needLocations, needLabels bool
+		items                     = make(map[string][]string) // item ID or asset ID to its URIs
+		errs                      []error
+	)
+	for _, uri := range uris {
+		segments := strings.Split(strings.TrimPrefix(uri, resourceScheme), "/")
+		switch segments[0] {
+		case "items":
+			items[segments[1]] = append(items[segments[1]], uri)
+		case "locations":
+			needLocations = true
+		case "labels":
+			needLabels = true
+		}
+	}
+
+	versions := make(map[string]string)
+	// listed marks the resources that a successful listing returned, so the
+	// requested ones it did not return are known to be deleted.
+	listed := func(prefix string, known map[string]string) {
+		for _, uri := range uris {
+			if _, ok := known[uri]; !ok && strings.HasPrefix(uri, resourceScheme+prefix) {
+				known[uri] = deletedVersion
+			}
+		}
+	}
+	if needLocations {
+		_, out, err := getLocations(ctx, nil, GetLocationsInput{})
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			for _, loc := range out.Locations {
+				versions[locationResourceURI(loc.ID)] = loc.UpdatedAt
+			}
+			listed("locations/", versions)
+		}
+	}
+	if needLabels {
+		_, out, err := getLabels(ctx, nil, GetLabelsInput{})
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			for _, label := range out.Labels {
+				versions[labelResourceURI(label.ID)] = label.UpdatedAt
+			}
+			listed("labels/", versions)
+		}
+	}
+
+	// An item that cannot be read is deleted if it is missing from the item
+	// list too; the list is only read if that happens.
+	var (
+		summaries []ItemSummary
+		listErr   error
+		listRead  bool
+	)
+	exists := func(ref string) (bool, error) {
+		if !listRead {
+			summaries, listErr = queryAllItems(ctx, url.Values{"includeArchived": {"true"}})
+			listRead = true
+		}
+		return slices.ContainsFunc(summaries, func(item ItemSummary) bool { return item.ID == ref || item.AssetID == ref }), listErr
+	}
+	for ref, itemURIs := range items {
+		id := ref
+		var err error
+		if assetIDPattern.MatchString(ref) {
+			id, err = resolveAssetID(ctx, ref)
+		}
+		var item ItemOut
+		if err == nil {
+			_, item, err = getItem(ctx, nil, GetItemInput{ID: id})
+		}
+		if err != nil {
+			if found, listErr := exists(ref); listErr != nil || found {
+				errs = append(errs, err)
+				continue
+			}
+			for _, uri := range itemURIs {
+				versions[uri] = deletedVersion
+			}
+			continue
+		}
+		itemURI := resourceScheme + "items/" + ref
+		versions[itemURI] = item.UpdatedAt
+		for _, attachment := range item.Attachments {
+			versions[itemURI+"/attachments/"+attachment.ID] = attachment.UpdatedAt
+		}
+		for _, uri := range itemURIs {
+			if _, ok := versions[uri]; !ok {
+				versions[uri] = deletedVersion
+			}
+		}
+	}
+	return versions, errors.Join(errs...)
+}
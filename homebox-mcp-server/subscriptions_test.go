@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPollIntervalFromEnv(t *testing.T) {
+	t.Setenv("HOMEBOX_POLL_INTERVAL", "")
+	interval, err := pollIntervalFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, defaultPollInterval, interval)
+
+	t.Setenv("HOMEBOX_POLL_INTERVAL", "5m")
+	interval, err = pollIntervalFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, interval)
+
+	t.Setenv("HOMEBOX_POLL_INTERVAL", "soon")
+	_, err = pollIntervalFromEnv()
+	assert.Error(t, err)
+}
+
+func TestResourceSubscriptions(t *testing.T) {
+	var (
+		labelVersion  atomic.Value
+		labelRequests atomic.Int32
+		itemRequests  atomic.Int32
+		itemDeleted   atomic.Bool
+	)
+	labelVersion.Store("v1")
+	homebox := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/labels":
+			labelRequests.Add(1)
+			fmt.Fprintf(w, `[{"id":"lab1","name":"Tools","updatedAt":%q}]`, labelVersion.Load())
+		case "/api/v1/items/item1":
+			itemRequests.Add(1)
+			if itemDeleted.Load() {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write([]byte(`{"id":"item1","name":"Drill","updatedAt":"v1","attachments":[{"id":"att1","updatedAt":"v1"}]}`))
+		case "/api/v1/items":
+			if itemDeleted.Load() {
+				w.Write([]byte(`{"items":[],"page":1,"pageSize":100,"total":0}`))
+				return
+			}
+			w.Write([]byte(`{"items":[{"id":"item1","name":"Drill","assetId":"000-001"}],"page":1,"pageSize":100,"total":1}`))
+		case "/api/v1/locations/tree":
+			w.Write([]byte(`[]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer homebox.Close()
+
+	os.Setenv("HOMEBOX_URL", homebox.URL)
+	os.Setenv("HOMEBOX_TOKEN", "test-token")
+
+	poller := newResourcePoller(time.Hour)
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, &mcp.ServerOptions{
+		SubscribeHandler:   poller.subscribe,
+		UnsubscribeHandler: poller.unsubscribe,
+	})
+	poller.server = server
+	registerResources(server)
+
+	var (
+		mu      sync.Mutex
+		updated []string
+	)
+	ctx := context.Background()
+	connect := func() *mcp.ClientSession {
+		client := mcp.NewClient(&mcp.Implementation{Name: "client"}, &mcp.ClientOptions{
+			ResourceUpdatedHandler: func(_ context.Context, req *mcp.ResourceUpdatedNotificationRequest) {
+				mu.Lock()
+				defer mu.Unlock()
+				updated = append(updated, req.Params.URI)
+			},
+		})
+		serverTransport, clientTransport := mcp.NewInMemoryTransports()
+		_, err := server.Connect(ctx, serverTransport, nil)
+		assert.NoError(t, err)
+		session, err := client.Connect(ctx, clientTransport, nil)
+		assert.NoError(t, err)
+		return session
+	}
+	first, second := connect(), connect()
+	defer first.Close()
+	defer second.Close()
+
+	for _, session := range []*mcp.ClientSession{first, second} {
+		assert.NoError(t, session.Subscribe(ctx, &mcp.SubscribeParams{URI: "homebox://labels/lab1"}))
+	}
+	assert.NoError(t, first.Subscribe(ctx, &mcp.SubscribeParams{URI: "homebox://items/item1"}))
+	assert.NoError(t, first.Subscribe(ctx, &mcp.SubscribeParams{URI: "homebox://items/item1/attachments/att1"}))
+	assert.Error(t, first.Subscribe(ctx, &mcp.SubscribeParams{URI: "homebox://labels/missing"}))
+	assert.Error(t, first.Subscribe(ctx, &mcp.SubscribeParams{URI: exportResourceURI}))
+
+	// Nothing changed: one request per listing or item, and no notifications.
+	labelRequests.Store(0)
+	itemRequests.Store(0)
+	assert.NoError(t, poller.poll(ctx))
+	assert.Equal(t, int32(1), labelRequests.Load())
+	assert.Equal(t, int32(1), itemRequests.Load())
+
+	labelVersion.Store("v2")
+	assert.NoError(t, poller.poll(ctx))
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(updated) == 2
+	}, time.Second, 10*time.Millisecond)
+	mu.Lock()
+	assert.Equal(t, []string{"homebox://labels/lab1", "homebox://labels/lab1"}, updated)
+	mu.Unlock()
+
+	// Once every subscriber has left, the label is no longer polled.
+	assert.NoError(t, first.Unsubscribe(ctx, &mcp.UnsubscribeParams{URI: "homebox://labels/lab1"}))
+	assert.NoError(t, second.Unsubscribe(ctx, &mcp.UnsubscribeParams{URI: "homebox://labels/lab1"}))
+	labelRequests.Store(0)
+	assert.NoError(t, poller.poll(ctx))
+	assert.Equal(t, int32(0), labelRequests.Load())
+
+	// Items can be subscribed to by asset ID, and deleting an item is
+	// reported once for each of its subscribed resources.
+	inventory.Invalidate()
+	defer inventory.Invalidate()
+	assert.NoError(t, second.Subscribe(ctx, &mcp.SubscribeParams{URI: "homebox://items/000-001"}))
+	itemDeleted.Store(true)
+	mu.Lock()
+	updated = nil
+	mu.Unlock()
+	assert.NoError(t, poller.poll(ctx))
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(updated) == 3
+	}, time.Second, 10*time.Millisecond)
+	mu.Lock()
+	assert.ElementsMatch(t, []string{"homebox://items/item1", "homebox://items/item1/attachments/att1", "homebox://items/000-001"}, updated)
+	mu.Unlock()
+	assert.NoError(t, poller.poll(ctx))
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	assert.Len(t, updated, 3)
+	mu.Unlock()
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	sdkresource "go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "homebox-mcp-server"
+
+// tracedArguments are the tool arguments recorded as span attributes, so
+// traces can be searched by the item, location or label they touched.
+var tracedArguments = []string{"id", "item_id", "attachment_id", "locationId", "parentId", "labelIds"}
+
+// setupTracing configures the global tracer provider from the environment
+// and returns a function that flushes and stops it.
+//
+// HOMEBOX_OTEL_EXPORTER selects the exporter: "otlp" sends spans over
+// OTLP/HTTP to HOMEBOX_OTEL_ENDPOINT (or the standard OTEL_EXPORTER_OTLP_*
+// variables), "stdout" prints them for local testing. Tracing is disabled
+// when it is unset, but W3C trace context is still propagated to Homebox.
+func setupTracing(ctx context.Context, stdio bool) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	switch kind := os.Getenv("HOMEBOX_OTEL_EXPORTER"); kind {
+	case "":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		var opts []otlptracehttp.Option
+		if endpoint := os.Getenv("HOMEBOX_OTEL_ENDPOINT"); endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(endpoint))
+		}
+		exp, err := otlptracehttp.New(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+		}
+		exporter = exp
+	case "stdout":
+		// stdout carries the MCP protocol on the stdio transport.
+		var w io.Writer = os.Stdout
+		if stdio {
+			w = os.Stderr
+		}
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(w), stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stdout exporter: %w", err)
+		}
+		exporter = exp
+	default:
+		return nil, fmt.Errorf("unknown HOMEBOX_OTEL_EXPORTER %q", kind)
+	}
+
+	res, err := sdkresource.Merge(sdkresource.Default(), sdkresource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(tracerName)))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// tracingMiddleware returns an MCP receiving middleware that wraps every
+// tools/call request in a span. Trace context sent by HTTP clients is used
+// as the parent, so it flows through to the Homebox requests.
+func tracingMiddleware() mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			callReq, ok := req.(*mcp.CallToolRequest)
+			if method != "tools/call" || !ok {
+				return next(ctx, method, req)
+			}
+
+			if extra := callReq.GetExtra(); extra != nil && extra.Header != nil {
+				ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(extra.Header))
+			}
+
+			tool := callReq.Params.Name
+			attrs := append([]attribute.KeyValue{attribute.String("mcp.tool.name", tool)}, argumentAttributes(callReq.Params.Arguments)...)
+			if callReq.Session != nil && callReq.Session.ID() != "" {
+				attrs = append(attrs, attribute.String("mcp.session.id", callReq.Session.ID()))
+			}
+			ctx, span := otel.Tracer(tracerName).Start(ctx, "tools/call "+tool,
+				trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+			defer span.End()
+
+			result, err := next(ctx, method, req)
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			} else if res, _ := result.(*mcp.CallToolResult); res != nil && res.IsError {
+				span.SetStatus(codes.Error, "tool returned an error")
+			}
+			return result, err
+		}
+	}
+}
+
+// argumentAttributes extracts the traced ID arguments of a tool call.
+func argumentAttributes(raw json.RawMessage) []attribute.KeyValue {
+	var args map[string]any
+	if len(raw) == 0 || json.Unmarshal(raw, &args) != nil {
+		return nil
+	}
+	var attrs []attribute.KeyValue
+	for _, name := range tracedArguments {
+		switch v := args[name].(type) {
+		case string:
+			if v != "" {
+				attrs = append(attrs, attribute.String("homebox."+name, v))
+			}
+		case []any:
+			var ids []string
+			for _, id := range v {
+				if s, ok := id.(string); ok {
+					ids = append(ids, s)
+				}
+			}
+			attrs = append(attrs, attribute.StringSlice("homebox."+name, ids))
+		}
+	}
+	return attrs
+}
+
+// startUpstreamSpan starts a client span for a request to the Homebox API
+// and injects the trace context into its headers.
+func startUpstreamSpan(req *http.Request, endpoint string) (*http.Request, trace.Span) {
+	ctx, span := otel.Tracer(tracerName).Start(req.Context(), req.Method+" "+endpoint,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.URLPath(req.URL.Path),
+			semconv.HTTPRoute(endpoint),
+			semconv.ServerAddress(req.URL.Hostname()),
+		))
+	req = req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	return req, span
+}
+
+// endUpstreamSpan records the outcome of a Homebox request and ends its span.
+func endUpstreamSpan(span trace.Span, resp *http.Response, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+		if resp.StatusCode >= 400 {
+			span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+		}
+	}
+	span.End()
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracingMiddleware(t *testing.T) {
+	var traceparent string
+	homebox := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id":"abc","name":"Garage"}`))
+	}))
+	defer homebox.Close()
+
+	os.Setenv("HOMEBOX_URL", homebox.URL)
+	os.Setenv("HOMEBOX_TOKEN", "test-token")
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	server.AddReceivingMiddleware(tracingMiddleware())
+	mcp.AddTool(server, &mcp.Tool{Name: "get_label"}, getLabel)
+
+	ctx := context.Background()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	_, err := server.Connect(ctx, serverTransport, nil)
+	assert.NoError(t, err)
+	session, err := mcp.NewClient(&mcp.Implementation{Name: "client"}, nil).Connect(ctx, clientTransport, nil)
+	assert.NoError(t, err)
+	defer session.Close()
+
+	_, err = session.CallTool(ctx, &mcp.CallToolParams{Name: "get_label", Arguments: map[string]any{"id": "abc"}})
+	assert.NoError(t, err)
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 2) {
+		upstream, tool := spans[0], spans[1]
+		assert.Equal(t, "tools/call get_label", tool.Name())
+		assert.Contains(t, tool.Attributes(), attribute.String("homebox.id", "abc"))
+		assert.Equal(t, "GET /api/v1/labels/abc", upstream.Name())
+		assert.Equal(t, tool.SpanContext().TraceID(), upstream.SpanContext().TraceID())
+		assert.Contains(t, traceparent, upstream.SpanContext().SpanID().String())
+	}
+}
@@ -0,0 +1,426 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"gopkg.in/yaml.v3"
+)
+
+// Depreciation methods.
+const (
+	straightLine     = "straight-line"
+	decliningBalance = "declining-balance"
+	noDepreciation   = "none"
+)
+
+// defaultDepreciation is the model of items without a label rule, when
+// neither the input nor HOMEBOX_DEPRECIATION_RULES sets one.
+var defaultDepreciation = DepreciationModel{Method: straightLine, UsefulLifeYears: 5}
+
+// defaultValuationMonths is the length of the value series.
+const defaultValuationMonths = 12
+
+// DepreciationModel is how an item loses value over time.
+type DepreciationModel struct {
+	Method          string  `json:"method,omitempty" yaml:"method" jsonschema:"straight-line (the default), declining-balance or none"`
+	UsefulLifeYears float64 `json:"usefulLifeYears,omitempty" yaml:"usefulLifeYears" jsonschema:"Years until the item is worth its salvage value. Straight-line needs it; declining-balance uses it for a rate of 2/usefulLifeYears"`
+	Rate            float64 `json:"rate,omitempty" yaml:"rate" jsonschema:"Share of its value a declining-balance item loses each year, such as 0.25"`
+	SalvagePercent  float64 `json:"salvagePercent,omitempty" yaml:"salvagePercent" jsonschema:"Percentage of the purchase price the item is always worth; 0 by default"`
+}
+
+// DepreciationRules are the default model and the models of the items with
+// a label.
+type DepreciationRules struct {
+	Default *DepreciationModel           `json:"default,omitempty" yaml:"default" jsonschema:"Model of the items without a label rule"`
+	Labels  map[string]DepreciationModel `json:"labels,omitempty" yaml:"labels" jsonschema:"Models by label name"`
+}
+
+// Input for the valuation tool.
+type ValuationInput struct {
+	Rules           DepreciationRules `json:"rules,omitempty" jsonschema:"Depreciation models, replacing the default and the label models from HOMEBOX_DEPRECIATION_RULES"`
+	Date            string            `json:"date,omitempty" jsonschema:"Date to value the items at, as YYYY-MM-DD; today by default"`
+	Months          int               `json:"months,omitempty" jsonschema:"Number of months the value series goes back from date; 12 by default"`
+	IncludeArchived bool              `json:"includeArchived,omitempty" jsonschema:"Also value archived items"`
+}
+
+// ItemValuation is the estimated value of an item.
+type ItemValuation struct {
+	ID            string  `json:"id" jsonschema:"ID of the item"`
+	Name          string  `json:"name" jsonschema:"Name of the item"`
+	Location      string  `json:"location,omitempty" jsonschema:"Path of the location of the item"`
+	ParentID      string  `json:"parentId,omitempty" jsonschema:"ID of the item this item is part of"`
+	Model         string  `json:"model" jsonschema:"Depreciation model applied, and the label it comes from"`
+	PurchaseTime  string  `json:"purchaseTime,omitempty" jsonschema:"Date the item was bought"`
+	PurchasePrice float64 `json:"purchasePrice" jsonschema:"Price paid for the item"`
+	Value         float64 `json:"value" jsonschema:"Estimated value of the item"`
+	TotalValue    float64 `json:"totalValue" jsonschema:"Estimated value of the item and its child items"`
+	Undated       bool    `json:"undated,omitempty" jsonschema:"Whether the purchase date is missing, so the item is valued at its purchase price"`
+}
+
+// ValueTotal is the value of the items of a location or label.
+type ValueTotal struct {
+	ID            string  `json:"id" jsonschema:"ID of the location or label"`
+	Name          string  `json:"name" jsonschema:"Path of the location, or name of the label"`
+	Items         int     `json:"items" jsonschema:"Number of items"`
+	PurchasePrice float64 `json:"purchasePrice" jsonschema:"Total purchase price of the items"`
+	Value         float64 `json:"value" jsonschema:"Total estimated value of the items"`
+}
+
+// Output for the valuation tool.
+type ValuationOutput struct {
+	Date               string          `json:"date" jsonschema:"Date the items are valued at"`
+	TotalPurchasePrice float64         `json:"totalPurchasePrice" jsonschema:"Total purchase price of the items"`
+	TotalValue         float64         `json:"totalValue" jsonschema:"Total estimated value of the items"`
+	Unpriced           int             `json:"unpriced" jsonschema:"Number of items without a purchase price, which are left out"`
+	Items              []ItemValuation `json:"items" jsonschema:"The items, most valuable first"`
+	ByLocation         []ValueTotal    `json:"byLocation" jsonschema:"Value by location, including the locations below it, highest first"`
+	ByLabel            []ValueTotal    `json:"byLabel" jsonschema:"Value by label, including the child items of labelled items, highest first"`
+	ValueOverTime      ValueOverTime   `json:"valueOverTime" jsonschema:"Total estimated value at the end of each month, in the shape of the Homebox purchase price statistics"`
+}
+
+// depreciationRulesFromEnv reads the depreciation rules from the YAML file
+// named by HOMEBOX_DEPRECIATION_RULES.
+func depreciationRulesFromEnv() (DepreciationRules, error) {
+	path := os.Getenv("HOMEBOX_DEPRECIATION_RULES")
+	if path == "" {
+		return DepreciationRules{}, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return DepreciationRules{}, fmt.Errorf("failed to read HOMEBOX_DEPRECIATION_RULES: %w", err)
+	}
+	var rules DepreciationRules
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return DepreciationRules{}, fmt.Errorf("failed to parse HOMEBOX_DEPRECIATION_RULES: %w", err)
+	}
+	return rules, nil
+}
+
+// validate checks a model and fills in its method.
+func (m *DepreciationModel) validate(name string) error {
+	if m.Method == "" {
+		m.Method = straightLine
+	}
+	switch {
+	case !slices.Contains([]string{straightLine, decliningBalance, noDepreciation}, m.Method):
+		return fmt.Errorf("%s has unknown depreciation method %q, expected straight-line, declining-balance or none", name, m.Method)
+	case m.Method == straightLine && m.UsefulLifeYears <= 0:
+		return fmt.Errorf("%s needs a positive usefulLifeYears", name)
+	case m.Method == decliningBalance && m.Rate <= 0 && m.UsefulLifeYears <= 0:
+		return fmt.Errorf("%s needs a rate or usefulLifeYears", name)
+	case m.Rate < 0 || m.Rate >= 1:
+		return fmt.Errorf("%s needs a rate between 0 and 1", name)
+	case m.SalvagePercent < 0 || m.SalvagePercent > 100:
+		return fmt.Errorf("%s needs a salvagePercent between 0 and 100", name)
+	}
+	return nil
+}
+
+// String describes the model, such as "straight-line over 5 years".
+func (m DepreciationModel) String() string {
+	var s string
+	switch m.Method {
+	case straightLine:
+		s = fmt.Sprintf("straight-line over %g years", m.UsefulLifeYears)
+	case decliningBalance:
+		s = fmt.Sprintf("declining-balance at %g%% a year", math.Round(m.rate()*1000)/10)
+	default:
+		return "no depreciation"
+	}
+	if m.SalvagePercent > 0 {
+		s += fmt.Sprintf(" to %g%%", m.SalvagePercent)
+	}
+	return s
+}
+
+// rate is the yearly rate of a declining-balance model.
+func (m DepreciationModel) rate() float64 {
+	if m.Rate > 0 {
+		return m.Rate
+	}
+	return min(2/m.UsefulLifeYears, 1)
+}
+
+// value is what something bought for price is worth after years.
+func (m DepreciationModel) value(price, years float64) float64 {
+	salvage := price * m.SalvagePercent / 100
+	var value float64
+	switch m.Method {
+	case straightLine:
+		value = price - (price-salvage)*years/m.UsefulLifeYears
+	case decliningBalance:
+		value = price * math.Pow(1-m.rate(), years)
+	default:
+		return price
+	}
+	return math.Max(value, salvage)
+}
+
+// valuation is the implementation of the "valuation" tool.
+func valuation(ctx context.Context, req *mcp.CallToolRequest, input ValuationInput) (*mcp.CallToolResult, ValuationOutput, error) {
+	query := url.Values{}
+	if input.IncludeArchived {
+		query.Set("includeArchived", "true")
+	}
+	summaries, err := queryAllItems(ctx, query)
+	if err != nil {
+		return nil, ValuationOutput{}, err
+	}
+	items, err := readItems(ctx, req, summaries)
+	if err != nil {
+		return nil, ValuationOutput{}, err
+	}
+	forest, err := loadLocationForest(ctx)
+	if err != nil {
+		return nil, ValuationOutput{}, err
+	}
+	out, err := appraise(items, forest, input)
+	if err != nil {
+		return nil, ValuationOutput{}, err
+	}
+	return nil, out, nil
+}
+
+// appraise values items with the models of input and the rules from
+// HOMEBOX_DEPRECIATION_RULES. Items are valued at their purchase price as
+// stored, like the Homebox statistics, and are worth nothing before they
+// were bought or after they were sold.
+func appraise(items []ItemOut, forest *locationForest, input ValuationInput) (ValuationOutput, error) {
+	rules, err := depreciationRulesFromEnv()
+	if err != nil {
+		return ValuationOutput{}, err
+	}
+	if input.Rules.Default != nil {
+		rules.Default = input.Rules.Default
+	}
+	labelModels := make(map[string]DepreciationModel)
+	for name, model := range rules.Labels {
+		labelModels[strings.ToLower(name)] = model
+	}
+	for name, model := range input.Rules.Labels {
+		labelModels[strings.ToLower(name)] = model
+	}
+	fallback := defaultDepreciation
+	if rules.Default != nil {
+		fallback = *rules.Default
+		if err := fallback.validate("the default model"); err != nil {
+			return ValuationOutput{}, err
+		}
+	}
+	for name, model := range labelModels {
+		if err := model.validate("the model for " + name); err != nil {
+			return ValuationOutput{}, err
+		}
+		labelModels[name] = model
+	}
+
+	date := time.Now().UTC().Truncate(24 * time.Hour)
+	if input.Date != "" {
+		if date, err = time.Parse(time.DateOnly, input.Date); err != nil {
+			return ValuationOutput{}, fmt.Errorf("date must be YYYY-MM-DD, got %q", input.Date)
+		}
+	}
+	months := input.Months
+	if months <= 0 {
+		months = defaultValuationMonths
+	}
+
+	// An item with several labels that have a model depreciates by the one
+	// with the shortest useful life, then by label name.
+	modelOf := func(item ItemOut) (DepreciationModel, string) {
+		model, from, life := fallback, "", math.Inf(1)
+		labels := slices.Clone(item.Labels)
+		slices.SortFunc(labels, func(a, b LabelSummary) int { return strings.Compare(a.Name, b.Name) })
+		for _, label := range labels {
+			m, ok := labelModels[strings.ToLower(label.Name)]
+			if !ok {
+				continue
+			}
+			l := m.UsefulLifeYears
+			if l <= 0 {
+				l = math.Inf(1)
+			}
+			if from == "" || l < life {
+				model, from, life = m, label.Name, l
+			}
+		}
+		return model, from
+	}
+
+	out := ValuationOutput{Date: date.Format(time.DateOnly), Items: []ItemValuation{}}
+	type valued struct {
+		item     ItemOut
+		model    DepreciationModel
+		bought   time.Time
+		sold     time.Time
+		undated  bool
+		location *locationNode
+	}
+	var priced []valued
+	for _, item := range items {
+		if item.PurchasePrice <= 0 {
+			out.Unpriced++
+			continue
+		}
+		v := valued{item: item}
+		v.model, _ = modelOf(item)
+		if t, err := time.Parse(time.DateOnly, dateOnly(item.PurchaseTime)); err == nil {
+			v.bought = t
+		} else {
+			v.undated = true
+		}
+		if t, err := time.Parse(time.DateOnly, dateOnly(item.SoldTime)); err == nil {
+			v.sold = t
+		}
+		if item.Location != nil {
+			v.location = forest.Nodes[item.Location.ID]
+		}
+		priced = append(priced, v)
+	}
+	valueAt := func(v valued, at time.Time) float64 {
+		if !v.sold.IsZero() && !at.Before(v.sold) {
+			return 0
+		}
+		if v.undated {
+			return v.item.PurchasePrice
+		}
+		if at.Before(v.bought) {
+			return 0
+		}
+		return v.model.value(v.item.PurchasePrice, at.Sub(v.bought).Hours()/24/365.25)
+	}
+
+	values := make(map[string]float64)
+	parents := make(map[string]string)
+	labelsOf := make(map[string][]LabelSummary)
+	for _, v := range priced {
+		values[v.item.ID] = roundCents(valueAt(v, date))
+	}
+	for _, item := range items {
+		labelsOf[item.ID] = item.Labels
+		if item.Parent != nil {
+			parents[item.ID] = item.Parent.ID
+		}
+	}
+	// ancestors lists the items an item is part of, nearest first, stopping
+	// at a cycle.
+	ancestors := func(id string) []string {
+		var chain []string
+		for parent := parents[id]; parent != "" && parent != id && !slices.Contains(chain, parent); parent = parents[parent] {
+			chain = append(chain, parent)
+		}
+		return chain
+	}
+
+	totals := make(map[string]float64)
+	byLocation, byLabel := make(map[string]*ValueTotal), make(map[string]*ValueTotal)
+	add := func(totals map[string]*ValueTotal, id, name string, v valued, value float64) {
+		if totals[id] == nil {
+			totals[id] = &ValueTotal{ID: id, Name: name}
+		}
+		totals[id].Items++
+		totals[id].PurchasePrice += v.item.PurchasePrice
+		totals[id].Value += value
+	}
+	for _, v := range priced {
+		value := values[v.item.ID]
+		out.TotalPurchasePrice += v.item.PurchasePrice
+		out.TotalValue += value
+		totals[v.item.ID] += value
+		chain := ancestors(v.item.ID)
+		for _, parent := range chain {
+			totals[parent] += value
+		}
+		// Locations are walked by ID, as names need not be unique and may
+		// contain "/".
+		for node := v.location; node != nil; node = forest.Nodes[node.ParentID] {
+			add(byLocation, node.ID, node.Path, v, value)
+		}
+		seen := make(map[string]bool)
+		for _, id := range append([]string{v.item.ID}, chain...) {
+			for _, label := range labelsOf[id] {
+				if !seen[label.ID] {
+					seen[label.ID] = true
+					add(byLabel, label.ID, label.Name, v, value)
+				}
+			}
+		}
+	}
+
+	for _, v := range priced {
+		model, from := modelOf(v.item)
+		description := model.String()
+		if from != "" {
+			description += " (label " + from + ")"
+		}
+		entry := ItemValuation{
+			ID:            v.item.ID,
+			Name:          v.item.Name,
+			Model:         description,
+			PurchaseTime:  dateOnly(v.item.PurchaseTime),
+			PurchasePrice: v.item.PurchasePrice,
+			Value:         values[v.item.ID],
+			TotalValue:    roundCents(totals[v.item.ID]),
+			Undated:       v.undated,
+		}
+		if v.item.Parent != nil {
+			entry.ParentID = v.item.Parent.ID
+		}
+		if v.location != nil {
+			entry.Location = v.location.Path
+		}
+		out.Items = append(out.Items, entry)
+	}
+	slices.SortFunc(out.Items, func(a, b ItemValuation) int {
+		return cmp.Or(cmp.Compare(b.Value, a.Value), strings.Compare(a.Name, b.Name), strings.Compare(a.ID, b.ID))
+	})
+	out.ByLocation, out.ByLabel = sortedValueTotals(byLocation), sortedValueTotals(byLabel)
+	out.TotalPurchasePrice, out.TotalValue = roundCents(out.TotalPurchasePrice), roundCents(out.TotalValue)
+
+	// The series ends at date and has a point at the end of every month
+	// before it, like the entries of ValueOverTime.
+	series := ValueOverTime{End: out.Date, Entries: []ValueOverTimeEntry{}}
+	for i := months; i >= 0; i-- {
+		at := date
+		if i > 0 {
+			at = time.Date(date.Year(), date.Month()-time.Month(i)+1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, -1)
+		}
+		total := 0.0
+		for _, v := range priced {
+			total += valueAt(v, at)
+		}
+		series.Entries = append(series.Entries, ValueOverTimeEntry{Date: at.Format(time.DateOnly), Name: "Estimated value", Value: roundCents(total)})
+	}
+	series.Start = series.Entries[0].Date
+	series.ValueAtStart = series.Entries[0].Value
+	series.ValueAtEnd = out.TotalValue
+	out.ValueOverTime = series
+	return out, nil
+}
+
+// sortedValueTotals returns the totals, highest value first.
+func sortedValueTotals(t map[string]*ValueTotal) []ValueTotal {
+	totals := []ValueTotal{}
+	for _, total := range t {
+		total.PurchasePrice, total.Value = roundCents(total.PurchasePrice), roundCents(total.Value)
+		totals = append(totals, *total)
+	}
+	slices.SortFunc(totals, func(a, b ValueTotal) int {
+		return cmp.Or(cmp.Compare(b.Value, a.Value), strings.Compare(a.Name, b.Name))
+	})
+	return totals
+}
+
+// roundCents rounds an amount to two decimals.
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDepreciationModel(t *testing.T) {
+	linear := DepreciationModel{Method: straightLine, UsefulLifeYears: 4}
+	assert.Equal(t, 750.0, linear.value(1000, 1))
+	assert.Equal(t, 0.0, linear.value(1000, 6))
+	linear.SalvagePercent = 10
+	assert.Equal(t, 100.0, linear.value(1000, 6))
+	assert.Equal(t, "straight-line over 4 years to 10%", linear.String())
+
+	declining := DepreciationModel{Method: decliningBalance, UsefulLifeYears: 4}
+	assert.Equal(t, 250.0, declining.value(1000, 2))
+	assert.Equal(t, "declining-balance at 50% a year", declining.String())
+	assert.Equal(t, 1000.0, DepreciationModel{Method: noDepreciation}.value(1000, 20))
+
+	for model, message := range map[DepreciationModel]string{
+		{Method: "sum-of-years"}:              "unknown depreciation method",
+		{}:                                    "needs a positive usefulLifeYears",
+		{Method: decliningBalance}:            "needs a rate or usefulLifeYears",
+		{Method: decliningBalance, Rate: 1.5}: "rate between 0 and 1",
+		{Method: straightLine, UsefulLifeYears: 3, SalvagePercent: 200}: "salvagePercent between 0 and 100",
+	} {
+		assert.ErrorContains(t, model.validate("model"), message)
+	}
+}
+
+func TestValuation(t *testing.T) {
+	const (
+		garageTreeID = "00000000-0000-4000-9000-000000000001"
+		screwsID     = "00000000-0000-4000-8000-000000000004"
+	)
+	store := newFakeItemStore()
+	garage := &LocationSummary{ID: garageTreeID}
+	store.items[item1ID] = ItemOut{ID: item1ID, Name: "Laptop", Location: garage, PurchasePrice: 1200, PurchaseTime: "2024-01-01T00:00:00Z",
+		Labels: []LabelSummary{{ID: electronicsID, Name: "Electronics"}}}
+	store.items[item2ID] = ItemOut{ID: item2ID, Name: "Charger", Location: garage, PurchasePrice: 100, PurchaseTime: "2025-01-01",
+		Parent: &ItemSummary{ID: item1ID}}
+	store.items[item3ID] = ItemOut{ID: item3ID, Name: "Workbench", PurchasePrice: 500, PurchaseTime: "0001-01-01"}
+	store.items[screwsID] = ItemOut{ID: screwsID, Name: "Screws"}
+	homebox := httptest.NewServer(store)
+	defer homebox.Close()
+	os.Setenv("HOMEBOX_URL", homebox.URL)
+	os.Setenv("HOMEBOX_TOKEN", "test-token")
+	inventory.Invalidate()
+	defer inventory.Invalidate()
+	rules := filepath.Join(t.TempDir(), "depreciation.yaml")
+	os.WriteFile(rules, []byte("default:\n  method: straight-line\n  usefulLifeYears: 10\nlabels:\n  Electronics:\n    method: declining-balance\n    rate: 0.5\n"), 0o644)
+	t.Setenv("HOMEBOX_DEPRECIATION_RULES", rules)
+	ctx := context.Background()
+
+	_, out, err := valuation(ctx, nil, ValuationInput{Date: "2026-01-01", Months: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, out.Unpriced)
+	assert.Equal(t, 1800.0, out.TotalPurchasePrice)
+	if assert.Len(t, out.Items, 3) {
+		assert.Equal(t, ItemValuation{
+			ID: item3ID, Name: "Workbench", Model: "straight-line over 10 years",
+			PurchasePrice: 500, Value: 500, TotalValue: 500, Undated: true,
+		}, out.Items[0])
+		laptop := out.Items[1]
+		assert.Equal(t, "declining-balance at 50% a year (label Electronics)", laptop.Model)
+		assert.InDelta(t, 300, laptop.Value, 1)
+		assert.Equal(t, roundCents(laptop.Value+out.Items[2].Value), laptop.TotalValue)
+		assert.Equal(t, "Garage", laptop.Location)
+		charger := out.Items[2]
+		assert.Equal(t, item1ID, charger.ParentID)
+		assert.InDelta(t, 90, charger.Value, 0.1)
+	}
+	assert.Equal(t, roundCents(out.Items[0].Value+out.Items[1].Value+out.Items[2].Value), out.TotalValue)
+	if assert.Len(t, out.ByLocation, 1) {
+		assert.Equal(t, ValueTotal{ID: garageTreeID, Name: "Garage", Items: 2, PurchasePrice: 1300, Value: out.Items[1].TotalValue}, out.ByLocation[0])
+	}
+	if assert.Len(t, out.ByLabel, 1) {
+		assert.Equal(t, 2, out.ByLabel[0].Items)
+		assert.Equal(t, out.Items[1].TotalValue, out.ByLabel[0].Value)
+	}
+
+	series := out.ValueOverTime
+	assert.Equal(t, "2025-10-31", series.Start)
+	assert.Equal(t, "2026-01-01", series.End)
+	assert.Equal(t, []string{"2025-10-31", "2025-11-30", "2025-12-31", "2026-01-01"}, []string{
+		series.Entries[0].Date, series.Entries[1].Date, series.Entries[2].Date, series.Entries[3].Date,
+	})
+	assert.Equal(t, out.TotalValue, series.ValueAtEnd)
+	assert.Greater(t, series.ValueAtStart, series.ValueAtEnd)
+
+	// Label models from the input replace the file's, and sold items are
+	// worth nothing.
+	sold := store.items[item2ID]
+	sold.SoldTime = "2025-06-01"
+	store.items[item2ID] = sold
+	_, out, err = valuation(ctx, nil, ValuationInput{Date: "2026-01-01", Rules: DepreciationRules{
+		Labels: map[string]DepreciationModel{"electronics": {Method: noDepreciation}},
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1700.0, out.TotalValue)
+	assert.Len(t, out.ValueOverTime.Entries, 13)
+
+	_, _, err = valuation(ctx, nil, ValuationInput{Rules: DepreciationRules{Default: &DepreciationModel{Method: straightLine}}})
+	assert.ErrorContains(t, err, "the default model needs a positive usefulLifeYears")
+}
+
+func TestAppraiseByLocation(t *testing.T) {
+	t.Setenv("HOMEBOX_DEPRECIATION_RULES", "")
+	// Two shelves share a name, and a name contains "/", so the rollup
+	// follows the location IDs rather than the paths.
+	forest := &locationForest{Nodes: map[string]*locationNode{
+		"garage": {ID: "garage", Name: "Garage", Path: "Garage", Children: []string{"shelf1", "shelf2"}},
+		"shelf1": {ID: "shelf1", Name: "Shelf", ParentID: "garage", Path: "Garage/Shelf"},
+		"shelf2": {ID: "shelf2", Name: "Shelf", ParentID: "garage", Path: "Garage/Shelf"},
+		"io":     {ID: "io", Name: "In/Out", Path: "In/Out"},
+	}, Roots: []string{"garage", "io"}}
+	items := []ItemOut{
+		{ID: item1ID, Name: "Drill", Location: &LocationSummary{ID: "shelf1"}, PurchasePrice: 100},
+		{ID: item2ID, Name: "Saw", Location: &LocationSummary{ID: "shelf2"}, PurchasePrice: 50},
+		{ID: item3ID, Name: "Tray", Location: &LocationSummary{ID: "io"}, PurchasePrice: 10},
+	}
+	out, err := appraise(items, forest, ValuationInput{Date: "2026-01-01"})
+	assert.NoError(t, err)
+	assert.Equal(t, []ValueTotal{
+		{ID: "garage", Name: "Garage", Items: 2, PurchasePrice: 150, Value: 150},
+		{ID: "shelf1", Name: "Garage/Shelf", Items: 1, PurchasePrice: 100, Value: 100},
+		{ID: "shelf2", Name: "Garage/Shelf", Items: 1, PurchasePrice: 50, Value: 50},
+		{ID: "io", Name: "In/Out", Items: 1, PurchasePrice: 10, Value: 10},
+	}, out.ByLocation)
+	assert.Equal(t, "In/Out", out.Items[2].Location)
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	// defaultWarrantyWindowDays is how far ahead warranty_report looks.
+	defaultWarrantyWindowDays = 90
+	// defaultReminderDaysBefore is how long before a warranty expires its
+	// reminder is due.
+	defaultReminderDaysBefore = 30
+	// warrantyReminderName is the name of the reminder entries, which is how
+	// existing reminders are recognised.
+	warrantyReminderName = "Warranty expires"
+)
+
+// Input for the warranty_report tool.
+type WarrantyReportInput struct {
+	WithinDays         int  `json:"withinDays,omitempty" jsonschema:"List warranties expiring within this many days; 90 by default"`
+	IncludeLapsed      bool `json:"includeLapsed,omitempty" jsonschema:"Also list warranties that have already expired"`
+	LapsedWithinDays   int  `json:"lapsedWithinDays,omitempty" jsonschema:"Only list warranties that expired within this many days. All lapsed warranties by default"`
+	IncludeArchived    bool `json:"includeArchived,omitempty" jsonschema:"Also list archived items"`
+	CreateReminders    bool `json:"createReminders,omitempty" jsonschema:"Create a scheduled maintenance entry ahead of each expiry that does not have one yet"`
+	ReminderDaysBefore int  `json:"reminderDaysBefore,omitempty" jsonschema:"How many days before the expiry the reminder is due; 30 by default"`
+}
+
+// WarrantyItem is an item whose warranty expires soon or has expired.
+type WarrantyItem struct {
+	ID              string  `json:"id" jsonschema:"ID of the item"`
+	Name            string  `json:"name" jsonschema:"Name of the item"`
+	AssetID         string  `json:"assetId,omitempty" jsonschema:"Asset ID of the item"`
+	Location        string  `json:"location,omitempty" jsonschema:"Path of the location of the item"`
+	Status          string  `json:"status" jsonschema:"expiring or lapsed"`
+	WarrantyExpires string  `json:"warrantyExpires" jsonschema:"Date the warranty expires"`
+	DaysLeft        int     `json:"daysLeft" jsonschema:"Days until the warranty expires, negative once it has"`
+	WarrantyDetails string  `json:"warrantyDetails,omitempty" jsonschema:"Warranty terms and contact details"`
+	PurchaseTime    string  `json:"purchaseTime,omitempty" jsonschema:"Date the item was bought"`
+	PurchasePrice   float64 `json:"purchasePrice" jsonschema:"Price paid for the item"`
+	PurchaseFrom    string  `json:"purchaseFrom,omitempty" jsonschema:"Where the item was bought"`
+	HasReceipt      bool    `json:"hasReceipt" jsonschema:"Whether the item has a receipt attachment"`
+	HasWarrantyFile bool    `json:"hasWarrantyFile" jsonschema:"Whether the item has a warranty attachment"`
+	ReminderDate    string  `json:"reminderDate,omitempty" jsonschema:"Date of the reminder entry, if there is one"`
+	ReminderCreated bool    `json:"reminderCreated,omitempty" jsonschema:"Whether the reminder was created by this call"`
+	ReminderMoved   bool    `json:"reminderMoved,omitempty" jsonschema:"Whether a pending reminder for an earlier expiry date was moved to this one by this call"`
+	ReminderError   string  `json:"reminderError,omitempty" jsonschema:"Why the reminder could not be created"`
+}
+
+// Output for the warranty_report tool.
+type WarrantyReportOutput struct {
+	Expiring  int            `json:"expiring" jsonschema:"Number of warranties expiring within the window"`
+	Lapsed    int            `json:"lapsed" jsonschema:"Number of lapsed warranties listed"`
+	Reminders int            `json:"remindersCreated" jsonschema:"Number of reminder entries created"`
+	Items     []WarrantyItem `json:"items" jsonschema:"The items, soonest expiry first"`
+}
+
+// warrantyReport is the implementation of the "warranty_report" tool.
+// Lifetime warranties never expire and are left out.
+func warrantyReport(ctx context.Context, req *mcp.CallToolRequest, input WarrantyReportInput) (*mcp.CallToolResult, WarrantyReportOutput, error) {
+	withinDays := input.WithinDays
+	if withinDays <= 0 {
+		withinDays = defaultWarrantyWindowDays
+	}
+	daysBefore := input.ReminderDaysBefore
+	if daysBefore <= 0 {
+		daysBefore = defaultReminderDaysBefore
+	}
+	// Whole days are counted from midnight UTC, like the dates.
+	today, _ := time.Parse(time.DateOnly, time.Now().Format(time.DateOnly))
+	horizon := today.AddDate(0, 0, withinDays).Format(time.DateOnly)
+	lapsedSince := ""
+	if input.LapsedWithinDays > 0 {
+		lapsedSince = today.AddDate(0, 0, -input.LapsedWithinDays).Format(time.DateOnly)
+	}
+
+	query := url.Values{}
+	if input.IncludeArchived {
+		query.Set("includeArchived", "true")
+	}
+	summaries, err := queryAllItems(ctx, query)
+	if err != nil {
+		return nil, WarrantyReportOutput{}, err
+	}
+	items, err := readItems(ctx, req, summaries)
+	if err != nil {
+		return nil, WarrantyReportOutput{}, err
+	}
+	snapshot, err := inventory.Snapshot(ctx)
+	if err != nil {
+		return nil, WarrantyReportOutput{}, err
+	}
+	locationPaths := make(map[string]string)
+	for _, loc := range snapshot.Locations {
+		locationPaths[loc.ID] = loc.Path
+	}
+
+	out := WarrantyReportOutput{Items: []WarrantyItem{}}
+	for _, item := range items {
+		expires := dateOnly(item.WarrantyExpires)
+		if item.LifetimeWarranty || expires == "" || expires > horizon {
+			continue
+		}
+		expiry, err := time.Parse(time.DateOnly, expires)
+		if err != nil {
+			continue
+		}
+		status := "expiring"
+		if expires < today.Format(time.DateOnly) {
+			if !input.IncludeLapsed || expires < lapsedSince {
+				continue
+			}
+			status = "lapsed"
+			out.Lapsed++
+		} else {
+			out.Expiring++
+		}
+		w := WarrantyItem{
+			ID:              item.ID,
+			Name:            item.Name,
+			AssetID:         item.AssetID,
+			Status:          status,
+			WarrantyExpires: expires,
+			DaysLeft:        int(expiry.Sub(today).Hours() / 24),
+			WarrantyDetails: item.WarrantyDetails,
+			PurchaseTime:    dateOnly(item.PurchaseTime),
+			PurchasePrice:   item.PurchasePrice,
+			PurchaseFrom:    item.PurchaseFrom,
+			HasReceipt:      hasAttachment(item, "receipt"),
+			HasWarrantyFile: hasAttachment(item, "warranty"),
+		}
+		if item.Location != nil {
+			w.Location = locationPaths[item.Location.ID]
+		}
+		if status == "expiring" && input.CreateReminders {
+			if err := remindWarranty(ctx, &w, expiry.AddDate(0, 0, -daysBefore), today); err != nil {
+				w.ReminderError = err.Error()
+			} else if w.ReminderCreated {
+				out.Reminders++
+			}
+		}
+		out.Items = append(out.Items, w)
+	}
+	slices.SortStableFunc(out.Items, func(a, b WarrantyItem) int {
+		if d := strings.Compare(a.WarrantyExpires, b.WarrantyExpires); d != 0 {
+			return d
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return nil, out, nil
+}
+
+// remindWarranty schedules a reminder entry for the warranty of w on date,
+// or today if that has passed, unless the item already has a reminder for
+// this expiry, done or not. Reminders name the expiry date in their
+// description. A pending reminder for another expiry date, left from before
+// the warranty changed, is moved to the new one instead.
+func remindWarranty(ctx context.Context, w *WarrantyItem, date, today time.Time) error {
+	_, maintenanceLog, err := getMaintenanceLog(ctx, nil, GetMaintenanceLogInput{ItemID: w.ID})
+	if err != nil {
+		return err
+	}
+	expiry := warrantyReminderExpiry(w.WarrantyExpires)
+	var stale *MaintenanceEntryWithDetails
+	for i, entry := range maintenanceLog.Entries {
+		if entry.Name != warrantyReminderName {
+			continue
+		}
+		if strings.Contains(entry.Description, expiry) {
+			w.ReminderDate = dateOnly(entry.ScheduledDate)
+			return nil
+		}
+		if dateOnly(entry.CompletedDate) == "" && stale == nil {
+			stale = &maintenanceLog.Entries[i]
+		}
+	}
+	if date.Before(today) {
+		date = today
+	}
+	description := fmt.Sprintf("The warranty of %s %s", w.Name, expiry)
+	if w.WarrantyDetails != "" {
+		description += " " + w.WarrantyDetails
+	}
+	if stale != nil {
+		_, _, err = updateMaintenanceEntry(ctx, nil, UpdateMaintenanceEntryInput{
+			ID:            stale.ID,
+			Name:          warrantyReminderName,
+			Description:   description,
+			Cost:          stale.Cost,
+			ScheduledDate: date.Format(time.DateOnly),
+		})
+		if err != nil {
+			return err
+		}
+		w.ReminderDate, w.ReminderMoved = date.Format(time.DateOnly), true
+		return nil
+	}
+	_, _, err = createMaintenanceEntry(ctx, nil, CreateMaintenanceEntryInput{
+		ItemID:        w.ID,
+		Name:          warrantyReminderName,
+		Description:   description,
+		ScheduledDate: date.Format(time.DateOnly),
+	})
+	if err != nil {
+		return err
+	}
+	w.ReminderDate, w.ReminderCreated = date.Format(time.DateOnly), true
+	return nil
+}
+
+// warrantyReminderExpiry is the part of a reminder description that names
+// the expiry date it is for.
+func warrantyReminderExpiry(expires string) string {
+	return fmt.Sprintf("expires on %s.", expires)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWarrantyReport(t *testing.T) {
+	store := newFakeItemStore()
+	day := func(days int) string { return time.Now().AddDate(0, 0, days).Format(time.DateOnly) }
+	garage := &LocationSummary{ID: "00000000-0000-4000-9000-000000000001"}
+	store.items[item1ID] = ItemOut{
+		ID: item1ID, Name: "TV", Location: garage, WarrantyExpires: day(45) + "T00:00:00Z", WarrantyDetails: "Call 555-0100",
+		PurchaseTime: "2024-05-01T00:00:00Z", PurchasePrice: 899, PurchaseFrom: "ElectroMart",
+		Attachments: []ItemAttachment{{ID: "r", Type: "receipt"}},
+	}
+	store.items[item2ID] = ItemOut{ID: item2ID, Name: "Drill", WarrantyExpires: day(-20)}
+	store.items[item3ID] = ItemOut{ID: item3ID, Name: "Fridge", WarrantyExpires: day(10), LifetimeWarranty: true}
+	store.items["00000000-0000-4000-8000-000000000004"] = ItemOut{ID: "00000000-0000-4000-8000-000000000004", Name: "Laptop", WarrantyExpires: day(200)}
+	store.items["00000000-0000-4000-8000-000000000005"] = ItemOut{ID: "00000000-0000-4000-8000-000000000005", Name: "Kettle", WarrantyExpires: day(-400)}
+	homebox := httptest.NewServer(store)
+	defer homebox.Close()
+	os.Setenv("HOMEBOX_URL", homebox.URL)
+	os.Setenv("HOMEBOX_TOKEN", "test-token")
+	inventory.Invalidate()
+	defer inventory.Invalidate()
+	ctx := context.Background()
+
+	_, out, err := warrantyReport(ctx, nil, WarrantyReportInput{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, out.Expiring)
+	assert.Equal(t, []WarrantyItem{{
+		ID: item1ID, Name: "TV", Location: "Garage", Status: "expiring", WarrantyExpires: day(45), DaysLeft: 45, WarrantyDetails: "Call 555-0100",
+		PurchaseTime: "2024-05-01", PurchasePrice: 899, PurchaseFrom: "ElectroMart", HasReceipt: true,
+	}}, out.Items)
+
+	_, out, err = warrantyReport(ctx, nil, WarrantyReportInput{IncludeLapsed: true, LapsedWithinDays: 365, WithinDays: 365})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, out.Expiring)
+	assert.Equal(t, 1, out.Lapsed)
+	names := []string{}
+	for _, item := range out.Items {
+		names = append(names, item.Name)
+	}
+	assert.Equal(t, []string{"Drill", "TV", "Laptop"}, names)
+	assert.Equal(t, -20, out.Items[0].DaysLeft)
+
+	// Reminders are created once, and never in the past.
+	for range 2 {
+		_, out, err = warrantyReport(ctx, nil, WarrantyReportInput{CreateReminders: true, ReminderDaysBefore: 60})
+		assert.NoError(t, err)
+	}
+	assert.Zero(t, out.Reminders)
+	assert.Equal(t, day(0), out.Items[0].ReminderDate)
+	if assert.Len(t, store.maintenance[item1ID], 1) {
+		entry := store.maintenance[item1ID][0]
+		assert.Equal(t, "Warranty expires", entry.Name)
+		assert.Equal(t, day(0), entry.ScheduledDate)
+		assert.Equal(t, "The warranty of TV expires on "+day(45)+". Call 555-0100", entry.Description)
+	}
+
+	// A completed reminder still counts for its expiry.
+	store.maintenance[item1ID][0].CompletedDate = day(0)
+	_, out, err = warrantyReport(ctx, nil, WarrantyReportInput{CreateReminders: true})
+	assert.NoError(t, err)
+	assert.Zero(t, out.Reminders)
+	assert.Len(t, store.maintenance[item1ID], 1)
+
+	// When the expiry changes, a new reminder is created, and a pending one
+	// for the old expiry is moved.
+	tv := store.items[item1ID]
+	tv.WarrantyExpires = day(50)
+	store.items[item1ID] = tv
+	_, out, err = warrantyReport(ctx, nil, WarrantyReportInput{CreateReminders: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, out.Reminders)
+	tv.WarrantyExpires = day(55)
+	store.items[item1ID] = tv
+	_, out, err = warrantyReport(ctx, nil, WarrantyReportInput{CreateReminders: true})
+	assert.NoError(t, err)
+	assert.Zero(t, out.Reminders)
+	assert.True(t, out.Items[0].ReminderMoved)
+	if assert.Len(t, store.maintenance[item1ID], 2) {
+		entry := store.maintenance[item1ID][1]
+		assert.Equal(t, day(25), entry.ScheduledDate)
+		assert.Equal(t, "The warranty of TV expires on "+day(55)+". Call 555-0100", entry.Description)
+	}
+}